@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/mcp"
+)
+
+// recordAudit appends the tool call served by ctx to the audit log of
+// database's workspace. Failures are logged; the call has already succeeded.
+func recordAudit(ctx context.Context, database *db.DB, detail map[string]any, ids ...int64) {
+	recordAuditIn(ctx, database, "", detail, ids...)
+}
+
+// recordAuditIn is recordAudit for calls that change another workspace than
+// database's, such as workspace renames.
+func recordAuditIn(ctx context.Context, database *db.DB, workspaceID string, detail map[string]any, ids ...int64) {
+	entry := db.AuditEntry{
+		WorkspaceID: workspaceID,
+		Actor:       "local",
+		MemoryIDs:   ids,
+		Detail:      detail,
+	}
+	if call, ok := mcp.ToolCallFromContext(ctx); ok {
+		entry.Tool = call.Name
+		entry.ArgsDigest = argsDigest(call.Arguments)
+		entry.ClientName = call.Client.Name
+		entry.ClientVersion = call.Client.Version
+	}
+	if k, ok := authenticatedKey(ctx); ok {
+		entry.Actor = fmt.Sprintf("key:%s (%s)", k.Prefix, k.Name)
+	}
+
+	if err := database.RecordAudit(ctx, entry); err != nil {
+		log.Printf("cortex: warning: failed to record %s in the audit log: %v", entry.Tool, err)
+	}
+}
+
+// recordCLIAudit appends a CLI command to the audit log of database's workspace.
+func recordCLIAudit(ctx context.Context, database *db.DB, command string, detail map[string]any, ids ...int64) {
+	err := database.RecordAudit(ctx, db.AuditEntry{
+		Actor:     "cli",
+		Tool:      "cli:" + command,
+		MemoryIDs: ids,
+		Detail:    detail,
+	})
+	if err != nil {
+		log.Printf("cortex: warning: failed to record %s in the audit log: %v", command, err)
+	}
+}
+
+// argsDigest returns the SHA-256 of a call's arguments, so the audit log can
+// match calls without storing memory text.
+func argsDigest(args json.RawMessage) string {
+	if len(args) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(args)
+	return hex.EncodeToString(sum[:])
+}
+
+// parseSince parses an RFC 3339 timestamp, a date meaning the start of that
+// day in UTC, or a duration meaning that long ago.
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q: expected an RFC 3339 timestamp, YYYY-MM-DD or a duration", s)
+}
+
+func createAuditHandler(database *db.DB) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryAuditArgs
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+
+		k := 50
+		if args.K != nil {
+			k = *args.K
+		}
+		if k < 1 || k > 500 {
+			return nil, fmt.Errorf("k must be between 1 and 500")
+		}
+
+		filter := db.AuditFilter{MemoryID: args.MemoryID, Tool: args.Tool, Limit: k}
+		if args.Since != "" {
+			since, err := parseSince(args.Since)
+			if err != nil {
+				return nil, err
+			}
+			filter.Since = &since
+		}
+
+		entries, err := database.ListAudit(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("list audit log: %w", err)
+		}
+
+		result := mcp.MemoryAuditResult{Entries: make([]mcp.AuditEntryResult, len(entries))}
+		for i, e := range entries {
+			result.Entries[i] = mcp.AuditEntryResult{
+				ID:            e.ID,
+				At:            e.At.Format(time.RFC3339),
+				Actor:         e.Actor,
+				Tool:          e.Tool,
+				ArgsDigest:    e.ArgsDigest,
+				ClientName:    e.ClientName,
+				ClientVersion: e.ClientVersion,
+				MemoryIDs:     e.MemoryIDs,
+				Detail:        e.Detail,
+			}
+		}
+		return result, nil
+	}
+}
+
+// runAuditCommand implements `cortex audit`.
+func runAuditCommand(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	since := fs.String("since", "", "Only export entries at or after this time (RFC 3339, YYYY-MM-DD or a duration ago such as 24h)")
+	memory := fs.String("memory", "", "Only export entries that affected this memory ID")
+	tool := fs.String("tool", "", "Only export entries of this tool")
+	all := fs.Bool("all-workspaces", false, "Export the audit log of every workspace of TENANT_ID")
+	output := fs.String("output", "", "File to write to (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex audit [flags]")
+		fmt.Fprintln(os.Stderr, "Exports the audit log of WORKSPACE_ID as JSONL, oldest first.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	filter := db.AuditFilter{Tool: *tool, AllWorkspaces: *all}
+	if *since != "" {
+		t, err := parseSince(*since)
+		if err != nil {
+			return err
+		}
+		filter.Since = &t
+	}
+	if *memory != "" {
+		id, err := strconv.ParseInt(*memory, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid memory id %q", *memory)
+		}
+		filter.MemoryID = &id
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, database, err := openCLIDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+
+	var n int
+	err = database.EachAudit(ctx, filter, func(e db.AuditEntry) error {
+		n++
+		return enc.Encode(e)
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	if *output != "" {
+		log.Printf("cortex: exported %d audit entries to %s", n, *output)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/mcp"
+)
+
+// toolRoles is the role an API key needs for each tool. Tools not listed need admin.
+var toolRoles = map[string]db.Role{
+	"memory.search":         db.RoleRead,
+	"memory.get":            db.RoleRead,
+	"memory.export":         db.RoleRead,
+	"memory.related":        db.RoleRead,
+	"memory.todo_list":      db.RoleRead,
+	"memory.entities":       db.RoleRead,
+	"memory.conflicts":      db.RoleRead,
+	"memory.sweeper_status": db.RoleRead,
+	"memory.audit":          db.RoleRead,
+
+	"memory.add":                 db.RoleWrite,
+	"memory.update":              db.RoleWrite,
+	"memory.delete":              db.RoleWrite,
+	"memory.import":              db.RoleWrite,
+	"memory.ingest":              db.RoleWrite,
+	"memory.ingest_conversation": db.RoleWrite,
+	"memory.link":                db.RoleWrite,
+	"memory.unlink":              db.RoleWrite,
+	"memory.todo_complete":       db.RoleWrite,
+}
+
+// keyAuthorizer checks each tool call against the caller's API key.
+type keyAuthorizer struct {
+	keys     *pgxpool.Pool // sees the API keys of every tenant
+	router   *workspaceRouter
+	scopes   workspaceScopes
+	required bool // reject calls made without a key
+}
+
+// callTarget holds the arguments that decide what a tool call reaches.
+type callTarget struct {
+	Workspace  string   `json:"workspace"`
+	Scope      string   `json:"scope"`
+	Workspaces []string `json:"workspaces"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Path       string   `json:"path"`
+}
+
+type authenticatedKeyContextKey struct{}
+
+// authenticatedKey returns the API key a tool call was authorized with, if any.
+func authenticatedKey(ctx context.Context) (*db.APIKey, bool) {
+	k, ok := ctx.Value(authenticatedKeyContextKey{}).(*db.APIKey)
+	return k, ok
+}
+
+// authorize implements mcp.Authorizer. Every call must stay within
+// ALLOWED_WORKSPACES. Calls without a key are allowed unless a key is
+// required. The returned context carries the caller's key, whose tenant the
+// call works on.
+func (a *keyAuthorizer) authorize(ctx context.Context, tool string, args json.RawMessage) (context.Context, error) {
+	var target callTarget
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &target); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	reached := a.workspaces(tool, target)
+	if err := a.router.authorize(tool, reached); err != nil {
+		return nil, err
+	}
+
+	key, ok := mcp.APIKeyFromContext(ctx)
+	if !ok {
+		if a.required {
+			return nil, fmt.Errorf("an API key is required")
+		}
+		return ctx, nil
+	}
+
+	k, err := db.AuthenticateAPIKey(ctx, a.keys, key)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, fmt.Errorf("invalid or revoked API key")
+	}
+
+	role, ok := toolRoles[tool]
+	if !ok || target.Path != "" {
+		// Reading files on the server is an admin action
+		role = db.RoleAdmin
+	}
+	if !k.Role.Includes(role) {
+		return nil, fmt.Errorf("key %s has role %s, this call requires %s", k.Prefix, k.Role, role)
+	}
+
+	for _, ws := range reached {
+		if !k.Allows(ws) {
+			if ws == db.AllWorkspaces {
+				return nil, fmt.Errorf("key %s does not grant every workspace", k.Prefix)
+			}
+			return nil, fmt.Errorf("key %s does not grant workspace %q", k.Prefix, ws)
+		}
+	}
+	return context.WithValue(ctx, authenticatedKeyContextKey{}, k), nil
+}
+
+// workspaces returns the workspaces a tool call reaches. Searches also cover
+// the inherited workspaces (INHERIT_WORKSPACES), which any key may read.
+func (a *keyAuthorizer) workspaces(tool string, target callTarget) []string {
+	switch tool {
+	case "memory.add":
+		if target.Scope == "shared" {
+			return []string{a.scopes.Shared}
+		}
+	case "memory.search":
+		if target.Scope == "tenant" {
+			return []string{db.AllWorkspaces}
+		}
+		if len(target.Workspaces) > 0 {
+			return target.Workspaces
+		}
+	case "memory.workspaces":
+		return []string{db.AllWorkspaces}
+	case "memory.workspace_rename", "memory.workspace_clone", "memory.workspace_merge":
+		// Missing ones are rejected by the tool
+		var moved []string
+		for _, ws := range []string{target.From, target.To} {
+			if ws != "" {
+				moved = append(moved, ws)
+			}
+		}
+		return moved
+	}
+
+	if target.Workspace != "" {
+		return []string{target.Workspace}
+	}
+	return []string{a.router.defaultWorkspace()}
+}
+
+// runKeysCommand implements `cortex keys`.
+func runKeysCommand(args []string) error {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex keys create --name <name> --role read|write|admin [--workspaces <a,b>|*]")
+		fmt.Fprintln(os.Stderr, "       cortex keys list")
+		fmt.Fprintln(os.Stderr, "       cortex keys revoke <id>")
+		fmt.Fprintln(os.Stderr, "Manages the API keys of TENANT_ID.")
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("an action is required")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("keys create", flag.ExitOnError)
+		name := fs.String("name", "", "Name to recognize the key by (required)")
+		roleName := fs.String("role", "read", "Role granted on the workspaces: read, write or admin")
+		workspaces := fs.String("workspaces", "", "Comma-separated workspaces the key grants, or * for all (default: WORKSPACE_ID)")
+		fs.Usage = func() {
+			usage()
+			fs.PrintDefaults()
+		}
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" || fs.NArg() > 0 {
+			fs.Usage()
+			return fmt.Errorf("invalid keys command")
+		}
+		role, err := db.ParseRole(*roleName)
+		if err != nil {
+			return err
+		}
+
+		cfg, database, err := openCLIDatabase(ctx)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		granted := splitList(*workspaces)
+		if len(granted) == 0 {
+			granted = []string{cfg.WorkspaceID}
+		}
+		key, k, err := database.CreateAPIKey(ctx, *name, role, granted)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "created key %d (%s) for tenant %s: %s on %s\n",
+			k.ID, k.Name, k.TenantID, k.Role, strings.Join(k.Workspaces, ","))
+		fmt.Fprintln(os.Stderr, "store it now; it cannot be shown again")
+		fmt.Println(key)
+		return nil
+
+	case "list", "revoke":
+		var id int64
+		if args[0] == "revoke" {
+			if len(args) != 2 {
+				usage()
+				return fmt.Errorf("invalid keys command")
+			}
+			var err error
+			if id, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+				return fmt.Errorf("invalid key id %q", args[1])
+			}
+		} else if len(args) != 1 {
+			usage()
+			return fmt.Errorf("invalid keys command")
+		}
+
+		_, database, err := openCLIDatabase(ctx)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		if args[0] == "revoke" {
+			if err := database.RevokeAPIKey(ctx, id); err != nil {
+				return err
+			}
+			fmt.Printf("revoked key %d\n", id)
+			return nil
+		}
+
+		keys, err := database.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			lastUsed, status := "never", "active"
+			if k.LastUsedAt != nil {
+				lastUsed = k.LastUsedAt.Format(time.RFC3339)
+			}
+			if k.RevokedAt != nil {
+				status = "revoked " + k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d %-20s %s... %-5s %-30s created %s, last used %s, %s\n",
+				k.ID, k.Name, k.Prefix, k.Role, strings.Join(k.Workspaces, ","),
+				k.CreatedAt.Format(time.RFC3339), lastUsed, status)
+		}
+		return nil
+
+	default:
+		usage()
+		return fmt.Errorf("invalid keys command")
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/johnswift/cortex/internal/llm"
+	"github.com/johnswift/cortex/internal/mcp"
+	"github.com/johnswift/cortex/internal/reembed"
+)
+
+// backfillOptions selects which missing data a backfill processes.
+type backfillOptions struct {
+	Embeddings bool
+	Entities   bool
+	Config     reembed.Config // batch size and delay; other fields are ignored
+}
+
+// backfillReport summarizes a backfill run.
+type backfillReport struct {
+	Embeddings map[string]*reembed.Stats // keyed by embedding model
+	Entities   *reembed.Stats
+}
+
+// backfill embeds memories that lack an embedding for any configured model
+// and extracts entities for memories that have no linked entities.
+func (p *pipeline) backfill(ctx context.Context, opts backfillOptions) (*backfillReport, error) {
+	report := &backfillReport{}
+
+	if opts.Embeddings {
+		report.Embeddings = make(map[string]*reembed.Stats)
+		for model, provider := range p.embeddingProviders() {
+			stats, err := p.backfillEmbeddings(ctx, model, provider, opts.Config)
+			if err != nil {
+				return report, fmt.Errorf("backfill embeddings (model=%s): %w", model, err)
+			}
+			report.Embeddings[model] = stats
+		}
+	}
+
+	if opts.Entities {
+		if p.extractor == nil {
+			return report, fmt.Errorf("entity extraction is not enabled")
+		}
+		stats, err := p.backfillEntities(ctx, opts.Config)
+		if err != nil {
+			return report, fmt.Errorf("backfill entities: %w", err)
+		}
+		report.Entities = stats
+	}
+
+	return report, nil
+}
+
+// embeddingProviders returns the provider for every configured embedding model.
+func (p *pipeline) embeddingProviders() map[string]llm.EmbeddingProvider {
+	providers := make(map[string]llm.EmbeddingProvider)
+	if p.multiEmbedder == nil {
+		providers[p.provider.EmbedModel()] = p.provider
+		return providers
+	}
+	for _, model := range p.multiEmbedder.Models() {
+		if provider, err := p.multiEmbedder.Provider(model); err == nil {
+			providers[model] = provider
+		}
+	}
+	return providers
+}
+
+func (p *pipeline) backfillEmbeddings(ctx context.Context, model string, provider llm.EmbeddingProvider, cfg reembed.Config) (*reembed.Stats, error) {
+	log.Printf("cortex: backfilling embeddings (model=%s, batch=%d, delay=%v)", model, cfg.BatchSize, cfg.DelayBetweenBatches)
+
+	// Provider.Model() reports the chat model, so the target model is always explicit
+	cfg.TargetModel = model
+	r := reembed.NewReembedder(p.database.Pool(), provider, p.database.TenantID(), p.database.WorkspaceID()).
+		WithConfig(cfg).
+		WithTextOpener(p.database.OpenText)
+
+	stats, err := r.ReembedMissing(ctx, backfillProgress("embedded"))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("cortex: embedding backfill complete (model=%s) - total=%d processed=%d errors=%d duration=%v",
+		model, stats.Total, stats.Processed, stats.Errors, stats.Duration)
+	return stats, nil
+}
+
+// backfillEntities runs entity extraction for memories without linked entities.
+// Memories that genuinely mention no entities are extracted again on every run.
+func (p *pipeline) backfillEntities(ctx context.Context, cfg reembed.Config) (*reembed.Stats, error) {
+	log.Printf("cortex: backfilling entities (batch=%d, delay=%v)", cfg.BatchSize, cfg.DelayBetweenBatches)
+
+	start := time.Now()
+	stats := &reembed.Stats{}
+
+	total, err := p.database.CountMemoriesWithoutEntities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Total = total
+
+	progress := backfillProgress("extracted entities for")
+	var lastID int64
+	for stats.Total > 0 {
+		select {
+		case <-ctx.Done():
+			stats.Duration = time.Since(start)
+			return stats, ctx.Err()
+		default:
+		}
+
+		memories, err := p.database.ListMemoriesWithoutEntities(ctx, lastID, cfg.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(memories) == 0 {
+			break
+		}
+
+		for _, m := range memories {
+			processErr := p.extractEntities(ctx, m.ID, m.Text)
+			if processErr != nil {
+				stats.Errors++
+			}
+			stats.Processed++
+			progress(stats.Processed, total, m.ID, processErr)
+		}
+
+		lastID = memories[len(memories)-1].ID
+
+		// Rate limiting delay between batches
+		if cfg.DelayBetweenBatches > 0 {
+			time.Sleep(cfg.DelayBetweenBatches)
+		}
+	}
+
+	stats.Duration = time.Since(start)
+	log.Printf("cortex: entity backfill complete - total=%d processed=%d errors=%d duration=%v",
+		stats.Total, stats.Processed, stats.Errors, stats.Duration)
+	return stats, nil
+}
+
+// backfillProgress returns a progress callback that logs errors and periodic progress.
+func backfillProgress(verb string) reembed.ProgressCallback {
+	return func(processed, total int64, memoryID int64, err error) {
+		if err != nil {
+			log.Printf("cortex: error backfilling memory %d: %v", memoryID, err)
+		} else if processed%100 == 0 || processed == total {
+			log.Printf("cortex: %s %d/%d memories (%.1f%%)", verb, processed, total, float64(processed)/float64(total)*100)
+		}
+	}
+}
+
+func createBackfillHandler(pipe *pipeline) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryBackfillArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		opts := backfillOptions{
+			Embeddings: args.Embeddings,
+			Entities:   args.Entities,
+			Config:     reembed.DefaultConfig(),
+		}
+		if !opts.Embeddings && !opts.Entities {
+			opts.Embeddings = true
+			opts.Entities = pipe.extractor != nil
+		}
+		if args.BatchSize != nil {
+			if *args.BatchSize < 1 || *args.BatchSize > 1000 {
+				return nil, fmt.Errorf("batch_size must be between 1 and 1000")
+			}
+			opts.Config.BatchSize = *args.BatchSize
+		}
+
+		report, err := pipe.backfill(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var result mcp.MemoryBackfillResult
+		models := make([]string, 0, len(report.Embeddings))
+		for model := range report.Embeddings {
+			models = append(models, model)
+		}
+		sort.Strings(models)
+		for _, model := range models {
+			stats := backfillStats(report.Embeddings[model])
+			stats.Model = model
+			result.Embeddings = append(result.Embeddings, stats)
+		}
+		if report.Entities != nil {
+			stats := backfillStats(report.Entities)
+			result.Entities = &stats
+		}
+
+		return result, nil
+	}
+}
+
+func backfillStats(s *reembed.Stats) mcp.BackfillStats {
+	return mcp.BackfillStats{
+		Total:      s.Total,
+		Processed:  s.Processed,
+		Errors:     s.Errors,
+		DurationMs: s.Duration.Milliseconds(),
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/johnswift/cortex/internal/chunk"
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/entity"
+	"github.com/johnswift/cortex/internal/jobs"
+	"github.com/johnswift/cortex/internal/reembed"
+)
+
+// commands maps subcommand names to their implementations.
+// Each command parses its own flags from args.
+var commands = map[string]func(args []string) error{
+	"audit":      runAuditCommand,
+	"backfill":   runBackfillCommand,
+	"encryption": runEncryptionCommand,
+	"ingest":     runIngestCommand,
+	"keys":       runKeysCommand,
+	"scan":       runScanCommand,
+	"sweep":      runSweepCommand,
+	"watch":      runWatchCommand,
+	"workspace":  runWorkspaceCommand,
+
+	"check-isolation":     runCheckIsolationCommand,
+	"ingest-conversation": runIngestConversationCommand,
+}
+
+// runCommand runs the named subcommand.
+func runCommand(name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command %q (available: %v)", name, names)
+	}
+	return cmd(args)
+}
+
+// openCLIDatabase loads CLI configuration, connects to the database and runs migrations.
+func openCLIDatabase(ctx context.Context) (*Config, *db.DB, error) {
+	cfg, err := loadConfigForCLI()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+
+	database, err := db.NewWithWorkspace(ctx, cfg.DatabaseURL, cfg.TenantID, cfg.WorkspaceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to database: %w", err)
+	}
+	cfg.applyEncryption(database)
+
+	if err := database.Migrate(ctx); err != nil {
+		database.Close()
+		return nil, nil, fmt.Errorf("run migrations: %w", err)
+	}
+
+	return cfg, database, nil
+}
+
+// newCLIPipeline creates a pipeline for CLI commands. Steps that fail are
+// queued for the server's job workers to retry.
+func newCLIPipeline(cfg *Config, database *db.DB, entities bool) (*pipeline, error) {
+	if err := requireAPIKey(cfg); err != nil {
+		return nil, err
+	}
+
+	provider, err := initLLMProvider(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init LLM provider: %w", err)
+	}
+
+	multiEmbedder, err := initMultiEmbedder(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init multi-embedder: %w", err)
+	}
+
+	pipe := &pipeline{
+		database:      database,
+		provider:      provider,
+		multiEmbedder: multiEmbedder,
+		queue:         jobs.NewQueue(database.Pool(), cfg.TenantID, cfg.WorkspaceID),
+		redactor:      cfg.Redactor,
+	}
+	if entities {
+		pipe.extractor = entity.NewExtractor(provider)
+	}
+	return pipe, nil
+}
+
+// runBackfillCommand implements `cortex backfill`.
+func runBackfillCommand(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	embeddings := fs.Bool("embeddings", false, "Embed memories missing an embedding for any configured model")
+	entities := fs.Bool("entities", false, "Extract entities for memories without linked entities")
+	batchSize := fs.Int("batch-size", 100, "Batch size")
+	delay := fs.Duration("delay", reembed.DefaultConfig().DelayBetweenBatches, "Delay between batches")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex backfill [--embeddings] [--entities] [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// With neither flag, backfill everything
+	if !*embeddings && !*entities {
+		*embeddings, *entities = true, true
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, database, err := openCLIDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	pipe, err := newCLIPipeline(cfg, database, *entities)
+	if err != nil {
+		return err
+	}
+
+	cfgBatch := reembed.DefaultConfig()
+	cfgBatch.BatchSize = *batchSize
+	cfgBatch.DelayBetweenBatches = *delay
+
+	_, err = pipe.backfill(ctx, backfillOptions{
+		Embeddings: *embeddings,
+		Entities:   *entities,
+		Config:     cfgBatch,
+	})
+	return err
+}
+
+// runIngestCommand implements `cortex ingest`.
+func runIngestCommand(args []string) error {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	format := fs.String("format", "", "Split format: text, markdown or go (default: detected from extension)")
+	title := fs.String("title", "", "Document title (default: file name; single file only)")
+	tags := fs.String("tags", "", "Comma-separated tags for the documents")
+	maxChars := fs.Int("max-chars", chunk.DefaultOptions().MaxChars, "Maximum chunk size in bytes")
+	overlap := fs.Int("overlap", chunk.DefaultOptions().Overlap, "Bytes of overlap between consecutive chunks")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex ingest [flags] <path>...")
+		fmt.Fprintln(os.Stderr, "Directories are searched recursively for .md, .markdown, .mdx, .txt and .go files.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("at least one path is required")
+	}
+
+	var forced chunk.Format
+	if *format != "" {
+		f, err := chunk.ParseFormat(*format)
+		if err != nil {
+			return err
+		}
+		forced = f
+	}
+
+	files, err := collectIngestFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *title != "" && len(files) > 1 {
+		return fmt.Errorf("--title can only be used with a single file")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, database, err := openCLIDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	pipe, err := newCLIPipeline(cfg, database, cfg.EntityExtraction)
+	if err != nil {
+		return err
+	}
+
+	opts := chunk.Options{MaxChars: *maxChars, Overlap: *overlap}
+	var failed int
+	for _, path := range files {
+		doc, err := readDocument(path)
+		if err != nil {
+			log.Printf("cortex: error: %v", err)
+			failed++
+			continue
+		}
+		if forced != "" {
+			doc.Format = forced
+		}
+		if *title != "" {
+			doc.Title = *title
+		}
+		doc.Tags = splitList(*tags)
+
+		outcome, err := pipe.ingest(ctx, doc, opts)
+		if err != nil {
+			log.Printf("cortex: error ingesting %s: %v", path, err)
+			failed++
+			continue
+		}
+		for _, w := range outcome.Warnings {
+			log.Printf("cortex: warning: %s: %s", path, w)
+		}
+	}
+
+	log.Printf("cortex: ingested %d files (%d failed)", len(files)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d files failed to ingest", failed)
+	}
+	return nil
+}
+
+// runIngestConversationCommand implements `cortex ingest-conversation`.
+func runIngestConversationCommand(args []string) error {
+	fs := flag.NewFlagSet("ingest-conversation", flag.ExitOnError)
+	tags := fs.String("tags", "", "Comma-separated tags added to every extracted memory")
+	minImportance := fs.Float64("min-importance", 0, "Drop candidates rated below this importance")
+	dryRun := fs.Bool("dry-run", false, "Print the extracted memories without storing them")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex ingest-conversation [flags] <transcript.jsonl>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("at least one transcript is required")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, database, err := openCLIDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	pipe, err := newCLIPipeline(cfg, database, cfg.EntityExtraction)
+	if err != nil {
+		return err
+	}
+
+	opts := conversationOptions{
+		Tags:          splitList(*tags),
+		MinImportance: float32(*minImportance),
+		DryRun:        *dryRun,
+	}
+	var failed int
+	for _, path := range fs.Args() {
+		t, err := readTranscript(path)
+		if err != nil {
+			log.Printf("cortex: error: %v", err)
+			failed++
+			continue
+		}
+
+		outcome, err := pipe.ingestConversation(ctx, t, opts)
+		if err != nil {
+			log.Printf("cortex: error ingesting %s: %v", path, err)
+			failed++
+			continue
+		}
+		for _, w := range outcome.Warnings {
+			log.Printf("cortex: warning: %s: %s", path, w)
+		}
+
+		for _, m := range outcome.Added {
+			if *dryRun {
+				fmt.Printf("[%s %.2f] %s\n", m.Kind, m.Importance, m.Text)
+			} else {
+				fmt.Printf("added %d [%s %.2f] %s\n", m.ID, m.Kind, m.Importance, m.Text)
+			}
+		}
+		for _, m := range outcome.Duplicates {
+			fmt.Printf("duplicate of %d [%s] %s\n", m.ID, m.Kind, m.Text)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d transcripts failed to ingest", failed)
+	}
+	return nil
+}
+
+// collectIngestFiles expands directories into the ingestable files they contain.
+// Hidden directories are skipped. Files named explicitly are always included.
+func collectIngestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ingestExtensions[strings.ToLower(filepath.Ext(path))] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// splitList parses a comma-separated list, such as tags.
+func splitList(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
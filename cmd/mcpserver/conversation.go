@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/llm"
+	"github.com/johnswift/cortex/internal/mcp"
+	"github.com/johnswift/cortex/internal/redact"
+	"github.com/johnswift/cortex/internal/transcript"
+)
+
+// conversationOptions configures how memories extracted from a conversation are stored.
+type conversationOptions struct {
+	Tags          []string // added to every extracted memory
+	MinImportance float32  // candidates below this importance are dropped
+	DryRun        bool     // extract and dedupe, but store nothing
+}
+
+// extractedMemory is a candidate memory and what happened to it.
+type extractedMemory struct {
+	transcript.Candidate
+	ID int64 // stored memory, or the existing memory it duplicates; 0 in a dry run
+}
+
+// conversationOutcome reports the result of ingesting a conversation.
+type conversationOutcome struct {
+	writeOutcome
+	SessionID  string
+	Turns      int
+	Added      []extractedMemory
+	Duplicates []extractedMemory
+	Skipped    int // candidates below MinImportance
+}
+
+// ingestConversation extracts durable memories from a transcript and stores
+// those that do not duplicate an existing memory. Extracted memories have the
+// source "conversation:<session id>" and record the session in meta.
+func (p *pipeline) ingestConversation(ctx context.Context, t *transcript.Transcript, opts conversationOptions) (*conversationOutcome, error) {
+	if err := p.redactTranscript(t); err != nil {
+		return nil, err
+	}
+
+	candidates, err := transcript.NewExtractor(p.provider).Extract(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("extract memories: %w", err)
+	}
+
+	source := "conversation"
+	if t.SessionID != "" {
+		source += ":" + t.SessionID
+	}
+
+	outcome := &conversationOutcome{SessionID: t.SessionID, Turns: len(t.Turns)}
+	pending := make(map[string]bool)
+	for _, c := range candidates {
+		if c.Importance < opts.MinImportance {
+			outcome.Skipped++
+			continue
+		}
+
+		dupID, err := p.findDuplicate(ctx, c.Text)
+		if err != nil {
+			log.Printf("cortex: warning: failed to check extracted memory for duplicates: %v", err)
+			outcome.Warnings = append(outcome.Warnings, fmt.Sprintf("duplicate check failed for %q", c.Text))
+		}
+		if dupID != 0 {
+			outcome.Duplicates = append(outcome.Duplicates, extractedMemory{Candidate: c, ID: dupID})
+			continue
+		}
+
+		c.Tags = mergeTags(opts.Tags, c.Tags)
+		if opts.DryRun {
+			outcome.Added = append(outcome.Added, extractedMemory{Candidate: c})
+			continue
+		}
+
+		meta := map[string]any{"extracted_from": "conversation"}
+		if t.SessionID != "" {
+			meta["session_id"] = t.SessionID
+		}
+		id, err := p.database.AddMemory(ctx, db.AddMemoryParams{
+			Kind:       c.Kind,
+			Text:       c.Text,
+			Source:     &source,
+			Tags:       c.Tags,
+			Importance: c.Importance,
+			Meta:       meta,
+		})
+		if err != nil {
+			return outcome, fmt.Errorf("add memory: %w", err)
+		}
+
+		out := p.afterWrite(ctx, id, c.Kind, c.Text, meta)
+		outcome.Conflicts = append(outcome.Conflicts, out.Conflicts...)
+		outcome.Warnings = append(outcome.Warnings, out.Warnings...)
+		for _, kind := range out.Pending {
+			if !pending[kind] {
+				pending[kind] = true
+				outcome.Pending = append(outcome.Pending, kind)
+			}
+		}
+		outcome.Added = append(outcome.Added, extractedMemory{Candidate: c, ID: id})
+	}
+
+	log.Printf("cortex: extracted %d memories from conversation %q (%d added, %d duplicates, %d below importance)",
+		len(candidates), t.SessionID, len(outcome.Added), len(outcome.Duplicates), outcome.Skipped)
+	return outcome, nil
+}
+
+// redactTranscript applies REDACT_MODE to the turns of a transcript, all of
+// which are sent to the LLM to extract memories. With REDACT_MODE=no-llm a
+// transcript with findings is rejected, since extraction needs the LLM.
+func (p *pipeline) redactTranscript(t *transcript.Transcript) error {
+	for i := range t.Turns {
+		redacted, err := p.redactor.Apply(t.Turns[i].Text)
+		if err != nil {
+			return fmt.Errorf("turn %d: %w", i+1, err)
+		}
+		if redacted.NoLLM {
+			return fmt.Errorf("turn %d contains possible %s, which REDACT_MODE=no-llm keeps from the LLM that extracts memories",
+				i+1, strings.Join(redact.Kinds(redacted.Findings), ", "))
+		}
+		t.Turns[i].Text = redacted.Text
+	}
+	return nil
+}
+
+// findDuplicate returns the ID of an existing memory whose primary-model
+// embedding is at least transcript.DefaultDuplicateSimilarity similar to text,
+// or 0 if there is none.
+func (p *pipeline) findDuplicate(ctx context.Context, text string) (int64, error) {
+	model := p.provider.EmbedModel()
+	var embedder llm.EmbeddingProvider = p.provider
+	if p.multiEmbedder != nil {
+		model = p.multiEmbedder.Primary()
+		provider, err := p.multiEmbedder.Provider(model)
+		if err != nil {
+			return 0, err
+		}
+		embedder = provider
+	}
+
+	embedding, err := embedder.Embed(ctx, text)
+	if err != nil {
+		return 0, fmt.Errorf("generate embedding: %w", err)
+	}
+
+	similar, err := p.database.VectorSearch(ctx, db.VectorSearchParams{
+		Embedding: embedding,
+		Limit:     1,
+		Model:     model,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(similar) == 0 || similar[0].Score < transcript.DefaultDuplicateSimilarity {
+		return 0, nil
+	}
+	return similar[0].ID, nil
+}
+
+// mergeTags returns the union of two tag lists, keeping the order of first appearance.
+func mergeTags(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var tags []string
+	for _, t := range append(append([]string{}, a...), b...) {
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// readTranscript parses a transcript file.
+func readTranscript(path string) (*transcript.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t, err := transcript.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return t, nil
+}
+
+func createIngestConversationHandler(pipe *pipeline) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryIngestConversationArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		if (args.Transcript == "") == (args.Path == "") {
+			return nil, fmt.Errorf("exactly one of transcript or path is required")
+		}
+
+		var t *transcript.Transcript
+		var err error
+		if args.Path != "" {
+			t, err = readTranscript(args.Path)
+		} else {
+			t, err = transcript.Parse(strings.NewReader(args.Transcript))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		opts := conversationOptions{Tags: args.Tags, DryRun: args.DryRun}
+		if args.MinImportance != nil {
+			opts.MinImportance = *args.MinImportance
+		}
+
+		outcome, err := pipe.ingestConversation(ctx, t, opts)
+		if err != nil {
+			return nil, fmt.Errorf("ingest conversation: %w", err)
+		}
+		if !args.DryRun && len(outcome.Added) > 0 {
+			ids := make([]int64, len(outcome.Added))
+			for i, m := range outcome.Added {
+				ids[i] = m.ID
+			}
+			recordAudit(ctx, pipe.database, map[string]any{"session_id": outcome.SessionID}, ids...)
+		}
+
+		return mcp.MemoryIngestConversationResult{
+			SessionID:  outcome.SessionID,
+			Turns:      outcome.Turns,
+			Added:      extractedResults(outcome.Added),
+			Duplicates: extractedResults(outcome.Duplicates),
+			Skipped:    outcome.Skipped,
+			DryRun:     args.DryRun,
+			Conflicts:  outcome.Conflicts,
+			Warnings:   outcome.Warnings,
+			Pending:    outcome.Pending,
+		}, nil
+	}
+}
+
+func extractedResults(memories []extractedMemory) []mcp.ExtractedMemory {
+	results := make([]mcp.ExtractedMemory, len(memories))
+	for i, m := range memories {
+		results[i] = mcp.ExtractedMemory{
+			ID:         m.ID,
+			Text:       m.Text,
+			Kind:       m.Kind,
+			Importance: m.Importance,
+			Tags:       m.Tags,
+		}
+	}
+	return results
+}
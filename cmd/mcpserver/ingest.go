@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/johnswift/cortex/internal/chunk"
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/mcp"
+	"github.com/johnswift/cortex/internal/redact"
+)
+
+// ingestExtensions lists the file extensions picked up when ingesting a directory.
+var ingestExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".mdx":      true,
+	".txt":      true,
+	".go":       true,
+}
+
+// document describes a document to ingest.
+type document struct {
+	Text       string
+	Title      string
+	Format     chunk.Format
+	Source     *string
+	Tags       []string
+	Importance float32
+	Meta       map[string]any // merged into the document's meta
+}
+
+// ingestOutcome reports the result of ingesting a document.
+type ingestOutcome struct {
+	writeOutcome
+	DocumentID int64
+	Chunks     int
+	Replaced   bool // an existing document with the same source was updated
+}
+
+// ingest stores a document as a parent memory with one child memory per chunk.
+// If a document with the same source already exists, its text and chunks are
+// replaced and its ID is kept.
+func (p *pipeline) ingest(ctx context.Context, doc document, opts chunk.Options) (*ingestOutcome, error) {
+	if strings.TrimSpace(doc.Text) == "" {
+		return nil, fmt.Errorf("document is empty")
+	}
+
+	redacted, err := p.redactor.Apply(doc.Text)
+	if err != nil {
+		return nil, err
+	}
+	doc.Text = redacted.Text
+
+	meta := mergeMeta(doc.Meta, map[string]any{"format": string(doc.Format)})
+	if doc.Title != "" {
+		meta["title"] = doc.Title
+	}
+
+	var existing *db.Memory
+	if doc.Source != nil {
+		var err error
+		existing, err = p.database.FindDocumentBySource(ctx, *doc.Source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	outcome := &ingestOutcome{}
+	if existing != nil {
+		kind := db.KindDocument
+		err := p.database.UpdateMemory(ctx, existing.ID, db.UpdateMemoryParams{
+			Kind:       &kind,
+			Text:       &doc.Text,
+			Tags:       doc.Tags,
+			Importance: &doc.Importance,
+			Meta:       redacted.Annotate(mergeMeta(existing.Meta, meta)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("update document: %w", err)
+		}
+		outcome.DocumentID = existing.ID
+		outcome.Replaced = true
+	} else {
+		id, err := p.database.AddMemory(ctx, db.AddMemoryParams{
+			Kind:       db.KindDocument,
+			Text:       doc.Text,
+			Source:     doc.Source,
+			Tags:       doc.Tags,
+			Importance: doc.Importance,
+			Meta:       redacted.Annotate(meta),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("add document: %w", err)
+		}
+		outcome.DocumentID = id
+	}
+
+	n, out, err := p.rechunk(ctx, outcome.DocumentID, opts)
+	if err != nil {
+		if existing == nil {
+			// Don't leave a document without chunks behind
+			if delErr := p.database.DeleteMemory(ctx, outcome.DocumentID); delErr != nil {
+				log.Printf("cortex: warning: failed to remove document %d after failed ingest: %v", outcome.DocumentID, delErr)
+			}
+		} else {
+			// The old chunks no longer match the new text; drop the content
+			// hash so that the next sync ingests the file again
+			stale := redacted.Annotate(mergeMeta(existing.Meta, meta))
+			delete(stale, "content_hash")
+			if updErr := p.database.UpdateMemory(ctx, outcome.DocumentID, db.UpdateMemoryParams{Meta: stale}); updErr != nil {
+				log.Printf("cortex: warning: failed to mark document %d for re-ingest: %v", outcome.DocumentID, updErr)
+			}
+		}
+		return nil, err
+	}
+	outcome.Chunks = n
+	outcome.writeOutcome = out
+	outcome.Warnings = append(redactionWarnings(redacted), outcome.Warnings...)
+
+	log.Printf("cortex: ingested document %d (%d chunks)", outcome.DocumentID, n)
+	return outcome, nil
+}
+
+// rechunk replaces a document's chunks with chunks of its current text,
+// then embeds them and extracts their entities. Chunks of a document marked
+// no_llm are marked too.
+func (p *pipeline) rechunk(ctx context.Context, documentID int64, opts chunk.Options) (int, writeOutcome, error) {
+	var out writeOutcome
+
+	parent, err := p.database.GetMemory(ctx, documentID)
+	if err != nil {
+		return 0, out, fmt.Errorf("get document: %w", err)
+	}
+	if parent == nil {
+		return 0, out, fmt.Errorf("document not found")
+	}
+
+	format, _ := parent.Meta["format"].(string)
+	chunks := chunk.Split(parent.Text, chunk.Format(format), opts)
+
+	params := make([]db.AddMemoryParams, len(chunks))
+	for i, c := range chunks {
+		index := c.Index
+		meta := map[string]any{
+			"start_line": c.StartLine,
+			"end_line":   c.EndLine,
+		}
+		if c.Section != "" {
+			meta["section"] = c.Section
+		}
+		if redact.SkipsLLM(parent.Meta) {
+			meta[redact.MetaNoLLM] = parent.Meta[redact.MetaNoLLM]
+		}
+		params[i] = db.AddMemoryParams{
+			Kind:       db.KindChunk,
+			Text:       c.Text,
+			Source:     parent.Source,
+			Tags:       parent.Tags,
+			Importance: parent.Importance,
+			Meta:       meta,
+			ChunkIndex: &index,
+		}
+	}
+
+	// The old chunks stay until all new ones are stored
+	ids, err := p.database.ReplaceChunks(ctx, documentID, params)
+	if err != nil {
+		return 0, out, err
+	}
+
+	pending := make(map[string]bool)
+	for i, id := range ids {
+		chunkOut := p.afterWrite(ctx, id, db.KindChunk, params[i].Text, params[i].Meta)
+		out.Warnings = append(out.Warnings, chunkOut.Warnings...)
+		for _, kind := range chunkOut.Pending {
+			if !pending[kind] {
+				pending[kind] = true
+				out.Pending = append(out.Pending, kind)
+			}
+		}
+	}
+
+	meta := mergeMeta(parent.Meta, map[string]any{"chunk_count": len(chunks)})
+	if err := p.database.UpdateMemory(ctx, documentID, db.UpdateMemoryParams{Meta: meta}); err != nil {
+		log.Printf("cortex: warning: failed to record chunk count for document %d: %v", documentID, err)
+	}
+
+	return len(chunks), out, nil
+}
+
+// mergeMeta returns a copy of base with the entries of overlay applied.
+func mergeMeta(base, overlay map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(overlay))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		merged[k] = v
+	}
+	return merged
+}
+
+// readDocument reads a file for ingestion. The source is "file:<absolute path>"
+// and the title defaults to the file name.
+func readDocument(path string) (document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return document{}, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	source := "file:" + abs
+
+	return document{
+		Text:       string(data),
+		Title:      filepath.Base(path),
+		Format:     chunk.DetectFormat(path),
+		Source:     &source,
+		Importance: 0.5,
+	}, nil
+}
+
+func createIngestHandler(pipe *pipeline) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryIngestArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		if (args.Text == "") == (args.Path == "") {
+			return nil, fmt.Errorf("exactly one of text or path is required")
+		}
+
+		var doc document
+		if args.Path != "" {
+			var err error
+			doc, err = readDocument(args.Path)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			doc = document{Text: args.Text, Format: chunk.FormatText, Importance: 0.5}
+		}
+
+		if args.Format != "" {
+			format, err := chunk.ParseFormat(args.Format)
+			if err != nil {
+				return nil, err
+			}
+			doc.Format = format
+		}
+		if args.Title != "" {
+			doc.Title = args.Title
+		}
+		if args.Source != nil {
+			doc.Source = args.Source
+		}
+		if args.Importance != nil {
+			doc.Importance = *args.Importance
+		}
+		doc.Tags = args.Tags
+
+		outcome, err := pipe.ingest(ctx, doc, chunk.DefaultOptions())
+		if err != nil {
+			return nil, fmt.Errorf("ingest: %w", err)
+		}
+		recordAudit(ctx, pipe.database, map[string]any{"chunks": outcome.Chunks, "replaced": outcome.Replaced}, outcome.DocumentID)
+
+		return mcp.MemoryIngestResult{
+			ID:       outcome.DocumentID,
+			Chunks:   outcome.Chunks,
+			Replaced: outcome.Replaced,
+			Warnings: outcome.Warnings,
+			Pending:  outcome.Pending,
+		}, nil
+	}
+}
@@ -9,16 +9,24 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/johnswift/cortex/internal/access"
+	"github.com/johnswift/cortex/internal/chunk"
+	"github.com/johnswift/cortex/internal/conflict"
 	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/encryption"
 	"github.com/johnswift/cortex/internal/entity"
+	"github.com/johnswift/cortex/internal/jobs"
 	"github.com/johnswift/cortex/internal/llm"
 	"github.com/johnswift/cortex/internal/mcp"
+	"github.com/johnswift/cortex/internal/redact"
 	"github.com/johnswift/cortex/internal/reembed"
 	"github.com/johnswift/cortex/internal/search"
 	"github.com/johnswift/cortex/internal/sweeper"
@@ -27,19 +35,52 @@ import (
 
 // Config holds all configuration for the Cortex server.
 type Config struct {
-	DatabaseURL       string
-	TenantID          string
-	WorkspaceID       string
-	LMBackend         string
-	LMModel           string
-	EmbedModel        string
-	EmbedModels       string // Comma-separated list for multi-model embeddings
-	OpenAIKey         string
-	GeminiKey         string
-	SweeperEnabled    bool
-	SweeperInterval   time.Duration
-	HealthPort        string
-	EntityExtraction  bool // Enable LLM-based entity extraction
+	DatabaseURL         string
+	TenantID            string
+	WorkspaceID         string
+	ChatBackend         string // Backend of the chat model; LM_BACKEND unless CHAT_BACKEND is set
+	EmbedBackend        string // Backend of the embedding models; LM_BACKEND unless EMBED_BACKEND is set
+	LMModel             string
+	EmbedModel          string
+	EmbedModels         string // Comma-separated list for multi-model embeddings
+	OpenAIKey           string
+	GeminiKey           string
+	AnthropicKey        string
+	ChatBaseURL         string // Server of a local chat backend
+	EmbedBaseURL        string // Server of a local embedding backend
+	LMAPIKey            string // API key of the openai-compatible backend, if it requires one
+	SweeperEnabled      bool
+	SweeperInterval     time.Duration
+	SweeperGlobal       bool                // Sweep every workspace of every tenant, not just WORKSPACE_ID
+	TodoArchiveDays     int                 // Archive todos this many days after completion (0 = never)
+	EvictUnaccessedDays int                 // Evict memories not accessed for this many days (0 = never)
+	RetentionPolicies   []sweeper.Policy    // Loaded from the RETENTION_POLICIES file
+	ArchiveMode         sweeper.ArchiveMode // delete, table or file
+	ArchiveDir          string              // Directory for archive files when ArchiveMode is file
+	AccessFlushInterval time.Duration       // How often batched access counts are written
+	SearchAccessWeight  float32             // Boost for frequently accessed memories in search (0 = disabled)
+	HealthPort          string
+	EntityExtraction    bool     // Enable LLM-based entity extraction
+	ConflictDetection   bool     // Enable LLM-based contradiction detection on add
+	NormalizeMemories   bool     // Normalize memory text with the chat model by default
+	AsyncProcessing     bool     // Queue embedding/extraction instead of running them inline
+	JobWorkers          int      // Number of background job workers
+	SharedWorkspace     string   // Workspace memory.add writes to with scope "shared"
+	InheritWorkspaces   []string // Workspaces searched along with WORKSPACE_ID by default
+	DetectWorkspace     bool     // WORKSPACE_ID unset: detected from git and the client's roots
+	AllowedWorkspaces   []string // Workspaces tool calls may select; "*" allows any
+	HTTPAddr            string   // Serve MCP over HTTP on this address instead of stdio
+	APIKey              string   // API key limiting what a stdio session may do
+	AllowRLSBypass      bool     // Start even if the database role bypasses row-level security
+
+	// Redaction
+	Redactor        *redact.Redactor // Applies REDACT_MODE to memory text; nil when off
+	RedactDetectors []string         // Detectors run by the redactor and cortex scan
+
+	// Encryption at rest
+	Keyring           *encryption.Keyring // Keys from ENCRYPTION_KEYFILE; nil when unset
+	EncryptWorkspaces []string            // Workspaces whose memory text and meta are sealed; "*" = all
+	EncryptBlindIndex bool                // Index sealed text for lexical search
 }
 
 // CLI flags for export/import/reembed operations
@@ -63,6 +104,14 @@ func main() {
 
 	flag.Parse()
 
+	// Check for subcommands (backfill, ...)
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Arg(0), flag.Args()[1:]); err != nil {
+			log.Fatalf("cortex: %v", err)
+		}
+		return
+	}
+
 	// Check for CLI mode (export/import/reembed)
 	if *exportFile != "" || *importFile != "" || *reembedAll {
 		if err := runCLI(); err != nil {
@@ -88,7 +137,7 @@ func run() error {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	log.Printf("cortex: starting MCP memory server (tenant=%s, workspace=%s, backend=%s)", cfg.TenantID, cfg.WorkspaceID, cfg.LMBackend)
+	log.Printf("cortex: starting MCP memory server (tenant=%s, workspace=%s, chat=%s, embed=%s)", cfg.TenantID, cfg.WorkspaceID, cfg.ChatBackend, cfg.EmbedBackend)
 
 	// Initialize database connection
 	database, err := db.NewWithWorkspace(ctx, cfg.DatabaseURL, cfg.TenantID, cfg.WorkspaceID)
@@ -96,6 +145,10 @@ func run() error {
 		return fmt.Errorf("connect to database: %w", err)
 	}
 	defer database.Close()
+	cfg.applyEncryption(database)
+	if len(cfg.EncryptWorkspaces) > 0 {
+		log.Printf("cortex: encrypting memories of workspaces %v with key %s", cfg.EncryptWorkspaces, cfg.Keyring.ActiveKey())
+	}
 
 	// Run migrations
 	log.Println("cortex: running database migrations")
@@ -103,31 +156,54 @@ func run() error {
 		return fmt.Errorf("run migrations: %w", err)
 	}
 
+	// Tenant isolation relies on row-level security, which some roles skip
+	if role, bypass, err := database.RoleBypassesRLS(ctx); err != nil {
+		log.Printf("cortex: warning: failed to check row-level security: %v", err)
+	} else if bypass && !cfg.AllowRLSBypass {
+		return fmt.Errorf("role %s bypasses row-level security, so tenants would not be isolated: connect as a NOSUPERUSER NOBYPASSRLS role that owns the tables (to move an existing database to one, see \"Upgrading an Existing Database\" in the README), or set ALLOW_RLS_BYPASS=true", role)
+	} else if bypass {
+		log.Printf("cortex: warning: role %s bypasses row-level security; tenants are isolated by query filters only", role)
+	}
+
 	// Initialize LLM provider
 	provider, err := initLLMProvider(cfg)
 	if err != nil {
 		return fmt.Errorf("init LLM provider: %w", err)
 	}
+	if localBackend(cfg.EmbedBackend) {
+		// Local models' dimensions are discovered, which also checks the server is up
+		if dims := provider.Dimensions(); dims > 0 {
+			log.Printf("cortex: embedding model %s has %d dimensions", provider.EmbedModel(), dims)
+		} else {
+			log.Printf("cortex: warning: could not reach embedding model %s of the %s backend", provider.EmbedModel(), cfg.EmbedBackend)
+		}
+	}
 
 	// Initialize multi-model embedder if EMBED_MODELS is configured
-	var multiEmbedder *llm.MultiEmbedder
-	if cfg.EmbedModels != "" {
-		var apiKey string
-		switch cfg.LMBackend {
-		case "openai":
-			apiKey = cfg.OpenAIKey
-		case "gemini":
-			apiKey = cfg.GeminiKey
-		}
-		multiEmbedder, err = llm.NewMultiEmbedder(cfg.LMBackend, apiKey, cfg.EmbedModels)
-		if err != nil {
-			return fmt.Errorf("init multi-embedder: %w", err)
-		}
+	multiEmbedder, err := initMultiEmbedder(cfg)
+	if err != nil {
+		return fmt.Errorf("init multi-embedder: %w", err)
+	}
+	if multiEmbedder != nil {
 		log.Printf("cortex: multi-model embeddings enabled (%v)", multiEmbedder.Models())
 	}
 
-	// Initialize hybrid searcher
+	// Initialize hybrid searcher, optionally favoring frequently accessed memories
 	searcher := search.NewHybridSearcher(database, provider)
+	if cfg.SearchAccessWeight > 0 {
+		searcher = searcher.WithRanking(search.RankingOptions{
+			AccessWeight:     cfg.SearchAccessWeight,
+			AccessSaturation: search.DefaultRankingOptions().AccessSaturation,
+		})
+		log.Printf("cortex: access-based search ranking enabled (weight=%.2f)", cfg.SearchAccessWeight)
+	}
+
+	// Start access tracking; reads are counted in memory and written in batches
+	tracker := access.NewTracker(database).WithConfig(access.Config{
+		FlushInterval: cfg.AccessFlushInterval,
+		MaxPending:    access.DefaultConfig().MaxPending,
+	})
+	tracker.Start(ctx)
 
 	// Initialize entity extractor if enabled
 	var extractor *entity.Extractor
@@ -136,34 +212,122 @@ func run() error {
 		log.Println("cortex: entity extraction enabled")
 	}
 
-	// Start health server if HEALTH_PORT is set
+	// Initialize contradiction detector if enabled
+	var detector *conflict.Detector
+	if cfg.ConflictDetection {
+		detector = conflict.NewDetector(provider)
+		log.Println("cortex: conflict detection enabled")
+	}
+
+	// Initialize normalizer (used per call, or for every write if NORMALIZE_MEMORIES is set)
+	normalizer := llm.NewNormalizer(provider)
+	if cfg.NormalizeMemories {
+		log.Println("cortex: memory normalization enabled")
+	}
+
+	// Start TTL sweeper if enabled
+	var sw *sweeper.Sweeper
+	if cfg.SweeperEnabled {
+		sweeperPool, err := cfg.sweeperPool(ctx, database)
+		if err != nil {
+			return err
+		}
+		if sweeperPool != database.Pool() {
+			defer sweeperPool.Close()
+		}
+		sw = sweeper.NewSweeper(sweeperPool, cfg.TenantID, cfg.WorkspaceID).WithConfig(cfg.sweeperConfig())
+		sw.Start(ctx, cfg.SweeperInterval)
+		log.Printf("cortex: TTL sweeper enabled (interval=%v)", cfg.SweeperInterval)
+		if cfg.TodoArchiveDays > 0 {
+			log.Printf("cortex: archiving todos %d days after completion", cfg.TodoArchiveDays)
+		}
+		if cfg.EvictUnaccessedDays > 0 {
+			log.Printf("cortex: evicting memories not accessed for %d days", cfg.EvictUnaccessedDays)
+		}
+		if len(cfg.RetentionPolicies) > 0 {
+			log.Printf("cortex: applying %d retention policies", len(cfg.RetentionPolicies))
+		}
+		if cfg.ArchiveMode != sweeper.ArchiveNone {
+			log.Printf("cortex: archiving removed memories (mode=%s)", cfg.ArchiveMode)
+		}
+		if cfg.SweeperGlobal {
+			log.Printf("cortex: sweeping all workspaces")
+		}
+	}
+
+	// Start health server if HEALTH_PORT is set; /metrics reports sweeper activity
 	var healthServer *mcp.HealthServer
 	if cfg.HealthPort != "" {
 		healthServer = mcp.NewHealthServer(cfg.HealthPort)
+		if sw != nil {
+			healthServer.AddMetrics("sweeper", func() any { return sw.Stats() })
+		}
 		if err := healthServer.Start(); err != nil {
 			return fmt.Errorf("start health server: %w", err)
 		}
 		defer healthServer.Shutdown(ctx)
 	}
 
-	// Start TTL sweeper if enabled
-	var sw *sweeper.Sweeper
-	if cfg.SweeperEnabled {
-		sw = sweeper.NewSweeper(database.Pool(), cfg.TenantID, cfg.WorkspaceID)
-		sw.Start(ctx, cfg.SweeperInterval)
-		log.Printf("cortex: TTL sweeper enabled (interval=%v)", cfg.SweeperInterval)
+	// Start job workers; they retry failed embeddings/extractions and run queued work
+	queue := jobs.NewQueue(database.Pool(), cfg.TenantID, cfg.WorkspaceID)
+	pipe := &pipeline{
+		database:      database,
+		provider:      provider,
+		multiEmbedder: multiEmbedder,
+		extractor:     extractor,
+		detector:      detector,
+		queue:         queue,
+		async:         cfg.AsyncProcessing,
+		redactor:      cfg.Redactor,
+		tracker:       tracker,
+	}
+	workerCfg := jobs.DefaultWorkerConfig()
+	workerCfg.Concurrency = cfg.JobWorkers
+	router := newWorkspaceRouter(ctx, pipe, workerCfg, cfg.AllowedWorkspaces, append([]string{cfg.SharedWorkspace}, cfg.InheritWorkspaces...), cfg.DetectWorkspace)
+
+	if cfg.AsyncProcessing {
+		log.Printf("cortex: async processing enabled (workers=%d)", cfg.JobWorkers)
 	}
 
 	// Create MCP server
 	server := mcp.NewServer("cortex", "1.0.0")
+	if cfg.DetectWorkspace && cfg.HTTPAddr == "" {
+		// Follow the project the client works in, if it tells us its roots
+		server.OnRoots(router.onRoots)
+		log.Printf("cortex: WORKSPACE_ID unset, detecting workspace from git (detected %s)", cfg.WorkspaceID)
+	}
+	if len(cfg.AllowedWorkspaces) > 0 {
+		log.Printf("cortex: tools may select workspaces %v", cfg.AllowedWorkspaces)
+	}
 
 	// Register memory tools
-	registerMemoryTools(server, database, provider, multiEmbedder, searcher, extractor)
+	scopes := workspaceScopes{Own: cfg.WorkspaceID, Shared: cfg.SharedWorkspace, Inherit: cfg.InheritWorkspaces}
+	registerMemoryTools(server, router, searcher, normalizer, cfg.NormalizeMemories, cfg.sweeperStatus(), scopes)
+
+	// Check API keys, of any tenant; network clients must present one
+	keysPool, err := db.NewAllTenantsPool(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("connect to database for API keys: %w", err)
+	}
+	defer keysPool.Close()
+	authorizer := &keyAuthorizer{keys: keysPool, router: router, scopes: scopes, required: cfg.HTTPAddr != ""}
+	server.SetAuthorizer(authorizer.authorize)
 
 	// Run the MCP server (blocks until context is cancelled)
-	log.Println("cortex: MCP server ready, listening on stdio")
-	if err := server.Run(ctx); err != nil {
-		return fmt.Errorf("run server: %w", err)
+	if cfg.HTTPAddr != "" {
+		if err := serveHTTP(ctx, server, cfg.HTTPAddr); err != nil {
+			return fmt.Errorf("run server: %w", err)
+		}
+	} else {
+		serveCtx := ctx
+		if cfg.APIKey != "" {
+			// Limit this stdio session to what the key grants
+			serveCtx = mcp.ContextWithAPIKey(ctx, cfg.APIKey)
+		}
+		log.Println("cortex: MCP server ready, listening on stdio")
+		if err := server.Run(serveCtx); err != nil {
+			return fmt.Errorf("run server: %w", err)
+		}
 	}
 
 	// Stop sweeper gracefully
@@ -171,10 +335,42 @@ func run() error {
 		sw.Stop()
 	}
 
+	// Wait for in-flight jobs and the final access count flush
+	cancel()
+	router.stop()
+	tracker.Stop()
+
 	log.Println("cortex: shutting down gracefully")
 	return nil
 }
 
+// serveHTTP serves MCP requests at /mcp on addr until ctx is cancelled.
+func serveHTTP(ctx context.Context, server *mcp.Server, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", server)
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+	log.Printf("cortex: MCP server ready, listening on http://%s/mcp", addr)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return httpServer.Shutdown(shutdownCtx)
+}
+
 func loadConfig() (*Config, error) {
 	// Parse sweeper interval
 	sweeperIntervalStr := getEnv("SWEEPER_INTERVAL", "1h")
@@ -189,26 +385,92 @@ func loadConfig() (*Config, error) {
 		sweeperEnabled = false
 	}
 
+	// Parse global sweeping (default: false, each process sweeps its own workspace)
+	sweeperGlobal := false
+	if v := getEnv("SWEEPER_GLOBAL", "false"); v == "true" || v == "1" {
+		sweeperGlobal = true
+	}
+
+	// Parse access tracking flush interval
+	accessFlushInterval, err := time.ParseDuration(getEnv("ACCESS_FLUSH_INTERVAL", "30s"))
+	if err != nil || accessFlushInterval <= 0 {
+		return nil, fmt.Errorf("invalid ACCESS_FLUSH_INTERVAL: must be a positive duration")
+	}
+
+	// Parse access boost for search ranking (default: 0, ranking ignores access counts)
+	searchAccessWeight, err := strconv.ParseFloat(getEnv("SEARCH_ACCESS_WEIGHT", "0"), 32)
+	if err != nil || searchAccessWeight < 0 {
+		return nil, fmt.Errorf("invalid SEARCH_ACCESS_WEIGHT: must be a non-negative number")
+	}
+
 	// Parse entity extraction enabled (default: false for backward compat)
 	entityExtraction := false
 	if v := getEnv("ENTITY_EXTRACTION", "false"); v == "true" || v == "1" {
 		entityExtraction = true
 	}
 
+	// Parse conflict detection enabled (default: false, costs one LLM call per fact/preference)
+	conflictDetection := false
+	if v := getEnv("CONFLICT_DETECTION", "false"); v == "true" || v == "1" {
+		conflictDetection = true
+	}
+
+	// Parse async processing enabled (default: true, memory.add returns before embedding)
+	asyncProcessing := true
+	if v := getEnv("ASYNC_PROCESSING", "true"); v == "false" || v == "0" {
+		asyncProcessing = false
+	}
+
+	// Parse row-level security bypass allowed (default: false, refuse to start)
+	allowRLSBypass := false
+	if v := getEnv("ALLOW_RLS_BYPASS", "false"); v == "true" || v == "1" {
+		allowRLSBypass = true
+	}
+
+	// Parse job worker count
+	jobWorkers, err := strconv.Atoi(getEnv("JOB_WORKERS", "2"))
+	if err != nil || jobWorkers < 1 {
+		return nil, fmt.Errorf("invalid JOB_WORKERS: must be a positive integer")
+	}
+
+	// Parse memory normalization enabled (default: false, callers can opt in per memory)
+	normalizeMemories := false
+	if v := getEnv("NORMALIZE_MEMORIES", "false"); v == "true" || v == "1" {
+		normalizeMemories = true
+	}
+
+	// Parse inherited workspaces (default: the shared workspace; empty inherits none)
+	sharedWorkspace := getEnv("SHARED_WORKSPACE", "shared")
+	inheritWorkspaces := []string{sharedWorkspace}
+	if v, ok := os.LookupEnv("INHERIT_WORKSPACES"); ok {
+		inheritWorkspaces = splitList(v)
+	}
+
+	// Detect the workspace from git when WORKSPACE_ID is unset
+	workspaceID, detectWorkspace := resolveWorkspace()
+
 	cfg := &Config{
-		DatabaseURL:       getEnv("DATABASE_URL", ""),
-		TenantID:          getEnv("TENANT_ID", "local"),
-		WorkspaceID:       getEnv("WORKSPACE_ID", "default"),
-		LMBackend:         getEnv("LM_BACKEND", "openai"),
-		LMModel:           getEnv("LM_MODEL", "auto"),
-		EmbedModel:        getEnv("EMBED_MODEL", "auto"),
-		EmbedModels:       getEnv("EMBED_MODELS", ""), // Comma-separated for multi-model
-		OpenAIKey:         getEnv("OPENAI_API_KEY", ""),
-		GeminiKey:         getEnv("GEMINI_API_KEY", ""),
-		SweeperEnabled:    sweeperEnabled,
-		SweeperInterval:   sweeperInterval,
-		HealthPort:        getEnv("HEALTH_PORT", ""),
-		EntityExtraction:  entityExtraction,
+		DatabaseURL:         getEnv("DATABASE_URL", ""),
+		TenantID:            getEnv("TENANT_ID", "local"),
+		WorkspaceID:         workspaceID,
+		SweeperEnabled:      sweeperEnabled,
+		SweeperInterval:     sweeperInterval,
+		SweeperGlobal:       sweeperGlobal,
+		AccessFlushInterval: accessFlushInterval,
+		SearchAccessWeight:  float32(searchAccessWeight),
+		HealthPort:          getEnv("HEALTH_PORT", ""),
+		EntityExtraction:    entityExtraction,
+		ConflictDetection:   conflictDetection,
+		NormalizeMemories:   normalizeMemories,
+		AsyncProcessing:     asyncProcessing,
+		JobWorkers:          jobWorkers,
+		SharedWorkspace:     sharedWorkspace,
+		InheritWorkspaces:   inheritWorkspaces,
+		DetectWorkspace:     detectWorkspace,
+		AllowedWorkspaces:   splitList(getEnv("ALLOWED_WORKSPACES", "")),
+		HTTPAddr:            getEnv("MCP_HTTP_ADDR", ""),
+		APIKey:              getEnv("CORTEX_API_KEY", ""),
+		AllowRLSBypass:      allowRLSBypass,
 	}
 
 	// Validate required configuration
@@ -216,51 +478,186 @@ func loadConfig() (*Config, error) {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
 	}
 
-	// Validate LLM backend and API key
-	switch cfg.LMBackend {
-	case "openai":
-		if cfg.OpenAIKey == "" {
-			return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required when LM_BACKEND=openai")
+	if err := loadBackendConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := loadSweeperConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := loadRedactConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := loadEncryptionConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateBackends(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadBackendConfig reads the LLM backend settings into cfg. LM_BACKEND selects
+// the backend of both the chat and embedding models; CHAT_BACKEND and
+// EMBED_BACKEND override it for one of them.
+func loadBackendConfig(cfg *Config) error {
+	backend := getEnv("LM_BACKEND", "openai")
+	baseURL := getEnv("LM_BASE_URL", "")
+	cfg.ChatBackend = getEnv("CHAT_BACKEND", backend)
+	cfg.EmbedBackend = getEnv("EMBED_BACKEND", backend)
+	cfg.ChatBaseURL = getEnv("CHAT_BASE_URL", baseURL)
+	cfg.EmbedBaseURL = getEnv("EMBED_BASE_URL", baseURL)
+	cfg.LMModel = getEnv("LM_MODEL", "auto")
+	cfg.EmbedModel = getEnv("EMBED_MODEL", "auto")
+	cfg.EmbedModels = getEnv("EMBED_MODELS", "") // Comma-separated for multi-model
+	cfg.OpenAIKey = getEnv("OPENAI_API_KEY", "")
+	cfg.GeminiKey = getEnv("GEMINI_API_KEY", "")
+	cfg.AnthropicKey = getEnv("ANTHROPIC_API_KEY", "")
+	cfg.LMAPIKey = getEnv("LM_API_KEY", "")
+
+	// Local embeddings are named for their dimensions, so changing them
+	// embeds memories anew rather than mixing sizes under one model
+	if cfg.EmbedBackend == "local" && cfg.EmbedModel == "auto" {
+		dims, err := strconv.Atoi(getEnv("EMBED_DIMENSIONS", strconv.Itoa(llm.DefaultLocalDimensions)))
+		if err != nil || dims < llm.MinLocalDimensions || dims > llm.MaxLocalDimensions {
+			return fmt.Errorf("EMBED_DIMENSIONS must be between %d and %d", llm.MinLocalDimensions, llm.MaxLocalDimensions)
 		}
-	case "gemini":
-		if cfg.GeminiKey == "" {
-			return nil, fmt.Errorf("GEMINI_API_KEY environment variable is required when LM_BACKEND=gemini")
+		cfg.EmbedModel = llm.LocalEmbedModel(dims)
+	}
+	return nil
+}
+
+// validateBackends checks that the chat and embedding backends exist and have
+// their API keys.
+func validateBackends(cfg *Config) error {
+	switch cfg.ChatBackend {
+	case "openai", "gemini", "anthropic", "ollama", "openai-compatible":
+		if key, name := backendKey(cfg, cfg.ChatBackend); name != "" && key == "" {
+			return fmt.Errorf("%s environment variable is required for the %s chat backend", name, cfg.ChatBackend)
+		}
+	case "local":
+		// The local backend has no chat model
+		if cfg.EntityExtraction || cfg.ConflictDetection || cfg.NormalizeMemories {
+			return fmt.Errorf("ENTITY_EXTRACTION, CONFLICT_DETECTION and NORMALIZE_MEMORIES need a chat model: set CHAT_BACKEND to a backend other than local")
 		}
 	default:
-		return nil, fmt.Errorf("invalid LM_BACKEND: %q (must be 'openai' or 'gemini')", cfg.LMBackend)
+		return fmt.Errorf("invalid CHAT_BACKEND: %q (must be 'openai', 'gemini', 'anthropic', 'ollama', 'openai-compatible' or 'local')", cfg.ChatBackend)
 	}
 
-	return cfg, nil
+	switch cfg.EmbedBackend {
+	case "openai", "gemini", "ollama", "openai-compatible", "local":
+		if key, name := backendKey(cfg, cfg.EmbedBackend); name != "" && key == "" {
+			return fmt.Errorf("%s environment variable is required for the %s embedding backend", name, cfg.EmbedBackend)
+		}
+	case "anthropic":
+		return fmt.Errorf("anthropic serves no embeddings: set EMBED_BACKEND to another backend")
+	default:
+		return fmt.Errorf("invalid EMBED_BACKEND: %q (must be 'openai', 'gemini', 'ollama', 'openai-compatible' or 'local')", cfg.EmbedBackend)
+	}
+	// Served model names cannot be guessed
+	if cfg.EmbedBackend == "openai-compatible" && (cfg.EmbedModel == "" || cfg.EmbedModel == "auto") {
+		return fmt.Errorf("EMBED_MODEL environment variable is required when EMBED_BACKEND=openai-compatible")
+	}
+	return nil
 }
 
+// initLLMProvider combines the chat model of CHAT_BACKEND with the embedding
+// model of EMBED_BACKEND.
 func initLLMProvider(cfg *Config) (llm.Provider, error) {
-	var apiKey string
-	switch cfg.LMBackend {
+	chatKey, _ := backendKey(cfg, cfg.ChatBackend)
+	chat, err := llm.NewChatProvider(cfg.ChatBackend, chatKey, cfg.ChatBaseURL, cfg.LMModel)
+	if err != nil {
+		return nil, fmt.Errorf("chat backend: %w", err)
+	}
+	embedKey, _ := backendKey(cfg, cfg.EmbedBackend)
+	embed, err := llm.NewEmbeddingProvider(cfg.EmbedBackend, embedKey, cfg.EmbedBaseURL, cfg.EmbedModel)
+	if err != nil {
+		return nil, fmt.Errorf("embedding backend: %w", err)
+	}
+	return llm.NewSplitProvider(chat, embed), nil
+}
+
+// initMultiEmbedder creates the multi-model embedder, or returns nil if EMBED_MODELS is unset.
+func initMultiEmbedder(cfg *Config) (*llm.MultiEmbedder, error) {
+	if cfg.EmbedModels == "" {
+		return nil, nil
+	}
+	key, _ := backendKey(cfg, cfg.EmbedBackend)
+	return llm.NewMultiEmbedder(cfg.EmbedBackend, key, cfg.EmbedBaseURL, cfg.EmbedModels)
+}
+
+// backendKey returns the API key of a backend and the variable it is read
+// from. The variable is empty for backends that do not require a key.
+func backendKey(cfg *Config, backend string) (key, name string) {
+	switch backend {
 	case "openai":
-		apiKey = cfg.OpenAIKey
+		return cfg.OpenAIKey, "OPENAI_API_KEY"
 	case "gemini":
-		apiKey = cfg.GeminiKey
+		return cfg.GeminiKey, "GEMINI_API_KEY"
+	case "anthropic":
+		return cfg.AnthropicKey, "ANTHROPIC_API_KEY"
+	case "openai-compatible":
+		return cfg.LMAPIKey, ""
 	}
-	return llm.NewProvider(cfg.LMBackend, apiKey, cfg.LMModel, cfg.EmbedModel)
+	return "", ""
+}
+
+// localBackend reports whether backend serves models locally rather than from a cloud API.
+func localBackend(backend string) bool {
+	return backend == "ollama" || backend == "openai-compatible"
 }
 
-func registerMemoryTools(server *mcp.Server, database *db.DB, provider llm.Provider, multiEmbedder *llm.MultiEmbedder, searcher *search.HybridSearcher, extractor *entity.Extractor) {
-	// Register all memory tools with their handlers
-	server.RegisterTool(mcp.MemoryAddTool(), createAddHandler(database, provider, multiEmbedder, extractor))
-	server.RegisterTool(mcp.MemorySearchTool(), createSearchHandler(searcher))
-	server.RegisterTool(mcp.MemoryUpdateTool(), createUpdateHandler(database, provider, multiEmbedder))
-	server.RegisterTool(mcp.MemoryDeleteTool(), createDeleteHandler(database))
-	server.RegisterTool(mcp.MemoryExportTool(), createExportHandler(database))
-	server.RegisterTool(mcp.MemoryImportTool(), createImportHandler(database, provider))
+func registerMemoryTools(server *mcp.Server, router *workspaceRouter, searcher *search.HybridSearcher, normalizer *llm.Normalizer, normalizeDefault bool, sweeperStatus mcp.MemorySweeperStatusResult, scopes workspaceScopes) {
+	// Each call builds its handler for the workspace it selects
+	type memoryTool struct {
+		tool  mcp.Tool
+		build func(pipe *pipeline) mcp.Handler
+	}
+	tools := []memoryTool{
+		{mcp.MemoryAddTool(), func(p *pipeline) mcp.Handler {
+			return createAddHandler(p, normalizer, normalizeDefault, scopes.forWorkspace(p.database.WorkspaceID()))
+		}},
+		{mcp.MemorySearchTool(), func(p *pipeline) mcp.Handler {
+			return createSearchHandler(searcher.WithDB(p.database), p.tracker, scopes.forWorkspace(p.database.WorkspaceID()))
+		}},
+		{mcp.MemoryGetTool(), func(p *pipeline) mcp.Handler { return createGetHandler(p.database, p.tracker) }},
+		{mcp.MemoryUpdateTool(), func(p *pipeline) mcp.Handler { return createUpdateHandler(p, normalizer, normalizeDefault) }},
+		{mcp.MemoryDeleteTool(), func(p *pipeline) mcp.Handler { return createDeleteHandler(p.database) }},
+		{mcp.MemoryExportTool(), func(p *pipeline) mcp.Handler { return createExportHandler(p.database) }},
+		{mcp.MemoryImportTool(), func(p *pipeline) mcp.Handler { return createImportHandler(p.database, p.provider, p.redactor) }},
+		{mcp.MemoryIngestTool(), createIngestHandler},
+		{mcp.MemoryIngestConversationTool(), createIngestConversationHandler},
+		{mcp.MemoryBackfillTool(), createBackfillHandler},
+		{mcp.MemoryLinkTool(), func(p *pipeline) mcp.Handler { return createLinkHandler(p.database) }},
+		{mcp.MemoryUnlinkTool(), func(p *pipeline) mcp.Handler { return createUnlinkHandler(p.database) }},
+		{mcp.MemoryRelatedTool(), func(p *pipeline) mcp.Handler { return createRelatedHandler(p.database) }},
+		{mcp.MemoryTodoListTool(), func(p *pipeline) mcp.Handler { return createTodoListHandler(p.database) }},
+		{mcp.MemoryTodoCompleteTool(), func(p *pipeline) mcp.Handler { return createTodoCompleteHandler(p.database) }},
+		{mcp.MemorySweeperStatusTool(), func(p *pipeline) mcp.Handler { return createSweeperStatusHandler(p.database, sweeperStatus) }},
+		{mcp.MemoryAuditTool(), func(p *pipeline) mcp.Handler { return createAuditHandler(p.database) }},
+		{mcp.MemoryWorkspacesTool(), func(p *pipeline) mcp.Handler { return createWorkspacesHandler(p.database, router.allows) }},
+		{mcp.MemoryWorkspaceRenameTool(), func(p *pipeline) mcp.Handler { return createWorkspaceMoveHandler(p.database, "rename") }},
+		{mcp.MemoryWorkspaceCloneTool(), func(p *pipeline) mcp.Handler { return createWorkspaceMoveHandler(p.database, "clone") }},
+		{mcp.MemoryWorkspaceMergeTool(), func(p *pipeline) mcp.Handler { return createWorkspaceMoveHandler(p.database, "merge") }},
+	}
 
 	// Register entity tools if extractor is enabled
-	if extractor != nil {
-		server.RegisterTool(mcp.MemoryEntitiesTool(), createEntitiesHandler(database))
-		server.RegisterTool(mcp.MemoryRelatedTool(), createRelatedHandler(database))
+	if router.own.base.extractor != nil {
+		tools = append(tools, memoryTool{mcp.MemoryEntitiesTool(), func(p *pipeline) mcp.Handler { return createEntitiesHandler(p.database) }})
+	}
+
+	// Register conflict tools if detector is enabled
+	if router.own.base.detector != nil {
+		tools = append(tools, memoryTool{mcp.MemoryConflictsTool(), func(p *pipeline) mcp.Handler { return createConflictsHandler(p.database) }})
+	}
+
+	for _, t := range tools {
+		server.RegisterTool(mcp.WithWorkspaceArg(t.tool), router.handle(t.build))
 	}
 }
 
-func createAddHandler(database *db.DB, provider llm.Provider, multiEmbedder *llm.MultiEmbedder, extractor *entity.Extractor) mcp.Handler {
+func createAddHandler(pipe *pipeline, normalizer *llm.Normalizer, normalizeDefault bool, scopes workspaceScopes) mcp.Handler {
 	return func(ctx context.Context, params json.RawMessage) (any, error) {
 		var args mcp.MemoryAddArgs
 		if err := json.Unmarshal(params, &args); err != nil {
@@ -282,102 +679,102 @@ func createAddHandler(database *db.DB, provider llm.Provider, multiEmbedder *llm
 			importance = *args.Importance
 		}
 
+		if args.Todo != nil && kind != db.KindTodo {
+			return nil, fmt.Errorf("todo fields require kind %q", db.KindTodo)
+		}
+
+		// Write to the workspace selected by scope
+		pipe, err := scopes.addPipeline(pipe, args.Scope)
+		if err != nil {
+			return nil, err
+		}
+
+		var ttlFrom string
+		if args.TTLFrom != nil {
+			if !db.ValidTTLFrom(*args.TTLFrom) {
+				return nil, fmt.Errorf("invalid ttl_from %q: must be created, updated or accessed", *args.TTLFrom)
+			}
+			ttlFrom = *args.TTLFrom
+		}
+
+		// Apply REDACT_MODE, then normalize text if requested, keeping the raw input in meta
+		redacted, err := pipe.redactor.Apply(args.Text)
+		if err != nil {
+			return nil, err
+		}
+		text := redacted.Text
+		var meta map[string]any
+		var warnings []string
+		if !redacted.NoLLM {
+			text, meta, warnings = normalizeText(ctx, normalizer, text, args.Normalize, normalizeDefault)
+		}
+		meta = redacted.Annotate(meta)
+		warnings = append(warnings, redactionWarnings(redacted)...)
+
 		// Add memory to database
-		id, err := database.AddMemory(ctx, db.AddMemoryParams{
+		id, err := pipe.database.AddMemory(ctx, db.AddMemoryParams{
 			Kind:       kind,
-			Text:       args.Text,
+			Text:       text,
 			Source:     args.Source,
 			Tags:       args.Tags,
 			Importance: importance,
 			TTLDays:    args.TTLDays,
-			Meta:       nil,
+			TTLFrom:    ttlFrom,
+			Meta:       meta,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("add memory: %w", err)
 		}
 
-		// Generate and store embeddings
-		if multiEmbedder != nil {
-			// Multi-model: generate embeddings from all configured models
-			embeddings, err := multiEmbedder.EmbedAll(ctx, args.Text)
-			if err != nil {
-				log.Printf("cortex: warning: failed to generate multi-model embeddings for memory %d: %v", id, err)
-			} else {
-				for model, embedding := range embeddings {
-					if err := database.AddEmbedding(ctx, id, model, embedding); err != nil {
-						log.Printf("cortex: warning: failed to store embedding (model=%s) for memory %d: %v", model, id, err)
-					}
-				}
-			}
-		} else {
-			// Single-model: use the default provider
-			embedding, err := provider.Embed(ctx, args.Text)
-			if err != nil {
-				log.Printf("cortex: warning: failed to generate embedding for memory %d: %v", id, err)
-			} else {
-				if err := database.AddEmbedding(ctx, id, provider.EmbedModel(), embedding); err != nil {
-					log.Printf("cortex: warning: failed to store embedding for memory %d: %v", id, err)
+		if args.Todo != nil {
+			if err := setTodoFields(ctx, pipe.database, id, *args.Todo); err != nil {
+				// Don't leave a todo behind without the requested fields
+				if delErr := pipe.database.DeleteMemory(ctx, id); delErr != nil {
+					log.Printf("cortex: warning: failed to remove memory %d after invalid todo fields: %v", id, delErr)
 				}
+				return nil, fmt.Errorf("set todo fields: %w", err)
 			}
 		}
 
-		// Extract and store entities if enabled
-		if extractor != nil {
-			extractAndStoreEntities(ctx, database, extractor, id, args.Text)
-		}
+		recordAudit(ctx, pipe.database, map[string]any{"kind": kind}, id)
 
-		return mcp.MemoryAddResult{ID: id}, nil
+		// Embed, extract entities and check for conflicts (inline or queued)
+		out := pipe.afterWrite(ctx, id, kind, text, meta)
+
+		return mcp.MemoryAddResult{
+			ID:        id,
+			Workspace: pipe.database.WorkspaceID(),
+			Conflicts: out.Conflicts,
+			Warnings:  append(warnings, out.Warnings...),
+			Pending:   out.Pending,
+		}, nil
 	}
 }
 
-// extractAndStoreEntities extracts entities from text and links them to the memory.
-func extractAndStoreEntities(ctx context.Context, database *db.DB, extractor *entity.Extractor, memoryID int64, text string) {
-	result, err := extractor.Extract(ctx, text)
-	if err != nil {
-		log.Printf("cortex: warning: failed to extract entities for memory %d: %v", memoryID, err)
-		return
+// normalizeText rewrites text with the normalizer when requested per call or, if the
+// call does not say, when normalizeDefault is set. When the text changes, the raw
+// input is returned in meta as "original_text". On failure the raw text is kept and
+// a warning is returned.
+func normalizeText(ctx context.Context, normalizer *llm.Normalizer, text string, normalize *bool, normalizeDefault bool) (string, map[string]any, []string) {
+	enabled := normalizeDefault
+	if normalize != nil {
+		enabled = *normalize
 	}
-
-	if len(result.Entities) == 0 {
-		return
+	if !enabled || normalizer == nil {
+		return text, nil, nil
 	}
 
-	// Store entities and link to memory
-	entityIDMap := make(map[string]int64) // name -> id for relation linking
-	for _, e := range result.Entities {
-		entityID, err := database.AddEntity(ctx, db.AddEntityParams{
-			Name:        e.Name,
-			Type:        db.EntityType(e.Type),
-			Aliases:     e.Aliases,
-			Description: ptrIfNotEmpty(e.Description),
-			Meta:        e.Meta,
-		})
-		if err != nil {
-			log.Printf("cortex: warning: failed to store entity %q: %v", e.Name, err)
-			continue
-		}
-
-		entityIDMap[e.Name] = entityID
-
-		// Link entity to memory
-		if err := database.LinkMemoryEntity(ctx, memoryID, entityID, ptrIfNotEmpty(e.Role), e.Confidence); err != nil {
-			log.Printf("cortex: warning: failed to link entity %q to memory %d: %v", e.Name, memoryID, err)
-		}
+	normalized, err := normalizer.Normalize(ctx, text)
+	if err != nil {
+		log.Printf("cortex: warning: failed to normalize memory text: %v", err)
+		return text, nil, []string{"normalization failed, stored original text"}
 	}
 
-	// Store entity relations
-	for _, r := range result.Relations {
-		sourceID, sourceOK := entityIDMap[r.SourceName]
-		targetID, targetOK := entityIDMap[r.TargetName]
-		if !sourceOK || !targetOK {
-			continue // Skip if entities weren't found/stored
-		}
-		if err := database.AddEntityRelation(ctx, sourceID, targetID, r.RelationType); err != nil {
-			log.Printf("cortex: warning: failed to store relation %s->%s: %v", r.SourceName, r.TargetName, err)
-		}
+	if normalized == strings.TrimSpace(text) {
+		return text, nil, nil
 	}
 
-	log.Printf("cortex: extracted %d entities for memory %d", len(result.Entities), memoryID)
+	return normalized, map[string]any{"original_text": text}, nil
 }
 
 // ptrIfNotEmpty returns a pointer to the string if non-empty, nil otherwise.
@@ -388,7 +785,7 @@ func ptrIfNotEmpty(s string) *string {
 	return &s
 }
 
-func createSearchHandler(searcher *search.HybridSearcher) mcp.Handler {
+func createSearchHandler(searcher *search.HybridSearcher, tracker *access.Tracker, scopes workspaceScopes) mcp.Handler {
 	return func(ctx context.Context, params json.RawMessage) (any, error) {
 		var args mcp.MemorySearchArgs
 		if err := json.Unmarshal(params, &args); err != nil {
@@ -416,11 +813,19 @@ func createSearchHandler(searcher *search.HybridSearcher) mcp.Handler {
 			model = *args.Model
 		}
 
+		workspaces, all, err := scopes.searchWorkspaces(args.Scope, args.Workspaces)
+		if err != nil {
+			return nil, err
+		}
+
 		results, err := searcher.Search(ctx, search.SearchParams{
-			Query:  args.Query,
-			Limit:  k,
-			Hybrid: hybrid,
-			Model:  model,
+			Query:           args.Query,
+			Limit:           k,
+			Hybrid:          hybrid,
+			Model:           model,
+			IncludeArchived: args.IncludeArchived,
+			Workspaces:      workspaces,
+			AllWorkspaces:   all,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("search: %w", err)
@@ -428,22 +833,42 @@ func createSearchHandler(searcher *search.HybridSearcher) mcp.Handler {
 
 		// Convert to response format
 		response := make([]mcp.MemorySearchResult, len(results))
+		ids := make([]int64, 0, len(results))
 		for i, r := range results {
 			response[i] = mcp.MemorySearchResult{
-				ID:         r.ID,
-				Text:       r.Text,
-				Score:      r.Score,
-				Source:     r.Source,
-				Tags:       r.Tags,
-				Importance: r.Importance,
+				ID:           r.ID,
+				Text:         r.Text,
+				Score:        r.Score,
+				Source:       r.Source,
+				Tags:         r.Tags,
+				Importance:   r.Importance,
+				SupersededBy: r.SupersededBy,
+				AccessCount:  r.AccessCount,
+				Archived:     r.Archived,
+				Workspace:    r.Workspace,
+			}
+			if r.Chunk != nil {
+				response[i].Chunk = &mcp.ChunkMatch{ID: r.Chunk.ID, Index: r.Chunk.Index}
+			}
+			if r.LastAccessedAt != nil {
+				response[i].LastAccessedAt = r.LastAccessedAt.Format(time.RFC3339)
+			}
+			if !r.Archived {
+				ids = append(ids, r.ID)
 			}
 		}
 
+		// Counts reflect reads before this one; they are written in the next batch.
+		// Archived memories are not tracked.
+		tracker.Record(ids...)
+
 		return response, nil
 	}
 }
 
-func createUpdateHandler(database *db.DB, provider llm.Provider, multiEmbedder *llm.MultiEmbedder) mcp.Handler {
+func createUpdateHandler(pipe *pipeline, normalizer *llm.Normalizer, normalizeDefault bool) mcp.Handler {
+	database := pipe.database
+
 	return func(ctx context.Context, params json.RawMessage) (any, error) {
 		var args mcp.MemoryUpdateArgs
 		if err := json.Unmarshal(params, &args); err != nil {
@@ -453,6 +878,9 @@ func createUpdateHandler(database *db.DB, provider llm.Provider, multiEmbedder *
 		if args.ID == 0 {
 			return nil, fmt.Errorf("id is required")
 		}
+		if args.Patch.TTLFrom != nil && !db.ValidTTLFrom(*args.Patch.TTLFrom) {
+			return nil, fmt.Errorf("invalid ttl_from %q: must be created, updated or accessed", *args.Patch.TTLFrom)
+		}
 
 		updateParams := db.UpdateMemoryParams{
 			Text:       args.Patch.Text,
@@ -460,40 +888,143 @@ func createUpdateHandler(database *db.DB, provider llm.Provider, multiEmbedder *
 			Importance: args.Patch.Importance,
 			Tags:       args.Patch.Tags,
 			TTLDays:    args.Patch.TTLDays,
+			TTLFrom:    args.Patch.TTLFrom,
 			Source:     args.Patch.Source,
 		}
 
+		// Redact and normalize new text as memory.add does, keeping meta.original_text
+		// and the redaction marks in sync with it
+		var warnings []string
+		var existingKind string
+		if args.Patch.Text != nil {
+			existing, err := database.GetMemory(ctx, args.ID)
+			if err != nil {
+				return nil, fmt.Errorf("get memory: %w", err)
+			}
+			if existing == nil {
+				return nil, fmt.Errorf("memory not found")
+			}
+			existingKind = existing.Kind
+
+			redacted, err := pipe.redactor.Apply(*args.Patch.Text)
+			if err != nil {
+				return nil, err
+			}
+			text := redacted.Text
+			var normalizedMeta map[string]any
+			if !redacted.NoLLM {
+				text, normalizedMeta, warnings = normalizeText(ctx, normalizer, text, args.Patch.Normalize, normalizeDefault)
+			}
+			warnings = append(warnings, redactionWarnings(redacted)...)
+			updateParams.Text = &text
+
+			meta := existing.Meta
+			if meta == nil {
+				meta = map[string]any{}
+			}
+			if original, ok := normalizedMeta["original_text"]; ok {
+				meta["original_text"] = original
+			} else {
+				delete(meta, "original_text")
+			}
+			updateParams.Meta = redacted.Annotate(meta)
+		}
+
 		if err := database.UpdateMemory(ctx, args.ID, updateParams); err != nil {
 			return nil, fmt.Errorf("update memory: %w", err)
 		}
 
-		// If text was updated, regenerate embeddings
-		if args.Patch.Text != nil {
-			if multiEmbedder != nil {
-				// Multi-model: regenerate all embeddings
-				embeddings, err := multiEmbedder.EmbedAll(ctx, *args.Patch.Text)
-				if err != nil {
-					log.Printf("cortex: warning: failed to regenerate multi-model embeddings for memory %d: %v", args.ID, err)
-				} else {
-					for model, embedding := range embeddings {
-						if err := database.AddEmbedding(ctx, args.ID, model, embedding); err != nil {
-							log.Printf("cortex: warning: failed to update embedding (model=%s) for memory %d: %v", model, args.ID, err)
-						}
-					}
+		if args.Patch.Todo != nil {
+			if err := setTodoFields(ctx, database, args.ID, *args.Patch.Todo); err != nil {
+				return nil, fmt.Errorf("set todo fields: %w", err)
+			}
+		}
+		recordAudit(ctx, database, nil, args.ID)
+
+		result := mcp.MemoryUpdateResult{OK: true, Warnings: warnings}
+
+		// If text was updated, regenerate embeddings and entities and re-check conflicts
+		if updateParams.Text != nil {
+			// Rewriting the text resolves any recorded conflicts
+			if pipe.detector != nil {
+				if _, err := database.DeleteMemoryLinks(ctx, args.ID, db.LinkTypeContradicts); err != nil {
+					log.Printf("cortex: warning: failed to clear conflicts for memory %d: %v", args.ID, err)
 				}
-			} else {
-				embedding, err := provider.Embed(ctx, *args.Patch.Text)
-				if err != nil {
-					log.Printf("cortex: warning: failed to regenerate embedding for memory %d: %v", args.ID, err)
-				} else {
-					if err := database.AddEmbedding(ctx, args.ID, provider.EmbedModel(), embedding); err != nil {
-						log.Printf("cortex: warning: failed to update embedding for memory %d: %v", args.ID, err)
-					}
+			}
+
+			// Text kept from the LLM is not re-embedded, so drop what the old text left behind
+			if redact.SkipsLLM(updateParams.Meta) {
+				if err := database.DeleteDerivedData(ctx, args.ID); err != nil {
+					log.Printf("cortex: warning: failed to clear embeddings and entities of memory %d: %v", args.ID, err)
 				}
 			}
+
+			kind := existingKind
+			if args.Patch.Kind != nil {
+				kind = *args.Patch.Kind
+			}
+
+			// Documents are re-split; their chunks are embedded instead of the full text
+			var out writeOutcome
+			if kind == db.KindDocument {
+				var err error
+				if _, out, err = pipe.rechunk(ctx, args.ID, chunk.DefaultOptions()); err != nil {
+					return nil, fmt.Errorf("rechunk document: %w", err)
+				}
+			} else {
+				out = pipe.afterWrite(ctx, args.ID, kind, *updateParams.Text, updateParams.Meta)
+			}
+			result.Conflicts = out.Conflicts
+			result.Warnings = append(result.Warnings, out.Warnings...)
+			result.Pending = out.Pending
+		}
+
+		return result, nil
+	}
+}
+
+func createGetHandler(database *db.DB, tracker *access.Tracker) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryGetArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		if args.ID == 0 {
+			return nil, fmt.Errorf("id is required")
+		}
+
+		m, err := database.GetMemory(ctx, args.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get memory: %w", err)
+		}
+		if m == nil {
+			return nil, fmt.Errorf("memory not found")
+		}
+
+		tracker.Record(m.ID)
+
+		result := mcp.MemoryGetResult{
+			ID:          m.ID,
+			Text:        m.Text,
+			Kind:        m.Kind,
+			Source:      m.Source,
+			Tags:        m.Tags,
+			Importance:  m.Importance,
+			TTLDays:     m.TTLDays,
+			TTLFrom:     m.TTLFrom,
+			Meta:        m.Meta,
+			ParentID:    m.ParentID,
+			ChunkIndex:  m.ChunkIndex,
+			CreatedAt:   m.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:   m.UpdatedAt.Format(time.RFC3339),
+			AccessCount: m.AccessCount,
+		}
+		if m.LastAccessedAt != nil {
+			result.LastAccessedAt = m.LastAccessedAt.Format(time.RFC3339)
 		}
 
-		return mcp.MemoryUpdateResult{OK: true}, nil
+		return result, nil
 	}
 }
 
@@ -511,6 +1042,7 @@ func createDeleteHandler(database *db.DB) mcp.Handler {
 		if err := database.DeleteMemory(ctx, args.ID); err != nil {
 			return nil, fmt.Errorf("delete memory: %w", err)
 		}
+		recordAudit(ctx, database, nil, args.ID)
 
 		return mcp.MemoryDeleteResult{OK: true}, nil
 	}
@@ -565,21 +1097,70 @@ func createRelatedHandler(database *db.DB) mcp.Handler {
 			k = *args.K
 		}
 
-		related, err := database.GetRelatedMemories(ctx, args.MemoryID, k)
+		depth := 1
+		if args.Depth != nil {
+			depth = *args.Depth
+		}
+		if depth < 1 || depth > 3 {
+			return nil, fmt.Errorf("depth must be between 1 and 3")
+		}
+
+		linkTypes := make([]db.LinkType, len(args.LinkTypes))
+		for i, name := range args.LinkTypes {
+			t, err := db.ParseLinkType(name)
+			if err != nil {
+				return nil, err
+			}
+			linkTypes[i] = t
+		}
+
+		// Explicit links come first, nearest first
+		linked, err := database.TraverseLinks(ctx, args.MemoryID, linkTypes, depth, k)
 		if err != nil {
-			return nil, fmt.Errorf("get related memories: %w", err)
+			return nil, fmt.Errorf("traverse links: %w", err)
 		}
 
-		results := make([]mcp.MemoryRelatedResult, len(related))
-		for i, m := range related {
-			results[i] = mcp.MemoryRelatedResult{
+		results := make([]mcp.MemoryRelatedResult, 0, k)
+		seen := make(map[int64]bool, k)
+		for _, m := range linked {
+			seen[m.ID] = true
+			results = append(results, mcp.MemoryRelatedResult{
 				ID:         m.ID,
 				Text:       m.Text,
 				Kind:       m.Kind,
-				Score:      m.Score,
+				Score:      1 / float32(m.Depth),
 				Source:     m.Source,
 				Tags:       m.Tags,
 				Importance: m.Importance,
+				Via:        string(m.LinkType),
+				Direction:  m.Direction,
+				Depth:      m.Depth,
+			})
+		}
+
+		// Fill the remaining slots with memories sharing entities
+		if len(results) < k {
+			related, err := database.GetRelatedMemories(ctx, args.MemoryID, k)
+			if err != nil {
+				return nil, fmt.Errorf("get related memories: %w", err)
+			}
+			for _, m := range related {
+				if len(results) == k {
+					break
+				}
+				if seen[m.ID] {
+					continue
+				}
+				results = append(results, mcp.MemoryRelatedResult{
+					ID:         m.ID,
+					Text:       m.Text,
+					Kind:       m.Kind,
+					Score:      m.Score,
+					Source:     m.Source,
+					Tags:       m.Tags,
+					Importance: m.Importance,
+					Via:        "entities",
+				})
 			}
 		}
 
@@ -587,6 +1168,108 @@ func createRelatedHandler(database *db.DB) mcp.Handler {
 	}
 }
 
+func createLinkHandler(database *db.DB) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryLinkArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		if args.SourceID == 0 || args.TargetID == 0 {
+			return nil, fmt.Errorf("source_id and target_id are required")
+		}
+
+		linkType, err := db.ParseLinkType(args.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		var meta map[string]any
+		if args.Reason != "" {
+			meta = map[string]any{"reason": args.Reason}
+		}
+
+		if err := database.AddMemoryLink(ctx, args.SourceID, args.TargetID, linkType, meta); err != nil {
+			return nil, fmt.Errorf("link memories: %w", err)
+		}
+		recordAudit(ctx, database, map[string]any{"type": string(linkType)}, args.SourceID, args.TargetID)
+
+		return mcp.MemoryLinkResult{OK: true}, nil
+	}
+}
+
+func createUnlinkHandler(database *db.DB) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryUnlinkArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		if args.SourceID == 0 || args.TargetID == 0 {
+			return nil, fmt.Errorf("source_id and target_id are required")
+		}
+
+		var linkType db.LinkType
+		if args.Type != "" {
+			t, err := db.ParseLinkType(args.Type)
+			if err != nil {
+				return nil, err
+			}
+			linkType = t
+		}
+
+		deleted, err := database.DeleteMemoryLink(ctx, args.SourceID, args.TargetID, linkType)
+		if err != nil {
+			return nil, fmt.Errorf("unlink memories: %w", err)
+		}
+		if deleted > 0 {
+			recordAudit(ctx, database, map[string]any{"deleted": deleted}, args.SourceID, args.TargetID)
+		}
+
+		return mcp.MemoryUnlinkResult{Deleted: deleted}, nil
+	}
+}
+
+func createConflictsHandler(database *db.DB) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryConflictsArgs
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+
+		k := 50
+		if args.K != nil {
+			k = *args.K
+		}
+
+		conflicts, err := database.ListConflicts(ctx, k)
+		if err != nil {
+			return nil, fmt.Errorf("list conflicts: %w", err)
+		}
+
+		result := mcp.MemoryConflictsResult{
+			Conflicts: make([]mcp.ConflictResult, len(conflicts)),
+		}
+		for i, c := range conflicts {
+			reason, _ := c.Meta["reason"].(string)
+			confidence, _ := c.Meta["confidence"].(float64)
+			result.Conflicts[i] = mcp.ConflictResult{
+				MemoryID:        c.SourceID,
+				MemoryText:      c.SourceText,
+				ConflictsWithID: c.TargetID,
+				ConflictsWith:   c.TargetText,
+				Reason:          reason,
+				Confidence:      float32(confidence),
+				DetectedAt:      c.CreatedAt.Format(time.RFC3339),
+			}
+		}
+
+		return result, nil
+	}
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -609,6 +1292,7 @@ func runCLI() error {
 		return fmt.Errorf("connect to database: %w", err)
 	}
 	defer database.Close()
+	cfg.applyEncryption(database)
 
 	// Run migrations
 	if err := database.Migrate(ctx); err != nil {
@@ -630,7 +1314,7 @@ func runCLI() error {
 				return fmt.Errorf("init LLM provider: %w", err)
 			}
 		}
-		return runImport(ctx, database, provider)
+		return runImport(ctx, database, provider, cfg.Redactor)
 	}
 
 	// Handle re-embedding
@@ -645,39 +1329,164 @@ func runCLI() error {
 	return nil
 }
 
+// loadSweeperConfig reads the sweeper's cleanup settings into cfg. They are
+// shared by the server and the sweep command.
+func loadSweeperConfig(cfg *Config) error {
+	// Parse completed todo archiving (default: 0, completed todos are kept as done)
+	todoArchiveDays, err := strconv.Atoi(getEnv("TODO_ARCHIVE_DAYS", "0"))
+	if err != nil || todoArchiveDays < 0 {
+		return fmt.Errorf("invalid TODO_ARCHIVE_DAYS: must be a non-negative integer")
+	}
+
+	// Parse unaccessed memory eviction (default: 0, memories are kept regardless of use)
+	evictUnaccessedDays, err := strconv.Atoi(getEnv("EVICT_UNACCESSED_DAYS", "0"))
+	if err != nil || evictUnaccessedDays < 0 {
+		return fmt.Errorf("invalid EVICT_UNACCESSED_DAYS: must be a non-negative integer")
+	}
+
+	// Load retention policies (default: none, only ttl_days applies)
+	var policies []sweeper.Policy
+	if path := getEnv("RETENTION_POLICIES", ""); path != "" {
+		policies, err = sweeper.LoadPolicies(path)
+		if err != nil {
+			return fmt.Errorf("invalid RETENTION_POLICIES: %w", err)
+		}
+	}
+
+	// Parse archive mode (default: delete, removed memories are gone)
+	archiveMode, err := sweeper.ParseArchiveMode(getEnv("ARCHIVE_MODE", "delete"))
+	if err != nil {
+		return fmt.Errorf("invalid ARCHIVE_MODE: %w", err)
+	}
+	archiveDir := getEnv("ARCHIVE_DIR", "")
+	if archiveMode == sweeper.ArchiveFile && archiveDir == "" {
+		return fmt.Errorf("ARCHIVE_DIR environment variable is required when ARCHIVE_MODE=file")
+	}
+
+	cfg.TodoArchiveDays = todoArchiveDays
+	cfg.EvictUnaccessedDays = evictUnaccessedDays
+	cfg.RetentionPolicies = policies
+	cfg.ArchiveMode = archiveMode
+	cfg.ArchiveDir = archiveDir
+	return nil
+}
+
+// loadRedactConfig reads the redaction settings into cfg. They are shared by
+// the server and the CLI, so imports and ingests are redacted like tool calls.
+func loadRedactConfig(cfg *Config) error {
+	mode, err := redact.ParseMode(getEnv("REDACT_MODE", "off"))
+	if err != nil {
+		return fmt.Errorf("invalid REDACT_MODE: %w", err)
+	}
+	detectors := redact.Detectors
+	if v, ok := os.LookupEnv("REDACT_DETECTORS"); ok {
+		detectors = splitList(v)
+	}
+	redactor, err := redact.New(mode, detectors)
+	if err != nil {
+		return fmt.Errorf("invalid REDACT_DETECTORS: %w", err)
+	}
+	if mode != redact.ModeOff {
+		cfg.Redactor = redactor
+	}
+	cfg.RedactDetectors = detectors
+	return nil
+}
+
+// loadEncryptionConfig reads the encryption settings into cfg. They are shared
+// by the server and the CLI, which must read and write sealed memories alike.
+func loadEncryptionConfig(cfg *Config) error {
+	// Parse blind index (default: true, lexical search matches exact words of sealed memories)
+	cfg.EncryptBlindIndex = true
+	if v := getEnv("ENCRYPT_BLIND_INDEX", "true"); v == "false" || v == "0" {
+		cfg.EncryptBlindIndex = false
+	}
+
+	cfg.EncryptWorkspaces = splitList(getEnv("ENCRYPT_WORKSPACES", ""))
+	path := getEnv("ENCRYPTION_KEYFILE", "")
+	if path == "" {
+		if len(cfg.EncryptWorkspaces) > 0 {
+			return fmt.Errorf("ENCRYPTION_KEYFILE environment variable is required when ENCRYPT_WORKSPACES is set")
+		}
+		return nil
+	}
+
+	keyring, err := encryption.LoadKeyfile(path)
+	if err != nil {
+		return fmt.Errorf("invalid ENCRYPTION_KEYFILE: %w", err)
+	}
+	cfg.Keyring = keyring
+	return nil
+}
+
+// applyEncryption configures database to seal and open memories. Without a
+// keyfile, sealed memories cannot be read.
+func (cfg *Config) applyEncryption(database *db.DB) {
+	if cfg.Keyring == nil {
+		return
+	}
+	database.SetEncryption(db.EncryptionConfig{
+		Keyring:    cfg.Keyring,
+		Workspaces: cfg.EncryptWorkspaces,
+		BlindIndex: cfg.EncryptBlindIndex,
+	})
+}
+
+// sweeperConfig returns the sweeper configuration for cfg.
+func (cfg *Config) sweeperConfig() sweeper.Config {
+	sweeperCfg := sweeper.DefaultConfig()
+	sweeperCfg.ArchiveDoneTodosAfter = time.Duration(cfg.TodoArchiveDays) * 24 * time.Hour
+	sweeperCfg.EvictUnaccessedAfter = time.Duration(cfg.EvictUnaccessedDays) * 24 * time.Hour
+	sweeperCfg.Policies = cfg.RetentionPolicies
+	sweeperCfg.Archive = cfg.ArchiveMode
+	sweeperCfg.ArchiveDir = cfg.ArchiveDir
+	sweeperCfg.Global = cfg.SweeperGlobal
+	return sweeperCfg
+}
+
 func loadConfigForCLI() (*Config, error) {
 	cfg := &Config{
 		DatabaseURL: getEnv("DATABASE_URL", ""),
 		TenantID:    getEnv("TENANT_ID", "local"),
-		WorkspaceID: getEnv("WORKSPACE_ID", "default"),
-		LMBackend:   getEnv("LM_BACKEND", "openai"),
-		LMModel:     getEnv("LM_MODEL", "auto"),
-		EmbedModel:  getEnv("EMBED_MODEL", "auto"),
-		OpenAIKey:   getEnv("OPENAI_API_KEY", ""),
-		GeminiKey:   getEnv("GEMINI_API_KEY", ""),
+	}
+	if err := loadBackendConfig(cfg); err != nil {
+		return nil, err
 	}
 
+	if v := getEnv("ENTITY_EXTRACTION", "false"); v == "true" || v == "1" {
+		cfg.EntityExtraction = true
+	}
+
+	cfg.WorkspaceID, cfg.DetectWorkspace = resolveWorkspace()
+
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
 	}
+	if err := loadRedactConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := loadEncryptionConfig(cfg); err != nil {
+		return nil, err
+	}
 
 	// Only require API key if regenerating embeddings or re-embedding
 	if *regenerateEmbeddings || *reembedAll {
-		switch cfg.LMBackend {
-		case "openai":
-			if cfg.OpenAIKey == "" {
-				return nil, fmt.Errorf("OPENAI_API_KEY required for embedding operations")
-			}
-		case "gemini":
-			if cfg.GeminiKey == "" {
-				return nil, fmt.Errorf("GEMINI_API_KEY required for embedding operations")
-			}
+		if err := requireAPIKey(cfg); err != nil {
+			return nil, err
 		}
 	}
 
 	return cfg, nil
 }
 
+// requireAPIKey checks that the API key for the embedding backend is set.
+func requireAPIKey(cfg *Config) error {
+	if key, name := backendKey(cfg, cfg.EmbedBackend); name != "" && key == "" {
+		return fmt.Errorf("%s required for embedding operations", name)
+	}
+	return nil
+}
+
 func runReembed(ctx context.Context, database *db.DB, provider llm.Provider, cfg *Config) error {
 	log.Printf("cortex: starting re-embedding (model=%s, batch=%d, delay=%v)", provider.EmbedModel(), *reembedBatchSize, *reembedDelay)
 
@@ -686,8 +1495,10 @@ func runReembed(ctx context.Context, database *db.DB, provider llm.Provider, cfg
 			BatchSize:           *reembedBatchSize,
 			DelayBetweenBatches: *reembedDelay,
 			DeleteOldEmbeddings: *reembedDeleteOld,
+			TargetModel:         provider.EmbedModel(),
 			SkipExisting:        true,
-		})
+		}).
+		WithTextOpener(database.OpenText)
 
 	stats, err := r.ReembedAll(ctx, func(processed, total int64, memoryID int64, err error) {
 		if err != nil {
@@ -708,7 +1519,7 @@ func runReembed(ctx context.Context, database *db.DB, provider llm.Provider, cfg
 func runExport(ctx context.Context, database *db.DB) error {
 	log.Printf("cortex: exporting memories to %s", *exportFile)
 
-	exporter := transfer.NewExporter(database.Pool())
+	exporter := transfer.NewExporter(database.Pool()).WithSealer(database)
 
 	opts := transfer.ExportOptions{
 		IncludeEmbeddings: *withEmbeddings,
@@ -732,7 +1543,7 @@ func runExport(ctx context.Context, database *db.DB) error {
 	return nil
 }
 
-func runImport(ctx context.Context, database *db.DB, provider llm.Provider) error {
+func runImport(ctx context.Context, database *db.DB, provider llm.Provider, redactor *redact.Redactor) error {
 	log.Printf("cortex: importing memories from %s", *importFile)
 
 	// Create embedder wrapper if provider is available
@@ -741,7 +1552,7 @@ func runImport(ctx context.Context, database *db.DB, provider llm.Provider) erro
 		embedder = &providerWrapper{provider}
 	}
 
-	importer := transfer.NewImporter(database.Pool(), embedder)
+	importer := transfer.NewImporter(database.Pool(), embedder).WithSealer(database)
 
 	opts := transfer.ImportOptions{
 		SkipExisting:         *skipExisting,
@@ -749,6 +1560,7 @@ func runImport(ctx context.Context, database *db.DB, provider llm.Provider) erro
 		OverrideTenantID:     database.TenantID(),
 		OverrideWorkspaceID:  database.WorkspaceID(),
 		DryRun:               *dryRun,
+		Prepare:              redactRecord(redactor),
 	}
 
 	// Open input file
@@ -762,6 +1574,11 @@ func runImport(ctx context.Context, database *db.DB, provider llm.Provider) erro
 	if err != nil {
 		return fmt.Errorf("import: %w", err)
 	}
+	if !*dryRun && result.Imported > 0 {
+		detail := importDetail(result)
+		detail["file"] = *importFile
+		recordCLIAudit(ctx, database, "import", detail, result.IDs...)
+	}
 
 	if *dryRun {
 		log.Printf("cortex: (dry run) would import %d memories (%d skipped, %d errors)",
@@ -773,6 +1590,11 @@ func runImport(ctx context.Context, database *db.DB, provider llm.Provider) erro
 	return nil
 }
 
+// importDetail summarizes an import for the audit log.
+func importDetail(result *transfer.ImportResult) map[string]any {
+	return map[string]any{"imported": result.Imported, "skipped": result.Skipped, "errors": result.Errors}
+}
+
 // providerWrapper wraps llm.Provider to implement transfer.EmbeddingProvider
 type providerWrapper struct {
 	provider llm.Provider
@@ -799,7 +1621,7 @@ func createExportHandler(database *db.DB) mcp.Handler {
 			return nil, fmt.Errorf("invalid arguments: %w", err)
 		}
 
-		exporter := transfer.NewExporter(database.Pool())
+		exporter := transfer.NewExporter(database.Pool()).WithSealer(database)
 
 		opts := transfer.ExportOptions{
 			IncludeEmbeddings: args.IncludeEmbeddings,
@@ -823,7 +1645,7 @@ func createExportHandler(database *db.DB) mcp.Handler {
 	}
 }
 
-func createImportHandler(database *db.DB, provider llm.Provider) mcp.Handler {
+func createImportHandler(database *db.DB, provider llm.Provider, redactor *redact.Redactor) mcp.Handler {
 	return func(ctx context.Context, params json.RawMessage) (any, error) {
 		var args mcp.MemoryImportArgs
 		if err := json.Unmarshal(params, &args); err != nil {
@@ -840,7 +1662,7 @@ func createImportHandler(database *db.DB, provider llm.Provider) mcp.Handler {
 			embedder = &providerWrapper{provider}
 		}
 
-		importer := transfer.NewImporter(database.Pool(), embedder)
+		importer := transfer.NewImporter(database.Pool(), embedder).WithSealer(database)
 
 		opts := transfer.ImportOptions{
 			SkipExisting:         args.SkipExisting,
@@ -848,6 +1670,7 @@ func createImportHandler(database *db.DB, provider llm.Provider) mcp.Handler {
 			OverrideTenantID:     database.TenantID(),
 			OverrideWorkspaceID:  database.WorkspaceID(),
 			DryRun:               args.DryRun,
+			Prepare:              redactRecord(redactor),
 		}
 
 		reader := strings.NewReader(args.Data)
@@ -855,6 +1678,9 @@ func createImportHandler(database *db.DB, provider llm.Provider) mcp.Handler {
 		if err != nil {
 			return nil, fmt.Errorf("import: %w", err)
 		}
+		if !args.DryRun && result.Imported > 0 {
+			recordAudit(ctx, database, importDetail(result), result.IDs...)
+		}
 
 		return mcp.MemoryImportResult{
 			Total:    result.Total,
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/johnswift/cortex/internal/access"
+	"github.com/johnswift/cortex/internal/conflict"
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/entity"
+	"github.com/johnswift/cortex/internal/jobs"
+	"github.com/johnswift/cortex/internal/llm"
+	"github.com/johnswift/cortex/internal/redact"
+)
+
+// pipeline runs the processing that follows a write to a memory's text:
+// embedding, entity extraction and conflict detection. Steps run inline, or
+// from the job queue when async is set; failed inline steps are queued for retry.
+// Memories marked no_llm by redaction skip every step.
+type pipeline struct {
+	database      *db.DB
+	provider      llm.Provider
+	multiEmbedder *llm.MultiEmbedder
+	extractor     *entity.Extractor
+	detector      *conflict.Detector
+	queue         *jobs.Queue
+	async         bool
+	redactor      *redact.Redactor // nil when REDACT_MODE=off
+	tracker       *access.Tracker  // counts reads of the tenant's memories; nil outside the server
+}
+
+// writeOutcome reports what happened after a memory's text was written.
+type writeOutcome struct {
+	Conflicts []int64
+	Warnings  []string
+	Pending   []string // job kinds queued for background processing
+}
+
+// afterWrite embeds the memory, extracts its entities and checks it for conflicts.
+// Meta is the memory's meta as written.
+func (p *pipeline) afterWrite(ctx context.Context, memoryID int64, kind, text string, meta map[string]any) writeOutcome {
+	var out writeOutcome
+	if redact.SkipsLLM(meta) {
+		return out
+	}
+
+	// Contradictions are reported to the caller, so memories checked for them
+	// are embedded inline even when processing is async
+	checks := p.detector != nil && p.detector.Checks(kind)
+	if p.async && !checks {
+		p.enqueue(ctx, &out, jobs.KindEmbed, memoryID)
+		if p.extractor != nil {
+			p.enqueue(ctx, &out, jobs.KindExtractEntities, memoryID)
+		}
+		return out
+	}
+
+	embedding, model, err := p.embed(ctx, memoryID, text)
+	if err != nil {
+		log.Printf("cortex: warning: failed to embed memory %d, queued for retry: %v", memoryID, err)
+		p.enqueue(ctx, &out, jobs.KindEmbed, memoryID)
+	}
+
+	if p.extractor != nil && p.async {
+		p.enqueue(ctx, &out, jobs.KindExtractEntities, memoryID)
+	} else if p.extractor != nil {
+		if err := p.extractEntities(ctx, memoryID, text); err != nil {
+			log.Printf("cortex: warning: failed to extract entities for memory %d, queued for retry: %v", memoryID, err)
+			p.enqueue(ctx, &out, jobs.KindExtractEntities, memoryID)
+		}
+	}
+
+	if checks && embedding != nil {
+		out.Conflicts, out.Warnings = p.detectConflicts(ctx, memoryID, text, embedding, model)
+	}
+
+	return out
+}
+
+// enqueue queues a job for the memory and records it as pending.
+func (p *pipeline) enqueue(ctx context.Context, out *writeOutcome, kind string, memoryID int64) {
+	if err := p.queue.Enqueue(ctx, kind, memoryID, nil); err != nil {
+		log.Printf("cortex: warning: failed to queue %s job for memory %d: %v", kind, memoryID, err)
+		out.Warnings = append(out.Warnings, fmt.Sprintf("%s failed and could not be queued for retry", kind))
+		return
+	}
+	out.Pending = append(out.Pending, kind)
+}
+
+// embed generates and stores embeddings for text. With multiple models configured,
+// every model is embedded. Returns the primary model's embedding and name.
+func (p *pipeline) embed(ctx context.Context, memoryID int64, text string) ([]float32, string, error) {
+	if p.multiEmbedder != nil {
+		// Multi-model: generate embeddings from all configured models
+		embeddings, err := p.multiEmbedder.EmbedAll(ctx, text)
+		if err != nil {
+			return nil, "", fmt.Errorf("generate multi-model embeddings: %w", err)
+		}
+		for model, embedding := range embeddings {
+			if err := p.database.AddEmbedding(ctx, memoryID, model, embedding); err != nil {
+				return nil, "", fmt.Errorf("store embedding (model=%s): %w", model, err)
+			}
+		}
+		primary := p.multiEmbedder.Primary()
+		return embeddings[primary], primary, nil
+	}
+
+	// Single-model: use the default provider
+	embedding, err := p.provider.Embed(ctx, text)
+	if err != nil {
+		return nil, "", fmt.Errorf("generate embedding: %w", err)
+	}
+	if err := p.database.AddEmbedding(ctx, memoryID, p.provider.EmbedModel(), embedding); err != nil {
+		return nil, "", fmt.Errorf("store embedding: %w", err)
+	}
+	return embedding, p.provider.EmbedModel(), nil
+}
+
+// extractEntities extracts entities from text and links them to the memory.
+// Failures to store individual entities are logged; only extraction failures are returned.
+func (p *pipeline) extractEntities(ctx context.Context, memoryID int64, text string) error {
+	result, err := p.extractor.Extract(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Entities) == 0 {
+		return nil
+	}
+
+	// Store entities and link to memory
+	entityIDMap := make(map[string]int64) // name -> id for relation linking
+	for _, e := range result.Entities {
+		entityID, err := p.database.AddEntity(ctx, db.AddEntityParams{
+			Name:        e.Name,
+			Type:        db.EntityType(e.Type),
+			Aliases:     e.Aliases,
+			Description: ptrIfNotEmpty(e.Description),
+			Meta:        e.Meta,
+		})
+		if err != nil {
+			log.Printf("cortex: warning: failed to store entity %q: %v", e.Name, err)
+			continue
+		}
+
+		entityIDMap[e.Name] = entityID
+
+		// Link entity to memory
+		if err := p.database.LinkMemoryEntity(ctx, memoryID, entityID, ptrIfNotEmpty(e.Role), e.Confidence); err != nil {
+			log.Printf("cortex: warning: failed to link entity %q to memory %d: %v", e.Name, memoryID, err)
+		}
+	}
+
+	// Store entity relations
+	for _, r := range result.Relations {
+		sourceID, sourceOK := entityIDMap[r.SourceName]
+		targetID, targetOK := entityIDMap[r.TargetName]
+		if !sourceOK || !targetOK {
+			continue // Skip if entities weren't found/stored
+		}
+		if err := p.database.AddEntityRelation(ctx, sourceID, targetID, r.RelationType); err != nil {
+			log.Printf("cortex: warning: failed to store relation %s->%s: %v", r.SourceName, r.TargetName, err)
+		}
+	}
+
+	log.Printf("cortex: extracted %d entities for memory %d", len(result.Entities), memoryID)
+	return nil
+}
+
+// detectConflicts checks the most similar existing memories for contradictions
+// with the given memory and records a contradicts link for each one found.
+// It returns the conflicting memory IDs and a human-readable warning for each.
+func (p *pipeline) detectConflicts(ctx context.Context, memoryID int64, text string, embedding []float32, model string) ([]int64, []string) {
+	similar, err := p.database.VectorSearch(ctx, db.VectorSearchParams{
+		Embedding: embedding,
+		Limit:     conflict.DefaultCandidates + 1, // +1 because the memory itself is returned
+		Model:     model,
+	})
+	if err != nil {
+		log.Printf("cortex: warning: failed to find conflict candidates for memory %d: %v", memoryID, err)
+		return nil, nil
+	}
+
+	var candidates []conflict.Candidate
+	texts := make(map[int64]string, len(similar))
+	for _, m := range similar {
+		if m.ID == memoryID || m.Score < conflict.DefaultMinSimilarity || !p.detector.Checks(m.Kind) {
+			continue
+		}
+		candidates = append(candidates, conflict.Candidate{ID: m.ID, Kind: m.Kind, Text: m.Text})
+		texts[m.ID] = m.Text
+	}
+
+	contradictions, err := p.detector.Detect(ctx, text, candidates)
+	if err != nil {
+		log.Printf("cortex: warning: failed to detect conflicts for memory %d: %v", memoryID, err)
+		return nil, nil
+	}
+
+	var ids []int64
+	var warnings []string
+	for _, c := range contradictions {
+		meta := map[string]any{"reason": c.Reason, "confidence": c.Confidence}
+		if err := p.database.AddMemoryLink(ctx, memoryID, c.MemoryID, db.LinkTypeContradicts, meta); err != nil {
+			log.Printf("cortex: warning: failed to store conflict %d->%d: %v", memoryID, c.MemoryID, err)
+			continue
+		}
+		ids = append(ids, c.MemoryID)
+		warnings = append(warnings, fmt.Sprintf("contradicts memory %d (%q): %s", c.MemoryID, texts[c.MemoryID], c.Reason))
+	}
+
+	if len(ids) > 0 {
+		log.Printf("cortex: memory %d contradicts %d existing memories", memoryID, len(ids))
+	}
+
+	return ids, warnings
+}
+
+// registerJobHandlers registers the pipeline's steps as job handlers.
+func (p *pipeline) registerJobHandlers(worker *jobs.Worker) {
+	for kind, handler := range p.jobHandlers() {
+		worker.Register(kind, handler)
+	}
+}
+
+// jobHandlers returns the pipeline's steps as job handlers, by job kind.
+// Handlers always work on the memory's current text, so a job queued before
+// an update processes the updated text.
+func (p *pipeline) jobHandlers() map[string]jobs.HandlerFunc {
+	handlers := map[string]jobs.HandlerFunc{
+		jobs.KindEmbed: func(ctx context.Context, job *jobs.Job) error {
+			memory, err := p.jobMemory(ctx, job)
+			if err != nil || memory == nil || redact.SkipsLLM(memory.Meta) {
+				return err
+			}
+			embedding, model, err := p.embed(ctx, memory.ID, memory.Text)
+			if err != nil {
+				return err
+			}
+			// Conflict detection needs the embedding, so it runs once embedding succeeds
+			if p.detector != nil && p.detector.Checks(memory.Kind) {
+				p.detectConflicts(ctx, memory.ID, memory.Text, embedding, model)
+			}
+			return nil
+		},
+	}
+
+	if p.extractor != nil {
+		handlers[jobs.KindExtractEntities] = func(ctx context.Context, job *jobs.Job) error {
+			memory, err := p.jobMemory(ctx, job)
+			if err != nil || memory == nil || redact.SkipsLLM(memory.Meta) {
+				return err
+			}
+			return p.extractEntities(ctx, memory.ID, memory.Text)
+		}
+	}
+	return handlers
+}
+
+// jobMemory loads the memory a job refers to.
+// Returns nil if the memory has since been deleted.
+func (p *pipeline) jobMemory(ctx context.Context, job *jobs.Job) (*db.Memory, error) {
+	if job.MemoryID == nil {
+		return nil, fmt.Errorf("job %d has no memory", job.ID)
+	}
+	return p.database.GetMemory(ctx, *job.MemoryID)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/johnswift/cortex/internal/redact"
+	"github.com/johnswift/cortex/internal/transfer"
+)
+
+// redactionWarnings tells the caller what redaction did to a memory's text,
+// naming the kinds found but not the text.
+func redactionWarnings(res redact.Result) []string {
+	if len(res.Findings) == 0 {
+		return nil
+	}
+	kinds := strings.Join(redact.Kinds(res.Findings), ", ")
+	if res.NoLLM {
+		return []string{fmt.Sprintf("contains possible %s; stored without embedding, entity extraction or normalization", kinds)}
+	}
+	return []string{fmt.Sprintf("masked possible %s", kinds)}
+}
+
+// redactRecord returns an import hook applying the redactor to each record,
+// or nil when redaction is off, so that marks in exported meta are kept.
+func redactRecord(redactor *redact.Redactor) func(*transfer.MemoryRecord) error {
+	if redactor == nil {
+		return nil
+	}
+	return func(record *transfer.MemoryRecord) error {
+		redacted, err := redactor.Apply(record.Text)
+		if err != nil {
+			return err
+		}
+		// An exported embedding of the original text would still match it
+		if redacted.Text != record.Text || redacted.NoLLM {
+			record.Embedding = nil
+		}
+		record.Text = redacted.Text
+		record.Meta = redacted.Annotate(record.Meta)
+		return nil
+	}
+}
+
+// runScanCommand implements `cortex scan`.
+func runScanCommand(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	detectors := fs.String("detectors", "", "Comma-separated detectors to run (default: REDACT_DETECTORS)")
+	batchSize := fs.Int("batch-size", 500, "Batch size")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex scan [flags]")
+		fmt.Fprintln(os.Stderr, "Reports memories of WORKSPACE_ID in which the redaction detectors find something.")
+		fmt.Fprintln(os.Stderr, "Memories are not changed, and what was found is not printed.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, database, err := openCLIDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	names := cfg.RedactDetectors
+	if *detectors != "" {
+		names = splitList(*detectors)
+	}
+	scanner, err := redact.New(redact.ModeOff, names)
+	if err != nil {
+		return err
+	}
+
+	var scanned, flagged int
+	var afterID int64
+	for {
+		memories, err := database.ListMemories(ctx, afterID, *batchSize)
+		if err != nil {
+			return err
+		}
+		if len(memories) == 0 {
+			break
+		}
+		for _, m := range memories {
+			afterID = m.ID
+			scanned++
+			findings := scanner.Scan(m.Text)
+			if len(findings) == 0 {
+				continue
+			}
+			flagged++
+			fmt.Printf("%d\t%s\t%s\n", m.ID, m.Kind, strings.Join(redact.Kinds(findings), ","))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "cortex: scanned %d memories, %d with possible secrets or personal data\n", scanned, flagged)
+	if flagged > 0 {
+		return fmt.Errorf("found possible secrets or personal data in %d memories", flagged)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/jobs"
+	"github.com/johnswift/cortex/internal/mcp"
+	"github.com/johnswift/cortex/internal/workspace"
+)
+
+// resolveWorkspace returns WORKSPACE_ID or, when it is unset, the workspace
+// detected from the git repository of the working directory ("default"
+// outside a repository). detected reports whether WORKSPACE_ID was unset.
+func resolveWorkspace() (id string, detected bool) {
+	if id, ok := os.LookupEnv("WORKSPACE_ID"); ok && id != "" {
+		return id, false
+	}
+
+	dir, err := os.Getwd()
+	if err == nil {
+		id, err = workspace.Detect(context.Background(), dir)
+	}
+	if err != nil {
+		return "default", true
+	}
+	return id, true
+}
+
+// workspaceRouter picks the tenant and workspace each tool call works on.
+// The tenant is the one of the caller's API key, else the server's. The
+// workspace is the call's workspace argument if allowed, else the server's
+// default workspace. Each tenant has a single job worker, which runs the
+// jobs of every workspace of the tenant with the pipeline of the workspace.
+type workspaceRouter struct {
+	ctx       context.Context // lifetime of the tenants' job workers and access trackers
+	own       *tenantPipeline
+	workerCfg jobs.WorkerConfig
+	allowed   []string // workspaces calls may select; "*" allows any
+	implicit  []string // workspaces calls reach by scope, such as the shared workspace
+	detect    bool     // follow the client's roots (WORKSPACE_ID unset)
+
+	mu      sync.Mutex
+	current string
+	tenants map[string]*tenantPipeline // tenants selected by API keys, by tenant ID
+}
+
+// tenantPipeline is the pipeline of a tenant's default workspace and the job
+// worker running the tenant's jobs.
+type tenantPipeline struct {
+	base   *pipeline
+	worker *jobs.Worker
+}
+
+// newWorkspaceRouter creates a router whose tenant and default workspace are
+// the ones of base, and starts the job worker of the tenant. Workers and
+// access trackers of other tenants run until ctx is cancelled. Calls may
+// reach the allowed workspaces and the implicit ones they reach by scope.
+func newWorkspaceRouter(ctx context.Context, base *pipeline, workerCfg jobs.WorkerConfig, allowed, implicit []string, detect bool) *workspaceRouter {
+	r := &workspaceRouter{
+		ctx:       ctx,
+		workerCfg: workerCfg,
+		allowed:   allowed,
+		implicit:  implicit,
+		detect:    detect,
+		current:   base.database.WorkspaceID(),
+		tenants:   make(map[string]*tenantPipeline),
+	}
+	r.own = r.startTenant(base)
+	return r
+}
+
+// startTenant starts the job worker of base's tenant.
+func (r *workspaceRouter) startTenant(base *pipeline) *tenantPipeline {
+	t := &tenantPipeline{base: base}
+	queue := jobs.NewTenantQueue(base.database.Pool(), base.database.TenantID())
+	t.worker = jobs.NewWorker(queue).WithConfig(r.workerCfg)
+	for kind := range base.jobHandlers() {
+		t.worker.Register(kind, t.runJob(kind))
+	}
+	t.worker.Start(r.ctx)
+	return t
+}
+
+// runJob returns a handler that runs jobs of a kind with the pipeline of
+// the job's workspace.
+func (t *tenantPipeline) runJob(kind string) jobs.HandlerFunc {
+	return func(ctx context.Context, job *jobs.Job) error {
+		return t.base.forWorkspace(job.WorkspaceID).jobHandlers()[kind](ctx, job)
+	}
+}
+
+// pipeline returns the pipeline of a workspace of the server's tenant.
+func (r *workspaceRouter) pipeline(workspaceID string) *pipeline {
+	return r.own.base.forWorkspace(workspaceID)
+}
+
+// tenant returns the tenant a call works on: the one of the API key it was
+// authorized with, else the server's. A tenant other than the server's gets
+// its own connection pool, job worker and access tracker the first time one
+// of its keys is used.
+func (r *workspaceRouter) tenant(ctx context.Context) (*tenantPipeline, error) {
+	k, ok := authenticatedKey(ctx)
+	if !ok || k.TenantID == r.own.base.database.TenantID() {
+		return r.own, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tenants[k.TenantID]; ok {
+		return t, nil
+	}
+
+	database, err := r.own.base.database.ForTenant(r.ctx, k.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("connect as tenant %s: %w", k.TenantID, err)
+	}
+	base := *r.own.base
+	base.database = database
+	base.queue = jobs.NewQueue(database.Pool(), k.TenantID, database.WorkspaceID())
+	base.tracker = r.own.base.tracker.WithDB(database)
+	base.tracker.Start(r.ctx)
+
+	t := r.startTenant(&base)
+	r.tenants[k.TenantID] = t
+	log.Printf("cortex: serving tenant %s for key %s", k.TenantID, k.Prefix)
+	return t, nil
+}
+
+// defaultWorkspace returns the workspace calls without a workspace argument work on.
+func (r *workspaceRouter) defaultWorkspace() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// allows reports whether a call may reach workspaceID. db.AllWorkspaces,
+// every workspace of the tenant, is allowed only by ALLOWED_WORKSPACES=*.
+func (r *workspaceRouter) allows(workspaceID string) bool {
+	if slices.Contains(r.allowed, db.AllWorkspaces) {
+		return true
+	}
+	if workspaceID == db.AllWorkspaces {
+		return false
+	}
+	return workspaceID == r.defaultWorkspace() ||
+		slices.Contains(r.allowed, workspaceID) || slices.Contains(r.implicit, workspaceID)
+}
+
+// authorize checks that a call reaches only workspaces it may. Without an
+// API key, ALLOWED_WORKSPACES is all that bounds what a call reaches.
+// memory.workspaces lists only the workspaces allowed.
+func (r *workspaceRouter) authorize(tool string, workspaces []string) error {
+	if tool == "memory.workspaces" {
+		return nil
+	}
+	for _, ws := range workspaces {
+		if r.allows(ws) {
+			continue
+		}
+		if ws == db.AllWorkspaces {
+			return fmt.Errorf("scope tenant reaches every workspace: set ALLOWED_WORKSPACES=* to allow it")
+		}
+		return fmt.Errorf("workspace %q is not allowed: add it to ALLOWED_WORKSPACES", ws)
+	}
+	return nil
+}
+
+// resolve returns the pipeline of the tenant and workspace selected by a call.
+func (r *workspaceRouter) resolve(ctx context.Context, params json.RawMessage) (*pipeline, error) {
+	var args struct {
+		Workspace string `json:"workspace"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	workspaceID := args.Workspace
+	if workspaceID == "" {
+		workspaceID = r.defaultWorkspace()
+	} else if !r.allows(workspaceID) {
+		return nil, fmt.Errorf("workspace %q is not allowed: add it to ALLOWED_WORKSPACES", workspaceID)
+	}
+
+	t, err := r.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.base.forWorkspace(workspaceID), nil
+}
+
+// handle returns a handler that builds the tool's handler for the workspace
+// selected by each call and runs it.
+func (r *workspaceRouter) handle(build func(pipe *pipeline) mcp.Handler) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		pipe, err := r.resolve(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return build(pipe)(ctx, params)
+	}
+}
+
+// onRoots makes the workspace of the client's first git root the default
+// workspace, if the server detects its workspace.
+func (r *workspaceRouter) onRoots(ctx context.Context, roots []mcp.Root) {
+	if !r.detect {
+		return
+	}
+	for _, root := range roots {
+		id, err := workspace.DetectRoot(ctx, root.URI)
+		if err != nil {
+			log.Printf("cortex: no workspace for root %s: %v", root.URI, err)
+			continue
+		}
+
+		r.mu.Lock()
+		changed := id != r.current
+		r.current = id
+		r.mu.Unlock()
+		if changed {
+			log.Printf("cortex: using workspace %s detected from root %s", id, root.URI)
+		}
+		return
+	}
+}
+
+// stop waits for the in-flight jobs and final access counts of every tenant,
+// and closes the connections of the tenants selected by API keys. The
+// context passed to newWorkspaceRouter must be cancelled first.
+func (r *workspaceRouter) stop() {
+	r.own.worker.Stop()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tenants {
+		t.worker.Stop()
+		t.base.tracker.Stop()
+		t.base.database.Close()
+	}
+}
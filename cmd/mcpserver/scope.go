@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/johnswift/cortex/internal/jobs"
+)
+
+// workspaceScopes resolves the scope arguments of memory.add and memory.search.
+type workspaceScopes struct {
+	Own     string   // workspace of the call
+	Shared  string   // workspace memory.add writes to with scope "shared"
+	Inherit []string // workspaces searched along with Own by default
+}
+
+// forWorkspace returns the scopes of calls working on workspaceID.
+func (s workspaceScopes) forWorkspace(workspaceID string) workspaceScopes {
+	s.Own = workspaceID
+	return s
+}
+
+// addPipeline returns the pipeline writing to the workspace selected by a
+// memory.add scope.
+func (s workspaceScopes) addPipeline(pipe *pipeline, scope string) (*pipeline, error) {
+	switch scope {
+	case "", "workspace":
+		return pipe, nil
+	case "shared":
+		return pipe.forWorkspace(s.Shared), nil
+	default:
+		return nil, fmt.Errorf("invalid scope %q: must be workspace or shared", scope)
+	}
+}
+
+// searchWorkspaces returns the workspaces a memory.search covers: the given
+// workspaces if any, every workspace of the tenant (all) for scope "tenant",
+// or the call's workspace and the ones it inherits.
+func (s workspaceScopes) searchWorkspaces(scope string, workspaces []string) ([]string, bool, error) {
+	switch scope {
+	case "", "workspace":
+	case "tenant":
+		if len(workspaces) > 0 {
+			return nil, false, fmt.Errorf("workspaces cannot be combined with scope tenant")
+		}
+		return nil, true, nil
+	default:
+		return nil, false, fmt.Errorf("invalid scope %q: must be workspace or tenant", scope)
+	}
+	if len(workspaces) > 0 {
+		return workspaces, false, nil
+	}
+
+	resolved := []string{s.Own}
+	for _, ws := range s.Inherit {
+		if ws != s.Own {
+			resolved = append(resolved, ws)
+		}
+	}
+	return resolved, false, nil
+}
+
+// forWorkspace returns a copy of the pipeline that stores memories, entities
+// and jobs in another workspace of the tenant.
+func (p *pipeline) forWorkspace(workspaceID string) *pipeline {
+	if workspaceID == p.database.WorkspaceID() {
+		return p
+	}
+	c := *p
+	c.database = p.database.WithWorkspace(workspaceID)
+	c.queue = jobs.NewQueue(p.database.Pool(), p.database.TenantID(), workspaceID)
+	return &c
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/mcp"
+	"github.com/johnswift/cortex/internal/sweeper"
+)
+
+// runSweepCommand implements `cortex sweep`.
+func runSweepCommand(args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Report what the sweep would delete, archive and decay without changing anything")
+	all := fs.Bool("all", false, "Sweep every workspace of every tenant instead of only WORKSPACE_ID")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex sweep [--all] [--dry-run]")
+		fmt.Fprintln(os.Stderr, "Runs the sweeper once with TODO_ARCHIVE_DAYS, EVICT_UNACCESSED_DAYS, RETENTION_POLICIES and ARCHIVE_MODE.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, database, err := openCLIDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if err := loadSweeperConfig(cfg); err != nil {
+		return err
+	}
+	cfg.SweeperGlobal = *all
+	pool, err := cfg.sweeperPool(ctx, database)
+	if err != nil {
+		return err
+	}
+	if pool != database.Pool() {
+		defer pool.Close()
+	}
+	sw := sweeper.NewSweeper(pool, cfg.TenantID, cfg.WorkspaceID).WithConfig(cfg.sweeperConfig())
+
+	if !*dryRun {
+		run := sw.RunOnce(ctx)
+		switch {
+		case run.Skipped:
+			return fmt.Errorf("another instance is sweeping; try again later")
+		case run.Error != "":
+			return fmt.Errorf("sweep: %s", run.Error)
+		}
+
+		var removed, failed int64
+		for _, ws := range run.Workspaces {
+			fmt.Printf("%s/%s: removed %d, decayed %d, archived %d todos\n",
+				ws.TenantID, ws.WorkspaceID, ws.Removed(), ws.Decayed, ws.TodosArchived)
+			removed += ws.Removed()
+			failed += int64(len(ws.Errors))
+		}
+		fmt.Printf("removed %d memories in %d workspaces\n", removed, len(run.Workspaces))
+		if failed > 0 {
+			return fmt.Errorf("%d sweep steps failed", failed)
+		}
+		return nil
+	}
+
+	report, err := sw.Preview(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range report.Changes {
+		detail := c.Reason
+		if c.Action == sweeper.ActionDecay {
+			detail = fmt.Sprintf("%s, importance -> %.2f", c.Reason, c.Importance)
+		}
+		if *all {
+			fmt.Printf("%s/%s: ", c.TenantID, c.WorkspaceID)
+		}
+		fmt.Printf("%-7s %d [%s] %s (%s)\n", c.Action, c.MemoryID, c.Kind, previewText(c.Text, 60), detail)
+	}
+	fmt.Printf("would delete %d, decay %d and archive %d memories\n",
+		report.Count(sweeper.ActionDelete), report.Count(sweeper.ActionDecay), report.Count(sweeper.ActionArchive))
+	return nil
+}
+
+// previewText returns the first line of text, shortened to at most maxLen bytes.
+func previewText(text string, maxLen int) string {
+	text, _, _ = strings.Cut(text, "\n")
+	if len(text) <= maxLen {
+		return text
+	}
+	return text[:maxLen-3] + "..."
+}
+
+// sweeperPool returns the connection pool the sweeper uses: the database's
+// own, or one that sees every tenant when sweeping globally.
+func (cfg *Config) sweeperPool(ctx context.Context, database *db.DB) (*pgxpool.Pool, error) {
+	if !cfg.SweeperGlobal {
+		return database.Pool(), nil
+	}
+	pool, err := db.NewAllTenantsPool(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect sweeper to database: %w", err)
+	}
+	return pool, nil
+}
+
+// sweeperStatus returns how the server's sweeper is configured, for memory.sweeper_status.
+func (cfg *Config) sweeperStatus() mcp.MemorySweeperStatusResult {
+	status := mcp.MemorySweeperStatusResult{Enabled: cfg.SweeperEnabled}
+	if !cfg.SweeperEnabled {
+		return status
+	}
+	status.Interval = cfg.SweeperInterval.String()
+	status.Global = cfg.SweeperGlobal
+	status.ArchiveMode = string(cfg.ArchiveMode)
+	for _, p := range cfg.RetentionPolicies {
+		status.Policies = append(status.Policies, p.Name)
+	}
+	return status
+}
+
+func createSweeperStatusHandler(database *db.DB, status mcp.MemorySweeperStatusResult) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemorySweeperStatusArgs
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+
+		k := 10
+		if args.K != nil {
+			k = *args.K
+		}
+		if k < 1 || k > 100 {
+			return nil, fmt.Errorf("k must be between 1 and 100")
+		}
+
+		runs, err := database.ListSweeperRuns(ctx, k)
+		if err != nil {
+			return nil, fmt.Errorf("list sweeper runs: %w", err)
+		}
+
+		result := status
+		result.Runs = make([]mcp.SweeperRunResult, 0, len(runs))
+		for _, r := range runs {
+			run := mcp.SweeperRunResult{
+				ID:            r.ID,
+				StartedAt:     r.StartedAt.Format(time.RFC3339),
+				FinishedAt:    r.FinishedAt.Format(time.RFC3339),
+				Global:        r.Global,
+				Skipped:       r.Skipped,
+				Removed:       r.Removed(),
+				Expired:       r.Expired,
+				Evicted:       r.Evicted,
+				PolicyExpired: r.PolicyExpired,
+				Decayed:       r.Decayed,
+				TodosArchived: r.TodosArchived,
+				Errors:        r.Errors,
+			}
+			if r.Error != nil {
+				run.Error = *r.Error
+			}
+			result.Runs = append(result.Runs, run)
+		}
+		return result, nil
+	}
+}
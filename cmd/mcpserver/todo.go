@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/mcp"
+)
+
+// setTodoFields applies the todo fields of memory.add or memory.update to a
+// todo memory. The assignee is stored as a person entity linked to the memory.
+func setTodoFields(ctx context.Context, database *db.DB, memoryID int64, fields mcp.TodoFields) error {
+	var params db.UpdateTodoParams
+
+	if fields.Status != "" {
+		status, err := db.ParseTodoStatus(fields.Status)
+		if err != nil {
+			return err
+		}
+		params.Status = &status
+	}
+	if fields.DueAt != "" {
+		dueAt, err := parseDueAt(fields.DueAt)
+		if err != nil {
+			return err
+		}
+		params.DueAt = &dueAt
+	}
+	if fields.Priority != "" {
+		priority, err := db.ParseTodoPriority(fields.Priority)
+		if err != nil {
+			return err
+		}
+		params.Priority = &priority
+	}
+	if fields.Assignee != "" {
+		assigneeID, err := database.AddEntity(ctx, db.AddEntityParams{
+			Name: fields.Assignee,
+			Type: db.EntityTypePerson,
+		})
+		if err != nil {
+			return fmt.Errorf("store assignee: %w", err)
+		}
+		params.AssigneeID = &assigneeID
+
+		role := "assignee"
+		if err := database.LinkMemoryEntity(ctx, memoryID, assigneeID, &role, 1.0); err != nil {
+			log.Printf("cortex: warning: failed to link assignee %q to memory %d: %v", fields.Assignee, memoryID, err)
+		}
+	}
+
+	return database.UpdateTodo(ctx, memoryID, params)
+}
+
+// parseDueAt parses an RFC 3339 timestamp, or a date meaning the end of that day in UTC.
+func parseDueAt(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due_at %q: expected RFC 3339 or YYYY-MM-DD", s)
+	}
+	return day.Add(24*time.Hour - time.Second), nil
+}
+
+// todoResult converts a todo for the response.
+func todoResult(t db.Todo, now time.Time) mcp.TodoResult {
+	result := mcp.TodoResult{
+		ID:        t.ID,
+		Text:      t.Text,
+		Status:    string(t.Status),
+		Priority:  t.PriorityName(),
+		Assignee:  t.Assignee,
+		Tags:      t.Tags,
+		CreatedAt: t.CreatedAt.Format(time.RFC3339),
+	}
+	if t.DueAt != nil {
+		result.DueAt = t.DueAt.Format(time.RFC3339)
+		result.Overdue = t.DueAt.Before(now) && (t.Status == db.TodoOpen || t.Status == db.TodoInProgress)
+	}
+	if t.CompletedAt != nil {
+		result.CompletedAt = t.CompletedAt.Format(time.RFC3339)
+	}
+	return result
+}
+
+func createTodoListHandler(database *db.DB) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryTodoListArgs
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+
+		filter := db.TodoFilter{Overdue: args.Overdue, Limit: 50}
+		if args.K != nil {
+			filter.Limit = *args.K
+		}
+		for _, name := range args.Status {
+			status, err := db.ParseTodoStatus(name)
+			if err != nil {
+				return nil, err
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+		if args.DueWithinDays != nil {
+			dueBefore := time.Now().AddDate(0, 0, *args.DueWithinDays)
+			filter.DueBefore = &dueBefore
+		}
+
+		result := mcp.MemoryTodoListResult{Todos: []mcp.TodoResult{}}
+
+		if args.Assignee != "" {
+			assignee, err := database.FindEntityByName(ctx, args.Assignee, db.EntityTypePerson)
+			if err != nil {
+				return nil, fmt.Errorf("find assignee: %w", err)
+			}
+			if assignee == nil {
+				return result, nil // Nobody by that name has been assigned anything
+			}
+			filter.AssigneeID = &assignee.ID
+		}
+
+		todos, err := database.ListTodos(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("list todos: %w", err)
+		}
+
+		now := time.Now()
+		for _, t := range todos {
+			result.Todos = append(result.Todos, todoResult(t, now))
+		}
+
+		return result, nil
+	}
+}
+
+func createTodoCompleteHandler(database *db.DB) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryTodoCompleteArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		if args.ID == 0 {
+			return nil, fmt.Errorf("id is required")
+		}
+
+		done := db.TodoDone
+		if err := database.UpdateTodo(ctx, args.ID, db.UpdateTodoParams{Status: &done}); err != nil {
+			return nil, fmt.Errorf("complete todo: %w", err)
+		}
+		recordAudit(ctx, database, nil, args.ID)
+
+		todo, err := database.GetTodo(ctx, args.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get todo: %w", err)
+		}
+		if todo == nil {
+			return nil, fmt.Errorf("todo not found")
+		}
+
+		return todoResult(*todo, time.Now()), nil
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/johnswift/cortex/internal/chunk"
+	"github.com/johnswift/cortex/internal/jobs"
+	"github.com/johnswift/cortex/internal/watch"
+)
+
+// stringList is a repeatable flag whose values may also be comma-separated.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*s = append(*s, item)
+		}
+	}
+	return nil
+}
+
+// runWatchCommand implements `cortex watch`.
+func runWatchCommand(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	var include, exclude stringList
+	fs.Var(&include, "include", "Gitignore-style pattern of files to ingest (repeatable; default: *.md, *.markdown, *.mdx, *.txt, *.go)")
+	fs.Var(&exclude, "exclude", "Gitignore-style pattern of files to skip (repeatable)")
+	useGitignore := fs.Bool("gitignore", true, "Also skip files matched by <dir>/.gitignore")
+	interval := fs.Duration("interval", 5*time.Second, "Polling interval")
+	once := fs.Bool("once", false, "Sync once and exit")
+	tags := fs.String("tags", "", "Comma-separated tags for the documents")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex watch [flags] <dir>")
+		fmt.Fprintln(os.Stderr, "Keeps the workspace in sync with the files in <dir>: new and changed files are")
+		fmt.Fprintln(os.Stderr, "ingested as documents with source \"file:<path>\", removed files are deleted.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one directory is required")
+	}
+	root := fs.Arg(0)
+
+	if len(include) == 0 {
+		for ext := range ingestExtensions {
+			include = append(include, "*"+ext)
+		}
+		sort.Strings(include)
+	}
+	excludePatterns := []string(exclude)
+	if *useGitignore {
+		ignore, err := readLines(filepath.Join(root, ".gitignore"))
+		if err != nil {
+			return err
+		}
+		excludePatterns = append(ignore, excludePatterns...)
+	}
+
+	watcher, err := watch.New(root, watch.ParsePatterns(include), watch.ParsePatterns(excludePatterns))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, database, err := openCLIDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	pipe, err := newCLIPipeline(cfg, database, cfg.EntityExtraction)
+	if err != nil {
+		return err
+	}
+
+	// Seed the watcher with the documents ingested by earlier runs, so that
+	// unchanged files are not re-ingested and files removed meanwhile are deleted
+	prefix := "file:" + watcher.Root() + string(filepath.Separator)
+	refs, err := database.ListDocumentsBySourcePrefix(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		watcher.Seed(strings.TrimPrefix(ref.Source, "file:"), ref.ContentHash)
+	}
+
+	s := &dirSync{pipe: pipe, watcher: watcher, tags: splitList(*tags)}
+
+	if *once {
+		return s.sync(ctx)
+	}
+
+	// Retry failed embeddings/extractions while watching
+	worker := jobs.NewWorker(pipe.queue)
+	pipe.registerJobHandlers(worker)
+	worker.Start(ctx)
+	defer worker.Stop()
+
+	log.Printf("cortex: watching %s (interval=%v, %d known documents)", watcher.Root(), *interval, len(refs))
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		if err := s.sync(ctx); err != nil {
+			log.Printf("cortex: watch error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("cortex: watch stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// dirSync applies watcher events to the workspace.
+type dirSync struct {
+	pipe    *pipeline
+	watcher *watch.Watcher
+	tags    []string
+}
+
+// sync polls the watcher once and ingests or deletes the affected documents.
+// Events that fail are retried on the next sync.
+func (s *dirSync) sync(ctx context.Context) error {
+	events, err := s.watcher.Poll()
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[watch.Op]int)
+	var failed int
+	for _, ev := range events {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var err error
+		if ev.Op == watch.OpRemove {
+			err = s.remove(ctx, ev.Path)
+		} else {
+			err = s.ingest(ctx, ev)
+		}
+		if err != nil {
+			log.Printf("cortex: watch: failed to sync %s (%s): %v", ev.Path, ev.Op, err)
+			s.watcher.Retry(ev)
+			failed++
+			continue
+		}
+		counts[ev.Op]++
+	}
+
+	if len(events) > 0 {
+		log.Printf("cortex: watch: %d added, %d changed, %d removed, %d failed",
+			counts[watch.OpAdd], counts[watch.OpChange], counts[watch.OpRemove], failed)
+	}
+	return nil
+}
+
+func (s *dirSync) ingest(ctx context.Context, ev watch.Event) error {
+	doc, err := readDocument(ev.Path)
+	if err != nil {
+		return err
+	}
+	doc.Tags = s.tags
+	doc.Meta = map[string]any{"content_hash": ev.Hash}
+
+	if strings.TrimSpace(doc.Text) == "" {
+		// Nothing to search; drop any earlier version of the file
+		return s.remove(ctx, ev.Path)
+	}
+
+	_, err = s.pipe.ingest(ctx, doc, chunk.DefaultOptions())
+	return err
+}
+
+func (s *dirSync) remove(ctx context.Context, path string) error {
+	existing, err := s.pipe.database.FindDocumentBySource(ctx, "file:"+path)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+	return s.pipe.database.DeleteMemory(ctx, existing.ID)
+}
+
+// readLines reads a file's lines. A missing file yields no lines.
+func readLines(name string) ([]string, error) {
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(data), "\n"), nil
+}
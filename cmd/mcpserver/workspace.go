@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/johnswift/cortex/internal/db"
+	"github.com/johnswift/cortex/internal/mcp"
+)
+
+// workspaceMoves maps the rename, clone and merge actions to their DB operations.
+var workspaceMoves = map[string]func(database *db.DB, ctx context.Context, from, to string) (*db.WorkspaceCounts, error){
+	"rename": (*db.DB).RenameWorkspace,
+	"clone":  (*db.DB).CloneWorkspace,
+	"merge":  (*db.DB).MergeWorkspace,
+}
+
+// runWorkspaceCommand implements `cortex workspace`.
+func runWorkspaceCommand(args []string) error {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: cortex workspace list")
+		fmt.Fprintln(os.Stderr, "       cortex workspace rename|clone|merge <from> <to>")
+		fmt.Fprintln(os.Stderr, "Operates on the workspaces of TENANT_ID.")
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("an action is required")
+	}
+
+	action := args[0]
+	move, isMove := workspaceMoves[action]
+	switch {
+	case action == "list" && len(args) == 1:
+	case isMove && len(args) == 3:
+	default:
+		usage()
+		return fmt.Errorf("invalid workspace command")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, database, err := openCLIDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if !isMove {
+		workspaces, err := database.ListWorkspaces(ctx)
+		if err != nil {
+			return err
+		}
+		for _, w := range workspaces {
+			lastActivity := "-"
+			if w.LastActivity != nil {
+				lastActivity = w.LastActivity.Format(time.RFC3339)
+			}
+			fmt.Printf("%-24s %6d memories %4d documents %5d entities %5d archived %10d bytes  %s\n",
+				w.ID, w.Memories, w.Documents, w.Entities, w.Archived, w.TextBytes+w.EmbeddingBytes, lastActivity)
+		}
+		return nil
+	}
+
+	from, to := args[1], args[2]
+	counts, err := move(database, ctx, from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s -> %s: %d memories, %d entities (%d merged), %d relations, %d links\n",
+		action, from, to, counts.Memories, counts.Entities, counts.EntitiesMerged, counts.Relations, counts.Links)
+	return nil
+}
+
+// createWorkspacesHandler returns the handler of memory.workspaces, which
+// lists the workspaces visible reports that calls may select.
+func createWorkspacesHandler(database *db.DB, visible func(workspaceID string) bool) mcp.Handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		workspaces, err := database.ListWorkspaces(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list workspaces: %w", err)
+		}
+
+		result := mcp.MemoryWorkspacesResult{
+			Current:    database.WorkspaceID(),
+			Workspaces: make([]mcp.WorkspaceResult, 0, len(workspaces)),
+		}
+		for _, w := range workspaces {
+			if !visible(w.ID) {
+				continue
+			}
+			ws := mcp.WorkspaceResult{
+				ID:             w.ID,
+				Memories:       w.Memories,
+				Documents:      w.Documents,
+				Entities:       w.Entities,
+				Archived:       w.Archived,
+				TextBytes:      w.TextBytes,
+				EmbeddingBytes: w.EmbeddingBytes,
+			}
+			if w.LastActivity != nil {
+				ws.LastActivity = w.LastActivity.Format(time.RFC3339)
+			}
+			result.Workspaces = append(result.Workspaces, ws)
+		}
+		return result, nil
+	}
+}
+
+// createWorkspaceMoveHandler returns the handler of memory.workspace_rename,
+// memory.workspace_clone or memory.workspace_merge, according to action.
+func createWorkspaceMoveHandler(database *db.DB, action string) mcp.Handler {
+	move := workspaceMoves[action]
+
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args mcp.MemoryWorkspaceMoveArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		if args.From == "" || args.To == "" {
+			return nil, fmt.Errorf("from and to are required")
+		}
+
+		counts, err := move(database, ctx, args.From, args.To)
+		if err != nil {
+			return nil, err
+		}
+		// Recorded where the memories are now; a renamed workspace is gone
+		recordAuditIn(ctx, database, args.To, map[string]any{
+			"action": action, "from": args.From, "to": args.To, "memories": counts.Memories,
+		})
+
+		return mcp.MemoryWorkspaceMoveResult{
+			Memories:       counts.Memories,
+			Entities:       counts.Entities,
+			EntitiesMerged: counts.EntitiesMerged,
+			Relations:      counts.Relations,
+			Links:          counts.Links,
+		}, nil
+	}
+}
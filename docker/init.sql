@@ -1,5 +1,12 @@
 -- Extensions are created automatically in pgvector/pgvector image
 -- pgvector extension is pre-installed, just need pg_trgm for lexical search
+CREATE EXTENSION IF NOT EXISTS vector;
 CREATE EXTENSION IF NOT EXISTS pg_trgm;
 
--- Schema will be created by embedded migrations on first run
+-- Cortex connects as an ordinary role: row-level security does not apply to
+-- the superuser POSTGRES_USER, so tenants would not be isolated
+CREATE ROLE cortex_app LOGIN PASSWORD 'cortex_app' NOSUPERUSER NOBYPASSRLS;
+GRANT CONNECT ON DATABASE cortex TO cortex_app;
+GRANT USAGE, CREATE ON SCHEMA public TO cortex_app;
+
+-- Schema will be created by embedded migrations on first run, owned by cortex_app
@@ -0,0 +1,195 @@
+// Package access records which memories are read, batching the updates to
+// access counts so that every search does not turn into a write per result.
+package access
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/johnswift/cortex/internal/db"
+)
+
+// Config controls how often recorded reads are written to the database.
+type Config struct {
+	FlushInterval time.Duration // write pending reads at least this often
+	MaxPending    int           // write early once this many memories have pending reads
+}
+
+// DefaultConfig returns the default tracker configuration.
+func DefaultConfig() Config {
+	return Config{
+		FlushInterval: 30 * time.Second,
+		MaxPending:    1000,
+	}
+}
+
+// Tracker accumulates reads in memory and periodically writes them as one batch.
+type Tracker struct {
+	database *db.DB
+	config   Config
+
+	mu      sync.Mutex
+	pending map[int64]*db.Access
+	flushCh chan struct{}
+	running bool
+	done    chan struct{}
+}
+
+// NewTracker creates a new access tracker.
+func NewTracker(database *db.DB) *Tracker {
+	return &Tracker{
+		database: database,
+		config:   DefaultConfig(),
+		pending:  make(map[int64]*db.Access),
+		flushCh:  make(chan struct{}, 1),
+	}
+}
+
+// WithConfig returns a new Tracker with the given configuration.
+func (t *Tracker) WithConfig(cfg Config) *Tracker {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultConfig().FlushInterval
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = DefaultConfig().MaxPending
+	}
+	return &Tracker{
+		database: t.database,
+		config:   cfg,
+		pending:  make(map[int64]*db.Access),
+		flushCh:  make(chan struct{}, 1),
+	}
+}
+
+// WithDB returns a new Tracker with the same configuration that writes reads
+// to database, such as the database of another tenant.
+func (t *Tracker) WithDB(database *db.DB) *Tracker {
+	return NewTracker(database).WithConfig(t.config)
+}
+
+// Record notes that the given memories were read. It never blocks on the database.
+func (t *Tracker) Record(ids ...int64) {
+	if len(ids) == 0 {
+		return
+	}
+
+	now := time.Now()
+	t.mu.Lock()
+	for _, id := range ids {
+		a, ok := t.pending[id]
+		if !ok {
+			a = &db.Access{MemoryID: id}
+			t.pending[id] = a
+		}
+		a.Count++
+		a.At = now
+	}
+	full := len(t.pending) >= t.config.MaxPending
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.flushCh <- struct{}{}:
+		default: // a flush is already requested
+		}
+	}
+}
+
+// Flush writes all pending reads. If the write fails, the reads are kept
+// and retried on the next flush.
+func (t *Tracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	batch := make([]db.Access, 0, len(t.pending))
+	for _, a := range t.pending {
+		batch = append(batch, *a)
+	}
+	t.pending = make(map[int64]*db.Access)
+	t.mu.Unlock()
+
+	if err := t.database.RecordAccess(ctx, batch); err != nil {
+		t.requeue(batch)
+		return err
+	}
+	return nil
+}
+
+// requeue merges reads from a failed flush back into the pending set.
+func (t *Tracker) requeue(batch []db.Access) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, b := range batch {
+		a, ok := t.pending[b.MemoryID]
+		if !ok {
+			a = &db.Access{MemoryID: b.MemoryID, At: b.At}
+			t.pending[b.MemoryID] = a
+		}
+		a.Count += b.Count
+		if b.At.After(a.At) {
+			a.At = b.At
+		}
+	}
+}
+
+// Start begins the goroutine that periodically flushes pending reads.
+// When ctx is cancelled, pending reads are flushed one last time.
+func (t *Tracker) Start(ctx context.Context) {
+	t.mu.Lock()
+	if t.running {
+		t.mu.Unlock()
+		log.Printf("[access] already running")
+		return
+	}
+	t.running = true
+	t.done = make(chan struct{})
+	t.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(t.config.FlushInterval)
+		defer ticker.Stop()
+		defer func() {
+			t.mu.Lock()
+			t.running = false
+			close(t.done)
+			t.mu.Unlock()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				// Final flush with a fresh context since ctx is already cancelled
+				flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := t.Flush(flushCtx); err != nil {
+					log.Printf("[access] error flushing access counts on shutdown: %v", err)
+				}
+				cancel()
+				return
+			case <-ticker.C:
+			case <-t.flushCh:
+			}
+
+			if err := t.Flush(ctx); err != nil {
+				log.Printf("[access] error flushing access counts: %v", err)
+			}
+		}
+	}()
+}
+
+// Stop waits for the flush goroutine to finish after its context is cancelled.
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	if !t.running {
+		t.mu.Unlock()
+		return
+	}
+	done := t.done
+	t.mu.Unlock()
+
+	<-done
+}
@@ -0,0 +1,300 @@
+// Package chunk splits long documents into overlapping chunks for embedding.
+package chunk
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format identifies how a document is split.
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatMarkdown Format = "markdown"
+	FormatGo       Format = "go"
+)
+
+// ParseFormat parses a format name. An empty name yields FormatText.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "text", "txt":
+		return FormatText, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "go", "golang":
+		return FormatGo, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (must be 'text', 'markdown' or 'go')", name)
+	}
+}
+
+// DetectFormat picks a format from a file's extension, defaulting to FormatText.
+func DetectFormat(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".mdx":
+		return FormatMarkdown
+	case ".go":
+		return FormatGo
+	default:
+		return FormatText
+	}
+}
+
+// Options controls chunk sizes.
+type Options struct {
+	// MaxChars is the maximum size of a chunk in bytes, including overlap.
+	MaxChars int
+	// Overlap is the number of bytes repeated from the end of the previous chunk.
+	Overlap int
+}
+
+// DefaultOptions returns a sensible default configuration: roughly 500 tokens
+// per chunk with 50 tokens of overlap.
+func DefaultOptions() Options {
+	return Options{
+		MaxChars: 2000,
+		Overlap:  200,
+	}
+}
+
+// Chunk is a contiguous piece of a document.
+type Chunk struct {
+	Index     int    `json:"index"`
+	Text      string `json:"text"`
+	Section   string `json:"section,omitempty"` // Markdown heading path or Go declaration
+	StartLine int    `json:"start_line"`        // 1-based, inclusive
+	EndLine   int    `json:"end_line"`          // 1-based, inclusive
+}
+
+// block is a structural unit of a document (paragraph, section, declaration)
+// that chunks are packed from.
+type block struct {
+	text      string
+	section   string
+	startLine int
+	endLine   int
+}
+
+// Split splits text into chunks. Structural boundaries (paragraphs, headings,
+// top-level declarations) are kept where possible; blocks larger than a chunk
+// are split by lines, and lines larger than a chunk by words.
+func Split(text string, format Format, opts Options) []Chunk {
+	if opts.MaxChars <= 0 {
+		opts = DefaultOptions()
+	}
+	if opts.Overlap < 0 || opts.Overlap >= opts.MaxChars/2 {
+		opts.Overlap = opts.MaxChars / 10
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	var blocks []block
+	switch format {
+	case FormatMarkdown:
+		blocks = markdownBlocks(text)
+	case FormatGo:
+		blocks = goBlocks(text)
+	default:
+		blocks = paragraphBlocks(text)
+	}
+
+	chunks := pack(blocks, opts.MaxChars-opts.Overlap)
+	addOverlap(chunks, opts.Overlap)
+	return chunks
+}
+
+// pack greedily combines blocks into chunks of at most budget bytes.
+func pack(blocks []block, budget int) []Chunk {
+	var chunks []Chunk
+	var cur []block
+	size := 0
+
+	flush := func() {
+		if len(cur) == 0 {
+			return
+		}
+		parts := make([]string, len(cur))
+		for i, b := range cur {
+			parts[i] = b.text
+		}
+		chunks = append(chunks, Chunk{
+			Index:     len(chunks),
+			Text:      strings.Join(parts, "\n\n"),
+			Section:   cur[0].section,
+			StartLine: cur[0].startLine,
+			EndLine:   cur[len(cur)-1].endLine,
+		})
+		cur = nil
+		size = 0
+	}
+
+	for _, b := range blocks {
+		if len(b.text) > budget {
+			flush()
+			for _, piece := range splitBlock(b, budget) {
+				cur = []block{piece}
+				flush()
+			}
+			continue
+		}
+		if len(cur) > 0 && size+2+len(b.text) > budget {
+			flush()
+		}
+		if len(cur) > 0 {
+			size += 2
+		}
+		cur = append(cur, b)
+		size += len(b.text)
+	}
+	flush()
+
+	return chunks
+}
+
+// splitBlock splits an oversized block into pieces of at most budget bytes,
+// breaking between lines where possible.
+func splitBlock(b block, budget int) []block {
+	var pieces []block
+	var cur []string
+	size := 0
+	start := b.startLine
+
+	flush := func(endLine int) {
+		if len(cur) == 0 {
+			return
+		}
+		pieces = append(pieces, block{
+			text:      strings.Join(cur, "\n"),
+			section:   b.section,
+			startLine: start,
+			endLine:   endLine,
+		})
+		cur = nil
+		size = 0
+	}
+
+	for i, line := range strings.Split(b.text, "\n") {
+		lineNo := b.startLine + i
+		if len(line) > budget {
+			flush(lineNo - 1)
+			for _, part := range splitWords(line, budget) {
+				pieces = append(pieces, block{text: part, section: b.section, startLine: lineNo, endLine: lineNo})
+			}
+			start = lineNo + 1
+			continue
+		}
+		if len(cur) > 0 && size+1+len(line) > budget {
+			flush(lineNo - 1)
+			start = lineNo
+		}
+		if len(cur) > 0 {
+			size++
+		}
+		cur = append(cur, line)
+		size += len(line)
+	}
+	flush(b.endLine)
+
+	return pieces
+}
+
+// splitWords splits a single long line at spaces, or at rune boundaries if a
+// word is itself longer than budget.
+func splitWords(line string, budget int) []string {
+	var parts []string
+	for len(line) > budget {
+		cut := strings.LastIndexByte(line[:budget+1], ' ')
+		if cut <= 0 {
+			cut = runeBoundary(line, budget)
+		}
+		parts = append(parts, strings.TrimRight(line[:cut], " "))
+		line = strings.TrimLeft(line[cut:], " ")
+	}
+	if line != "" {
+		parts = append(parts, line)
+	}
+	return parts
+}
+
+// addOverlap prefixes each chunk after the first with the tail of the previous one.
+func addOverlap(chunks []Chunk, overlap int) {
+	if overlap <= 0 {
+		return
+	}
+	for i := len(chunks) - 1; i > 0; i-- {
+		if prefix := strings.TrimLeft(tail(chunks[i-1].Text, overlap), "\n"); prefix != "" {
+			chunks[i].Text = prefix + "\n" + chunks[i].Text
+		}
+	}
+}
+
+// tail returns roughly the last n bytes of s, starting at a line or word boundary.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	if i := strings.IndexByte(s[start:], '\n'); i >= 0 && i < n/2 {
+		return s[start+i+1:]
+	}
+	if i := strings.IndexByte(s[start:], ' '); i >= 0 && i < n/2 {
+		return s[start+i+1:]
+	}
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
+}
+
+// runeBoundary returns the largest index <= n that starts a rune in s.
+func runeBoundary(s string, n int) int {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	if n == 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		return size
+	}
+	return n
+}
+
+// paragraphBlocks splits text into blank-line separated paragraphs.
+func paragraphBlocks(text string) []block {
+	var blocks []block
+	var cur []string
+	start := 0
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if len(cur) > 0 {
+				blocks = append(blocks, block{
+					text:      strings.Join(cur, "\n"),
+					startLine: start + 1,
+					endLine:   i,
+				})
+				cur = nil
+			}
+			continue
+		}
+		if len(cur) == 0 {
+			start = i
+		}
+		cur = append(cur, line)
+	}
+	if len(cur) > 0 {
+		blocks = append(blocks, block{
+			text:      strings.Join(cur, "\n"),
+			startLine: start + 1,
+			endLine:   len(lines),
+		})
+	}
+
+	return blocks
+}
@@ -0,0 +1,107 @@
+package chunk
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// goBlocks splits Go source into top-level declarations, each together with
+// its doc comment. Text between declarations (package clause, imports, free
+// comments) is attached to the following declaration. Source that does not
+// parse is split into paragraphs instead.
+func goBlocks(text string) []block {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", text, parser.ParseComments)
+	if err != nil || len(file.Decls) == 0 {
+		return paragraphBlocks(text)
+	}
+
+	tf := fset.File(file.Pos())
+	var blocks []block
+	offset := 0 // start of the text not yet assigned to a block
+
+	for _, decl := range file.Decls {
+		end := tf.Offset(decl.End())
+		// Include a trailing comment on the declaration's last line
+		if nl := strings.IndexByte(text[end:], '\n'); nl >= 0 {
+			end += nl
+		} else {
+			end = len(text)
+		}
+		if end <= offset {
+			continue
+		}
+
+		blocks = append(blocks, goBlock(text, offset, end, declName(decl)))
+		offset = end
+	}
+
+	// Trailing comments after the last declaration
+	if strings.TrimSpace(text[offset:]) != "" {
+		blocks = append(blocks, goBlock(text, offset, len(text), ""))
+	}
+
+	return blocks
+}
+
+// goBlock builds a block from text[start:end], trimming surrounding blank lines.
+func goBlock(text string, start, end int, name string) block {
+	for start < end && (text[start] == '\n' || text[start] == ' ' || text[start] == '\t') {
+		start++
+	}
+	startLine := strings.Count(text[:start], "\n") + 1
+	body := strings.TrimRight(text[start:end], " \t\n")
+	return block{
+		text:      body,
+		section:   name,
+		startLine: startLine,
+		endLine:   startLine + strings.Count(body, "\n"),
+	}
+}
+
+// declName describes a top-level declaration, e.g. "func (*DB) AddMemory" or "type Memory".
+func declName(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return "func (" + exprString(d.Recv.List[0].Type) + ") " + d.Name.Name
+		}
+		return "func " + d.Name.Name
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			return d.Tok.String()
+		}
+		return d.Tok.String() + " " + strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// exprString renders a receiver type expression.
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.IndexExpr:
+		return exprString(e.X) + "[" + exprString(e.Index) + "]"
+	case *ast.IndexListExpr:
+		return exprString(e.X) + "[...]"
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	}
+	return "?"
+}
@@ -0,0 +1,133 @@
+package chunk
+
+import (
+	"strings"
+)
+
+// markdownBlocks splits Markdown into paragraphs and fenced code blocks,
+// tracking the heading path of each. Headings are kept with the block that
+// follows them so a chunk never ends on a bare heading.
+func markdownBlocks(text string) []block {
+	var blocks []block
+	var headings []string // heading path, indexed by level-1
+	var cur []string
+	start := 0
+	headingOnly := false // cur holds only headings (and blank lines after them)
+	fence := ""          // opening fence marker while inside a code block
+
+	section := func() string {
+		var parts []string
+		for _, h := range headings {
+			if h != "" {
+				parts = append(parts, h)
+			}
+		}
+		return strings.Join(parts, " > ")
+	}
+	curSection := ""
+
+	flush := func(end int) {
+		if len(cur) == 0 {
+			return
+		}
+		blocks = append(blocks, block{
+			text:      strings.TrimRight(strings.Join(cur, "\n"), "\n"),
+			section:   curSection,
+			startLine: start + 1,
+			endLine:   end + 1,
+		})
+		cur = nil
+		headingOnly = false
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		// Inside a fenced code block: everything belongs to the block
+		if fence != "" {
+			cur = append(cur, line)
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+				flush(i)
+			}
+			continue
+		}
+
+		if marker := fenceMarker(trimmed); marker != "" {
+			if !headingOnly {
+				flush(i - 1)
+			}
+			if len(cur) == 0 {
+				start = i
+				curSection = section()
+			}
+			cur = append(cur, line)
+			fence = marker
+			continue
+		}
+
+		if level, title := parseHeading(trimmed); level > 0 {
+			if !headingOnly {
+				flush(i - 1)
+			}
+			// Replace this level and drop deeper ones
+			if len(headings) < level {
+				headings = append(headings, make([]string, level-len(headings))...)
+			}
+			headings = append(headings[:level-1], title)
+			if len(cur) == 0 {
+				start = i
+			}
+			curSection = section()
+			cur = append(cur, line)
+			headingOnly = true
+			continue
+		}
+
+		if trimmed == "" {
+			if headingOnly {
+				cur = append(cur, "")
+				continue
+			}
+			flush(i - 1)
+			continue
+		}
+
+		if len(cur) == 0 {
+			start = i
+			curSection = section()
+		}
+		cur = append(cur, line)
+		headingOnly = false
+	}
+	flush(len(lines) - 1)
+
+	return blocks
+}
+
+// parseHeading returns the level and title of an ATX heading, or 0 if line is not one.
+func parseHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(strings.TrimRight(rest, "# "))
+}
+
+// fenceMarker returns the fence (``` or ~~~) that opens a code block, or "".
+func fenceMarker(line string) string {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			return marker
+		}
+	}
+	return ""
+}
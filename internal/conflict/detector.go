@@ -0,0 +1,156 @@
+// Package conflict provides LLM-based contradiction detection between memories.
+package conflict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultMinSimilarity is the minimum vector similarity for an existing memory
+// to be considered a contradiction candidate.
+const DefaultMinSimilarity = 0.7
+
+// DefaultCandidates is the number of similar memories checked for contradictions.
+const DefaultCandidates = 5
+
+// ChatProvider is the interface for LLM text completion.
+type ChatProvider interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// Candidate is an existing memory that may contradict a new one.
+type Candidate struct {
+	ID   int64
+	Kind string
+	Text string
+}
+
+// Contradiction describes an existing memory that conflicts with new text.
+type Contradiction struct {
+	MemoryID   int64   `json:"id"`
+	Reason     string  `json:"reason"`
+	Confidence float32 `json:"confidence,omitempty"` // 0-1 confidence score
+}
+
+// Detector uses an LLM to decide whether new text contradicts existing memories.
+type Detector struct {
+	llm           ChatProvider
+	kinds         map[string]bool
+	minConfidence float32
+}
+
+// NewDetector creates a new contradiction detector.
+// Only "fact" and "preference" memories are checked by default.
+func NewDetector(llm ChatProvider) *Detector {
+	return &Detector{
+		llm:           llm,
+		kinds:         map[string]bool{"fact": true, "preference": true},
+		minConfidence: 0.5,
+	}
+}
+
+// Checks reports whether memories of the given kind are checked for contradictions.
+func (d *Detector) Checks(kind string) bool {
+	return d.kinds[kind]
+}
+
+// Detect asks the LLM which candidates contradict the given text.
+// Candidates the LLM does not flag, or flags with low confidence, are omitted.
+func (d *Detector) Detect(ctx context.Context, text string, candidates []Candidate) ([]Contradiction, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	prompt := buildDetectionPrompt(text, candidates)
+
+	response, err := d.llm.Complete(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("llm complete: %w", err)
+	}
+
+	contradictions, err := parseDetectionResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	// Keep only contradictions that refer to a known candidate
+	known := make(map[int64]bool, len(candidates))
+	for _, c := range candidates {
+		known[c.ID] = true
+	}
+
+	var result []Contradiction
+	for _, c := range contradictions {
+		if !known[c.MemoryID] || c.Confidence < d.minConfidence {
+			continue
+		}
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+// buildDetectionPrompt creates the prompt for contradiction detection.
+func buildDetectionPrompt(text string, candidates []Candidate) string {
+	var existing strings.Builder
+	for _, c := range candidates {
+		fmt.Fprintf(&existing, "- id %d (%s): %s\n", c.ID, c.Kind, c.Text)
+	}
+
+	return fmt.Sprintf(`Decide whether a new memory contradicts any existing memories. Return a JSON object with:
+- "contradictions": array of objects with "id" (the existing memory id), "reason" (one sentence), "confidence" (0-1)
+
+Guidelines:
+- A contradiction means both statements cannot be true at the same time (e.g., "we use Postgres 14" vs "we moved to Postgres 16")
+- Statements about different subjects, or that merely add detail, are not contradictions
+- Return an empty array if nothing contradicts
+
+New memory:
+"""
+%s
+"""
+
+Existing memories:
+%s
+Respond with ONLY valid JSON, no markdown or explanation:`, text, existing.String())
+}
+
+// parseDetectionResponse parses the LLM response into contradictions.
+func parseDetectionResponse(response string) ([]Contradiction, error) {
+	// Clean up response - remove markdown code blocks if present
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+	response = strings.TrimSpace(response)
+
+	// Handle empty or null responses
+	if response == "" || response == "null" || response == "{}" {
+		return nil, nil
+	}
+
+	var result struct {
+		Contradictions []Contradiction `json:"contradictions"`
+	}
+	if err := json.Unmarshal([]byte(response), &result); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w (response: %s)", err, truncate(response, 200))
+	}
+
+	for i := range result.Contradictions {
+		if result.Contradictions[i].Confidence == 0 {
+			result.Contradictions[i].Confidence = 1.0 // Default confidence
+		}
+	}
+
+	return result.Contradictions, nil
+}
+
+// truncate shortens a string to the given length.
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Access is a batch of reads of one memory.
+type Access struct {
+	MemoryID int64
+	Count    int64
+	At       time.Time // time of the latest read
+}
+
+// RecordAccess adds a batch of reads to the memories' access counts and
+// advances their last access time. Memories deleted in the meantime are ignored.
+// Memories of other workspaces of the tenant, returned by cross-workspace
+// searches, are counted too.
+func (db *DB) RecordAccess(ctx context.Context, accesses []Access) error {
+	if len(accesses) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(accesses))
+	counts := make([]int64, len(accesses))
+	times := make([]time.Time, len(accesses))
+	for i, a := range accesses {
+		ids[i], counts[i], times[i] = a.MemoryID, a.Count, a.At
+	}
+
+	_, err := db.pool.Exec(ctx, `
+		UPDATE memories m SET
+			access_count = m.access_count + v.count,
+			last_accessed_at = GREATEST(m.last_accessed_at, v.at)
+		FROM unnest($1::bigint[], $2::bigint[], $3::timestamptz[]) AS v(id, count, at)
+		WHERE m.id = v.id AND m.tenant_id = $4
+	`, ids, counts, times, db.tenantID)
+	if err != nil {
+		return fmt.Errorf("record access: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Role is the access an API key grants on its workspaces. Each role includes
+// the ones before it: read, write, admin.
+type Role string
+
+const (
+	RoleRead  Role = "read"  // search, get and list memories
+	RoleWrite Role = "write" // also add, update, delete and import
+	RoleAdmin Role = "admin" // also backfills and workspace management
+)
+
+var roleRanks = map[Role]int{RoleRead: 1, RoleWrite: 2, RoleAdmin: 3}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	if _, ok := roleRanks[Role(s)]; !ok {
+		return "", fmt.Errorf("unknown role %q (expected read, write or admin)", s)
+	}
+	return Role(s), nil
+}
+
+// Includes reports whether r grants everything other grants.
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// AllWorkspaces in an API key's workspaces grants access to every workspace of its tenant.
+const AllWorkspaces = "*"
+
+// apiKeyPrefix starts every key, so leaked keys are easy to recognize.
+const apiKeyPrefix = "cortex_"
+
+// APIKey is a stored API key. The key itself is never stored.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Role       Role       `json:"role"`
+	Workspaces []string   `json:"workspaces"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether the key grants access to workspaceID. Only keys
+// for all workspaces allow AllWorkspaces.
+func (k *APIKey) Allows(workspaceID string) bool {
+	return slices.Contains(k.Workspaces, AllWorkspaces) || slices.Contains(k.Workspaces, workspaceID)
+}
+
+// hashAPIKey returns the stored hash of a key. Keys are random, so a plain
+// SHA-256 is enough to make the stored hashes useless to an attacker.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey creates a key for the tenant granting role on workspaces, and
+// returns the key. It cannot be retrieved later.
+func (db *DB) CreateAPIKey(ctx context.Context, name string, role Role, workspaces []string) (string, *APIKey, error) {
+	if len(workspaces) == 0 {
+		return "", nil, fmt.Errorf("at least one workspace is required")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("generate key: %w", err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+
+	k := &APIKey{
+		TenantID:   db.tenantID,
+		Name:       name,
+		Prefix:     key[:len(apiKeyPrefix)+8],
+		Role:       role,
+		Workspaces: workspaces,
+	}
+	err := db.pool.QueryRow(ctx, `
+		INSERT INTO api_keys (tenant_id, name, key_prefix, key_hash, role, workspaces)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, db.tenantID, name, k.Prefix, hashAPIKey(key), string(role), workspaces).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return "", nil, fmt.Errorf("insert api key: %w", err)
+	}
+	return key, k, nil
+}
+
+// ListAPIKeys returns the tenant's keys, newest first, including revoked ones.
+func (db *DB) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, tenant_id, name, key_prefix, role, workspaces, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, db.tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("query api keys: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (APIKey, error) {
+		return scanAPIKey(row)
+	})
+}
+
+// RevokeAPIKey revokes one of the tenant's keys. Revoking a revoked key is a no-op.
+func (db *DB) RevokeAPIKey(ctx context.Context, id int64) error {
+	result, err := db.pool.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now())
+		WHERE id = $1 AND tenant_id = $2
+	`, id, db.tenantID)
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("api key %d not found", id)
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns the unrevoked key matching key, of whichever
+// tenant it belongs to, or nil if there is none, and records that it was
+// used. The key selects the tenant, so pool must see the keys of every
+// tenant, as one opened with NewAllTenantsPool does.
+func AuthenticateAPIKey(ctx context.Context, pool *pgxpool.Pool, key string) (*APIKey, error) {
+	row := pool.QueryRow(ctx, `
+		UPDATE api_keys SET last_used_at = now()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING id, tenant_id, name, key_prefix, role, workspaces, created_at, last_used_at, revoked_at
+	`, hashAPIKey(key))
+	k, err := scanAPIKey(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("authenticate api key: %w", err)
+	}
+	return &k, nil
+}
+
+func scanAPIKey(row pgx.Row) (APIKey, error) {
+	var k APIKey
+	var role string
+	err := row.Scan(&k.ID, &k.TenantID, &k.Name, &k.Prefix, &role, &k.Workspaces, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	k.Role = Role(role)
+	return k, err
+}
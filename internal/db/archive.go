@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetArchivedMemories retrieves memories of the archive tier by ID, keyed by ID.
+// IDs that are not archived in the workspace are omitted.
+func (db *DB) GetArchivedMemories(ctx context.Context, ids []int64) (map[int64]Memory, error) {
+	memories := make(map[int64]Memory, len(ids))
+	if len(ids) == 0 {
+		return memories, nil
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories_archive m
+		WHERE m.id = ANY($1) AND m.tenant_id = $2 AND m.workspace_id = $3
+	`, ids, db.tenantID, db.workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("get archived memories: %w", err)
+	}
+	defer rows.Close()
+
+	list, err := db.scanMemories(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range list {
+		memories[m.ID] = m
+	}
+	return memories, nil
+}
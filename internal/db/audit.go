@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditEntry records a change to the memories of a workspace.
+type AuditEntry struct {
+	ID            int64          `json:"id"`
+	TenantID      string         `json:"tenant_id"`
+	WorkspaceID   string         `json:"workspace_id"`
+	At            time.Time      `json:"at"`
+	Actor         string         `json:"actor"` // key:<prefix> (<name>), local, cli or sweeper
+	Tool          string         `json:"tool"`  // tool name, cli:<command> or sweeper
+	ArgsDigest    string         `json:"args_digest,omitempty"`
+	ClientName    string         `json:"client_name,omitempty"`
+	ClientVersion string         `json:"client_version,omitempty"`
+	MemoryIDs     []int64        `json:"memory_ids"`
+	Detail        map[string]any `json:"detail,omitempty"`
+}
+
+// AuditFilter selects audit entries of the DB's workspace, or of every
+// workspace of the tenant with AllWorkspaces.
+type AuditFilter struct {
+	MemoryID      *int64     // entries that affected this memory
+	Tool          string     // entries of this tool
+	Since         *time.Time // entries at or after this time
+	AllWorkspaces bool
+	Limit         int // ListAudit only; 0 means no limit
+}
+
+// RecordAudit appends an entry to the tenant's audit log. The entry goes to
+// the DB's workspace unless it names another.
+func (db *DB) RecordAudit(ctx context.Context, e AuditEntry) error {
+	if e.WorkspaceID == "" {
+		e.WorkspaceID = db.workspaceID
+	}
+	if e.MemoryIDs == nil {
+		e.MemoryIDs = []int64{}
+	}
+	if e.Detail == nil {
+		e.Detail = map[string]any{}
+	}
+	detailJSON, err := json.Marshal(e.Detail)
+	if err != nil {
+		return fmt.Errorf("marshal detail: %w", err)
+	}
+
+	_, err = db.pool.Exec(ctx, `
+		INSERT INTO audit_log (tenant_id, workspace_id, actor, tool, args_digest, client_name, client_version, memory_ids, detail)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9)
+	`, db.tenantID, e.WorkspaceID, e.Actor, e.Tool, e.ArgsDigest, e.ClientName, e.ClientVersion, e.MemoryIDs, detailJSON)
+	if err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns the audit entries matching f, newest first.
+func (db *DB) ListAudit(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	err := db.queryAudit(ctx, f, "DESC", func(e AuditEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// EachAudit calls fn with every audit entry matching f, oldest first.
+func (db *DB) EachAudit(ctx context.Context, f AuditFilter, fn func(AuditEntry) error) error {
+	f.Limit = 0
+	return db.queryAudit(ctx, f, "ASC", fn)
+}
+
+func (db *DB) queryAudit(ctx context.Context, f AuditFilter, order string, fn func(AuditEntry) error) error {
+	conds := []string{"tenant_id = $1"}
+	args := []any{db.tenantID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if !f.AllWorkspaces {
+		conds = append(conds, "workspace_id = "+arg(db.workspaceID))
+	}
+	if f.MemoryID != nil {
+		conds = append(conds, arg(*f.MemoryID)+" = ANY(memory_ids)")
+	}
+	if f.Tool != "" {
+		conds = append(conds, "tool = "+arg(f.Tool))
+	}
+	if f.Since != nil {
+		conds = append(conds, "at >= "+arg(*f.Since))
+	}
+	limit := ""
+	if f.Limit > 0 {
+		limit = "LIMIT " + arg(f.Limit)
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, tenant_id, workspace_id, at, actor, tool, COALESCE(args_digest, ''),
+			COALESCE(client_name, ''), COALESCE(client_version, ''), memory_ids, detail
+		FROM audit_log
+		WHERE `+strings.Join(conds, " AND ")+`
+		ORDER BY id `+order+`
+		`+limit, args...)
+	if err != nil {
+		return fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AuditEntry
+		var detailJSON []byte
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.WorkspaceID, &e.At, &e.Actor, &e.Tool, &e.ArgsDigest,
+			&e.ClientName, &e.ClientVersion, &e.MemoryIDs, &detailJSON); err != nil {
+			return fmt.Errorf("scan audit entry: %w", err)
+		}
+		if err := json.Unmarshal(detailJSON, &e.Detail); err != nil {
+			return fmt.Errorf("unmarshal audit detail: %w", err)
+		}
+		if len(e.Detail) == 0 {
+			e.Detail = nil
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query audit log: %w", err)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ type DB struct {
 	pool        *pgxpool.Pool
 	tenantID    string
 	workspaceID string
+	encryption  *EncryptionConfig // nil: memories are stored as plaintext
 }
 
 // New creates a new DB instance with the given connection URL and tenant ID.
@@ -27,11 +28,40 @@ func New(ctx context.Context, databaseURL, tenantID string) (*DB, error) {
 }
 
 // NewWithWorkspace creates a new DB instance with the given connection URL, tenant ID, and workspace ID.
+// Row-level security limits its connections to the rows of tenantID.
 func NewWithWorkspace(ctx context.Context, databaseURL, tenantID, workspaceID string) (*DB, error) {
+	pool, err := openPool(ctx, databaseURL, settingTenantID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		pool:        pool,
+		tenantID:    tenantID,
+		workspaceID: workspaceID,
+	}
+
+	return db, nil
+}
+
+// NewAllTenantsPool opens a connection pool whose sessions see the rows of
+// every tenant, for maintenance that spans tenants such as the global sweep.
+// Queries on it must filter by tenant themselves.
+func NewAllTenantsPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
+	return openPool(ctx, databaseURL, settingAllTenants, "on")
+}
+
+// openPool opens a connection pool that sets a row-level security setting
+// on each new connection.
+func openPool(ctx context.Context, databaseURL, setting, value string) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("parse database URL: %w", err)
 	}
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, `SELECT set_config($1, $2, false)`, setting, value)
+		return err
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
@@ -43,14 +73,35 @@ func NewWithWorkspace(ctx context.Context, databaseURL, tenantID, workspaceID st
 		pool.Close()
 		return nil, fmt.Errorf("ping database: %w", err)
 	}
+	return pool, nil
+}
 
-	db := &DB{
-		pool:        pool,
-		tenantID:    tenantID,
+// WithWorkspace returns a DB for another workspace of the same tenant,
+// sharing the connection pool. Closing either closes both.
+func (db *DB) WithWorkspace(workspaceID string) *DB {
+	return &DB{
+		pool:        db.pool,
+		tenantID:    db.tenantID,
 		workspaceID: workspaceID,
+		encryption:  db.encryption,
 	}
+}
 
-	return db, nil
+// ForTenant opens a DB for another tenant of the same database, on the same
+// workspace and with the same encryption settings. Row-level security needs
+// the tenant on each connection, so it gets a connection pool of its own,
+// which must be closed separately.
+func (db *DB) ForTenant(ctx context.Context, tenantID string) (*DB, error) {
+	pool, err := openPool(ctx, db.pool.Config().ConnString(), settingTenantID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{
+		pool:        pool,
+		tenantID:    tenantID,
+		workspaceID: db.workspaceID,
+		encryption:  db.encryption,
+	}, nil
 }
 
 // Close closes the database connection pool.
@@ -90,14 +141,21 @@ func (db *DB) Migrate(ctx context.Context) error {
 	}
 	sort.Strings(sqlFiles)
 
-	// Execute each migration
+	// Execute each migration; data changes apply to every tenant
 	for _, filename := range sqlFiles {
 		content, err := fs.ReadFile(migrations.FS, filename)
 		if err != nil {
 			return fmt.Errorf("read migration %s: %w", filename, err)
 		}
 
-		if _, err := db.pool.Exec(ctx, string(content)); err != nil {
+		err = db.WithTx(ctx, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, `SELECT set_config($1, 'on', true)`, settingAllTenants); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, string(content))
+			return err
+		})
+		if err != nil {
 			return fmt.Errorf("execute migration %s: %w", filename, err)
 		}
 	}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Kinds used for ingested documents. The parent holds the full text and is
+// not embedded; its chunks are embedded and searched individually.
+const (
+	KindDocument = "document"
+	KindChunk    = "chunk"
+)
+
+// ListChunks returns the chunks of a document in order.
+func (db *DB) ListChunks(ctx context.Context, parentID int64) ([]Memory, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories m
+		WHERE m.parent_id = $1 AND m.tenant_id = $2 AND m.workspace_id = $3
+		ORDER BY m.chunk_index
+	`, parentID, db.tenantID, db.workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("list chunks: %w", err)
+	}
+	defer rows.Close()
+
+	return db.scanMemories(rows)
+}
+
+// ReplaceChunks replaces the chunks of a document with new ones in a single
+// transaction, so a failure leaves the old chunks in place. It returns the
+// IDs of the new chunks in order.
+func (db *DB) ReplaceChunks(ctx context.Context, parentID int64, chunks []AddMemoryParams) ([]int64, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM memories WHERE parent_id = $1 AND tenant_id = $2 AND workspace_id = $3
+	`, parentID, db.tenantID, db.workspaceID); err != nil {
+		return nil, fmt.Errorf("delete chunks: %w", err)
+	}
+
+	ids := make([]int64, 0, len(chunks))
+	for i, params := range chunks {
+		params.ParentID = &parentID
+		id, err := db.insertMemory(ctx, tx, params)
+		if err != nil {
+			return nil, fmt.Errorf("add chunk %d: %w", i, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit chunks: %w", err)
+	}
+	return ids, nil
+}
+
+// GetMemories retrieves several memories by ID. Missing IDs are omitted from the result.
+func (db *DB) GetMemories(ctx context.Context, ids []int64) (map[int64]Memory, error) {
+	memories := make(map[int64]Memory, len(ids))
+	if len(ids) == 0 {
+		return memories, nil
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories m
+		WHERE m.id = ANY($1) AND m.tenant_id = $2 AND m.workspace_id = $3
+	`, ids, db.tenantID, db.workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("get memories: %w", err)
+	}
+	defer rows.Close()
+
+	list, err := db.scanMemories(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range list {
+		memories[m.ID] = m
+	}
+	return memories, nil
+}
+
+// FindDocumentBySource returns the document with the given source, or nil if none exists.
+func (db *DB) FindDocumentBySource(ctx context.Context, source string) (*Memory, error) {
+	var m Memory
+
+	row := db.pool.QueryRow(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories m
+		WHERE m.source = $1 AND m.kind = $2 AND m.parent_id IS NULL
+		  AND m.tenant_id = $3 AND m.workspace_id = $4
+		ORDER BY m.id DESC
+		LIMIT 1
+	`, source, KindDocument, db.tenantID, db.workspaceID)
+
+	err := db.scanMemory(row, &m)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find document: %w", err)
+	}
+
+	return &m, nil
+}
+
+// DocumentRef identifies a document by its source and content hash.
+type DocumentRef struct {
+	ID          int64
+	Source      string
+	ContentHash string // meta.content_hash, empty if not recorded
+}
+
+// ListDocumentsBySourcePrefix returns the documents whose source starts with prefix.
+func (db *DB) ListDocumentsBySourcePrefix(ctx context.Context, prefix string) ([]DocumentRef, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, source, meta
+		FROM memories
+		WHERE tenant_id = $1 AND workspace_id = $2
+		  AND kind = $3 AND parent_id IS NULL AND starts_with(source, $4)
+		ORDER BY source
+	`, db.tenantID, db.workspaceID, KindDocument, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list documents: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []DocumentRef
+	for rows.Next() {
+		var r DocumentRef
+		var metaJSON []byte
+		if err := rows.Scan(&r.ID, &r.Source, &metaJSON); err != nil {
+			return nil, fmt.Errorf("scan document: %w", err)
+		}
+		var meta map[string]any
+		if len(metaJSON) > 0 {
+			if err := json.Unmarshal(metaJSON, &meta); err != nil {
+				return nil, fmt.Errorf("unmarshal meta: %w", err)
+			}
+		}
+		// The content hash is sealed with the rest of an encrypted document's meta
+		if _, meta, err = db.OpenMemory("", meta); err != nil {
+			return nil, fmt.Errorf("document %d: %w", r.ID, err)
+		}
+		r.ContentHash, _ = meta["content_hash"].(string)
+		refs = append(refs, r)
+	}
+
+	return refs, rows.Err()
+}
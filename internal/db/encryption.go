@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/johnswift/cortex/internal/encryption"
+)
+
+// EncryptionConfig selects the workspaces whose memory text and meta are
+// sealed at rest with keys from a keyfile.
+type EncryptionConfig struct {
+	Keyring    *encryption.Keyring
+	Workspaces []string // workspaces to encrypt; "*" encrypts every workspace
+	BlindIndex bool     // index sealed text so lexical search can match exact words
+}
+
+// metaSealed is the meta key holding the sealed part of an encrypted memory's meta.
+const metaSealed = "_encrypted"
+
+// clearMetaKeys stay readable in the meta of encrypted memories, since queries
+// filter on them. no_llm holds only the kinds of findings, not the text.
+var clearMetaKeys = []string{"no_llm"}
+
+// SetEncryption seals the text and meta of memories written to the configured
+// workspaces, and opens sealed memories read from any workspace. DBs returned
+// by WithWorkspace share the setting.
+func (db *DB) SetEncryption(cfg EncryptionConfig) {
+	db.encryption = &cfg
+}
+
+// Encrypts reports whether memories written to workspaceID are sealed.
+func (db *DB) Encrypts(workspaceID string) bool {
+	if db.encryption == nil {
+		return false
+	}
+	return slices.Contains(db.encryption.Workspaces, "*") || slices.Contains(db.encryption.Workspaces, workspaceID)
+}
+
+// keyring returns the keyring, or an error naming what to configure.
+func (db *DB) keyring() (*encryption.Keyring, error) {
+	if db.encryption == nil || db.encryption.Keyring == nil {
+		return nil, fmt.Errorf("memory is encrypted: set ENCRYPTION_KEYFILE to read it")
+	}
+	return db.encryption.Keyring, nil
+}
+
+// sealContext binds a sealed value to the tenant and column it is stored in.
+func (db *DB) sealContext(column string) string {
+	return db.tenantID + "/" + column
+}
+
+// sealText returns the text to store for workspaceID and its blind index,
+// which is nil for unsealed text.
+func (db *DB) sealText(workspaceID, text string) (string, []string, error) {
+	if !db.Encrypts(workspaceID) {
+		return text, nil, nil
+	}
+	keyring := db.encryption.Keyring
+	sealed, err := keyring.Seal([]byte(text), db.sealContext("text"))
+	if err != nil {
+		return "", nil, fmt.Errorf("seal text: %w", err)
+	}
+	index := []string{}
+	if db.encryption.BlindIndex {
+		index = keyring.BlindIndex(text)
+	}
+	return sealed, index, nil
+}
+
+// sealMeta returns the meta to store for workspaceID. Keys other than
+// clearMetaKeys are sealed together under metaSealed.
+func (db *DB) sealMeta(workspaceID string, meta map[string]any) (map[string]any, error) {
+	if !db.Encrypts(workspaceID) {
+		return meta, nil
+	}
+	stored := map[string]any{}
+	private := map[string]any{}
+	for k, v := range meta {
+		if slices.Contains(clearMetaKeys, k) {
+			stored[k] = v
+		} else {
+			private[k] = v
+		}
+	}
+	if len(private) == 0 {
+		return stored, nil
+	}
+	privateJSON, err := json.Marshal(private)
+	if err != nil {
+		return nil, fmt.Errorf("marshal meta: %w", err)
+	}
+	sealed, err := db.encryption.Keyring.Seal(privateJSON, db.sealContext("meta"))
+	if err != nil {
+		return nil, fmt.Errorf("seal meta: %w", err)
+	}
+	stored[metaSealed] = sealed
+	return stored, nil
+}
+
+// SealMemory returns the text, meta and blind index to store for a memory of
+// workspaceID, for writers that bypass AddMemory such as imports.
+func (db *DB) SealMemory(workspaceID, text string, meta map[string]any) (string, map[string]any, []string, error) {
+	text, index, err := db.sealText(workspaceID, text)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	meta, err = db.sealMeta(workspaceID, meta)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return text, meta, index, nil
+}
+
+// OpenMemory returns the plaintext of stored text and meta. Values that are
+// not sealed are returned as they are.
+func (db *DB) OpenMemory(text string, meta map[string]any) (string, map[string]any, error) {
+	if encryption.IsSealed(text) {
+		keyring, err := db.keyring()
+		if err != nil {
+			return "", nil, err
+		}
+		plaintext, err := keyring.Open(text, db.sealContext("text"))
+		if err != nil {
+			return "", nil, fmt.Errorf("open text: %w", err)
+		}
+		text = string(plaintext)
+	}
+
+	sealed, ok := meta[metaSealed].(string)
+	if !ok {
+		return text, meta, nil
+	}
+	keyring, err := db.keyring()
+	if err != nil {
+		return "", nil, err
+	}
+	plaintext, err := keyring.Open(sealed, db.sealContext("meta"))
+	if err != nil {
+		return "", nil, fmt.Errorf("open meta: %w", err)
+	}
+	opened := map[string]any{}
+	if err := json.Unmarshal(plaintext, &opened); err != nil {
+		return "", nil, fmt.Errorf("unmarshal meta: %w", err)
+	}
+	for k, v := range meta {
+		if k != metaSealed {
+			opened[k] = v
+		}
+	}
+	return text, opened, nil
+}
+
+// OpenText returns the plaintext of stored text.
+func (db *DB) OpenText(text string) (string, error) {
+	text, _, err := db.OpenMemory(text, nil)
+	return text, err
+}
+
+// openMemory replaces a scanned memory's stored text and meta with their plaintext.
+func (db *DB) openMemory(m *Memory) error {
+	text, meta, err := db.OpenMemory(m.Text, m.Meta)
+	if err != nil {
+		return fmt.Errorf("memory %d: %w", m.ID, err)
+	}
+	m.Text, m.Meta = text, meta
+	return nil
+}
+
+// lexicalIndex returns the blind index hashes of a query and its number of
+// tokens; none without a keyring.
+func (db *DB) lexicalIndex(query string) ([]string, int) {
+	if db.encryption == nil || db.encryption.Keyring == nil || !db.encryption.BlindIndex {
+		return []string{}, 0
+	}
+	return db.encryption.Keyring.QueryIndex(query)
+}
+
+// ReencryptStats reports what Reencrypt changed.
+type ReencryptStats struct {
+	Scanned   int64 `json:"scanned"`
+	Sealed    int64 `json:"sealed"`    // plaintext memories sealed
+	Resealed  int64 `json:"resealed"`  // sealed memories sealed again, such as with a new active key
+	Unsealed  int64 `json:"unsealed"`  // memories of workspaces no longer encrypted, stored as plaintext
+	Unchanged int64 `json:"unchanged"` // memories already stored as configured
+}
+
+// Reencrypt brings the stored form of the workspace's memories in line with
+// the encryption settings: plaintext memories of an encrypted workspace are
+// sealed, memories sealed with a key other than the active one or with a stale
+// blind index are sealed again, and sealed memories of a workspace no longer
+// encrypted are stored as plaintext. Archived memories are included. With
+// dryRun, it only counts what would change. Timestamps are not changed.
+func (db *DB) Reencrypt(ctx context.Context, dryRun bool) (*ReencryptStats, error) {
+	stats := &ReencryptStats{}
+	for _, table := range []string{"memories", "memories_archive"} {
+		if err := db.reencryptTable(ctx, table, dryRun, stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+func (db *DB) reencryptTable(ctx context.Context, table string, dryRun bool, stats *ReencryptStats) error {
+	const batchSize = 500
+	encrypt := db.Encrypts(db.workspaceID)
+
+	var afterID int64
+	for {
+		rows, err := db.pool.Query(ctx, `
+			SELECT id, text, meta, blind_index FROM `+table+`
+			WHERE tenant_id = $1 AND workspace_id = $2 AND id > $3
+			ORDER BY id
+			LIMIT $4
+		`, db.tenantID, db.workspaceID, afterID, batchSize)
+		if err != nil {
+			return fmt.Errorf("list %s: %w", table, err)
+		}
+
+		type row struct {
+			id    int64
+			text  string
+			meta  map[string]any
+			index []string
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			var metaJSON []byte
+			if err := rows.Scan(&r.id, &r.text, &metaJSON, &r.index); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan %s: %w", table, err)
+			}
+			if len(metaJSON) > 0 {
+				if err := json.Unmarshal(metaJSON, &r.meta); err != nil {
+					rows.Close()
+					return fmt.Errorf("unmarshal meta of memory %d: %w", r.id, err)
+				}
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("list %s: %w", table, err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, r := range batch {
+			afterID = r.id
+			stats.Scanned++
+
+			text, meta, err := db.OpenMemory(r.text, r.meta)
+			if err != nil {
+				return fmt.Errorf("memory %d: %w", r.id, err)
+			}
+			wasSealed := r.index != nil || encryption.IsSealed(r.text) || r.meta[metaSealed] != nil
+
+			if !encrypt {
+				if !wasSealed {
+					stats.Unchanged++
+					continue
+				}
+				stats.Unsealed++
+			} else if !wasSealed {
+				stats.Sealed++
+			} else if db.isCurrent(r.text, r.meta, r.index, text) {
+				stats.Unchanged++
+				continue
+			} else {
+				stats.Resealed++
+			}
+			if dryRun {
+				continue
+			}
+
+			storedText, storedMeta, index, err := db.SealMemory(db.workspaceID, text, meta)
+			if err != nil {
+				return fmt.Errorf("memory %d: %w", r.id, err)
+			}
+			metaJSON, err := json.Marshal(storedMeta)
+			if err != nil {
+				return fmt.Errorf("marshal meta: %w", err)
+			}
+			if _, err := db.pool.Exec(ctx, `
+				UPDATE `+table+` SET text = $2, meta = $3, blind_index = $4
+				WHERE id = $1 AND tenant_id = $5
+			`, r.id, storedText, metaJSON, index, db.tenantID); err != nil {
+				return fmt.Errorf("update memory %d: %w", r.id, err)
+			}
+		}
+	}
+}
+
+// isCurrent reports whether a sealed memory is sealed with the active key,
+// keeps no private meta in the clear, and has the blind index its text needs.
+func (db *DB) isCurrent(text string, meta map[string]any, index []string, plaintext string) bool {
+	keyring := db.encryption.Keyring
+	active := keyring.ActiveKey()
+	if !encryption.IsSealed(text) || encryption.KeyID(text) != active {
+		return false
+	}
+	for k, v := range meta {
+		switch {
+		case k == metaSealed:
+			sealed, ok := v.(string)
+			if !ok || encryption.KeyID(sealed) != active {
+				return false
+			}
+		case !slices.Contains(clearMetaKeys, k):
+			return false
+		}
+	}
+	want := []string{}
+	if db.encryption.BlindIndex {
+		want = keyring.BlindIndex(plaintext)
+	}
+	return slices.Equal(index, want)
+}
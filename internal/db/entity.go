@@ -305,9 +305,7 @@ func (db *DB) GetRelatedMemories(ctx context.Context, memoryID int64, limit int)
 	}
 
 	rows, err := db.pool.Query(ctx, `
-		SELECT DISTINCT ON (m.id)
-			m.id, m.tenant_id, m.workspace_id, m.kind, m.text, m.source,
-			m.created_at, m.updated_at, m.tags, m.importance, m.ttl_days, m.meta,
+		SELECT DISTINCT ON (m.id) `+memoryColumns+`,
 			COUNT(DISTINCT me2.entity_id)::real / GREATEST(COUNT(DISTINCT me1.entity_id)::real, 1) AS score
 		FROM memories m
 		JOIN memory_entities me2 ON m.id = me2.memory_id
@@ -322,7 +320,50 @@ func (db *DB) GetRelatedMemories(ctx context.Context, memoryID int64, limit int)
 	}
 	defer rows.Close()
 
-	return scanMemoriesWithScore(rows)
+	return db.scanMemoriesWithScore(rows, false)
+}
+
+// CountMemoriesWithoutEntities counts memories that have no linked entities.
+// Documents split into chunks are not counted; their chunks are. Memories
+// marked no_llm by redaction are never sent for extraction, so neither are they.
+func (db *DB) CountMemoriesWithoutEntities(ctx context.Context) (int64, error) {
+	var count int64
+	err := db.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM memories m
+		WHERE m.tenant_id = $1 AND m.workspace_id = $2
+		  AND NOT EXISTS (SELECT 1 FROM memory_entities me WHERE me.memory_id = m.id)
+		  AND NOT EXISTS (SELECT 1 FROM memories c WHERE c.parent_id = m.id)
+		  AND NOT COALESCE(m.meta ? 'no_llm', false)
+	`, db.tenantID, db.workspaceID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count memories without entities: %w", err)
+	}
+	return count, nil
+}
+
+// ListMemoriesWithoutEntities returns memories that have no linked entities,
+// in ID order, starting after afterID.
+func (db *DB) ListMemoriesWithoutEntities(ctx context.Context, afterID int64, limit int) ([]Memory, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories m
+		WHERE m.tenant_id = $1 AND m.workspace_id = $2 AND m.id > $3
+		  AND NOT EXISTS (SELECT 1 FROM memory_entities me WHERE me.memory_id = m.id)
+		  AND NOT EXISTS (SELECT 1 FROM memories c WHERE c.parent_id = m.id)
+		  AND NOT COALESCE(m.meta ? 'no_llm', false)
+		ORDER BY m.id
+		LIMIT $4
+	`, db.tenantID, db.workspaceID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list memories without entities: %w", err)
+	}
+	defer rows.Close()
+
+	return db.scanMemories(rows)
 }
 
 func scanEntities(rows pgx.Rows) ([]Entity, error) {
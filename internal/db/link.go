@@ -0,0 +1,275 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LinkType represents the kind of a directed memory-to-memory link.
+type LinkType string
+
+const (
+	LinkTypeContradicts LinkType = "contradicts"  // set by conflict detection
+	LinkTypeSupersedes  LinkType = "supersedes"   // source replaces the outdated target
+	LinkTypeRelatesTo   LinkType = "relates_to"   // general association
+	LinkTypeDerivedFrom LinkType = "derived_from" // source was derived from the target
+	LinkTypeBlocks      LinkType = "blocks"       // source must be resolved before the target
+)
+
+// LinkTypes lists the link types that can be created explicitly.
+var LinkTypes = []LinkType{
+	LinkTypeSupersedes, LinkTypeRelatesTo, LinkTypeDerivedFrom, LinkTypeBlocks, LinkTypeContradicts,
+}
+
+// ParseLinkType validates a link type name.
+func ParseLinkType(s string) (LinkType, error) {
+	for _, t := range LinkTypes {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown link type %q (expected one of %v)", s, LinkTypes)
+}
+
+// MemoryLink represents a directed link between two memories.
+type MemoryLink struct {
+	ID          int64          `json:"id"`
+	TenantID    string         `json:"tenant_id"`
+	WorkspaceID string         `json:"workspace_id"`
+	SourceID    int64          `json:"source_id"`
+	TargetID    int64          `json:"target_id"`
+	LinkType    LinkType       `json:"link_type"`
+	Meta        map[string]any `json:"meta,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Conflict is a contradicts link together with the text of both memories.
+type Conflict struct {
+	MemoryLink
+	SourceText string `json:"source_text"`
+	TargetText string `json:"target_text"`
+}
+
+// AddMemoryLink creates a directed link between two memories of the workspace.
+// Adding a link that already exists replaces its metadata.
+func (db *DB) AddMemoryLink(ctx context.Context, sourceID, targetID int64, linkType LinkType, meta map[string]any) error {
+	if meta == nil {
+		meta = map[string]any{}
+	}
+
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("marshal meta: %w", err)
+	}
+
+	if sourceID == targetID {
+		return fmt.Errorf("cannot link a memory to itself")
+	}
+
+	// Both memories must belong to this workspace
+	result, err := db.pool.Exec(ctx, `
+		INSERT INTO memory_links (tenant_id, workspace_id, source_id, target_id, link_type, meta)
+		SELECT $1::text, $2::text, $3::bigint, $4::bigint, $5::text, $6::jsonb
+		WHERE (SELECT COUNT(*) FROM memories
+		       WHERE id IN ($3, $4) AND tenant_id = $1 AND workspace_id = $2) = 2
+		ON CONFLICT (source_id, target_id, link_type) DO UPDATE SET
+			meta = EXCLUDED.meta
+	`, db.tenantID, db.workspaceID, sourceID, targetID, linkType, metaJSON)
+
+	if err != nil {
+		return fmt.Errorf("add memory link: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("memory not found")
+	}
+
+	return nil
+}
+
+// DeleteMemoryLink removes the link of the given type from source to target,
+// or every link from source to target if linkType is empty.
+func (db *DB) DeleteMemoryLink(ctx context.Context, sourceID, targetID int64, linkType LinkType) (int64, error) {
+	result, err := db.pool.Exec(ctx, `
+		DELETE FROM memory_links
+		WHERE source_id = $1 AND target_id = $2 AND ($3 = '' OR link_type = $3)
+			AND tenant_id = $4 AND workspace_id = $5
+	`, sourceID, targetID, string(linkType), db.tenantID, db.workspaceID)
+	if err != nil {
+		return 0, fmt.Errorf("delete memory link: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteMemoryLinks removes all links of the given type touching a memory, in either direction.
+func (db *DB) DeleteMemoryLinks(ctx context.Context, memoryID int64, linkType LinkType) (int64, error) {
+	result, err := db.pool.Exec(ctx, `
+		DELETE FROM memory_links
+		WHERE (source_id = $1 OR target_id = $1) AND link_type = $2
+			AND tenant_id = $3 AND workspace_id = $4
+	`, memoryID, linkType, db.tenantID, db.workspaceID)
+	if err != nil {
+		return 0, fmt.Errorf("delete memory links: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// ListConflicts returns unresolved contradicts links, newest first.
+// A conflict is resolved by deleting or rewriting either memory of the pair.
+func (db *DB) ListConflicts(ctx context.Context, limit int) ([]Conflict, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT l.id, l.tenant_id, l.workspace_id, l.source_id, l.target_id, l.link_type, l.meta, l.created_at,
+			s.text, t.text
+		FROM memory_links l
+		JOIN memories s ON s.id = l.source_id
+		JOIN memories t ON t.id = l.target_id
+		WHERE l.tenant_id = $1 AND l.workspace_id = $2 AND l.link_type = $3
+		ORDER BY l.created_at DESC
+		LIMIT $4
+	`, db.tenantID, db.workspaceID, LinkTypeContradicts, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	var conflicts []Conflict
+	for rows.Next() {
+		var c Conflict
+		var metaJSON []byte
+		if err := rows.Scan(
+			&c.ID, &c.TenantID, &c.WorkspaceID, &c.SourceID, &c.TargetID, &c.LinkType, &metaJSON, &c.CreatedAt,
+			&c.SourceText, &c.TargetText,
+		); err != nil {
+			return nil, fmt.Errorf("scan conflict: %w", err)
+		}
+		if c.SourceText, err = db.OpenText(c.SourceText); err != nil {
+			return nil, fmt.Errorf("memory %d: %w", c.SourceID, err)
+		}
+		if c.TargetText, err = db.OpenText(c.TargetText); err != nil {
+			return nil, fmt.Errorf("memory %d: %w", c.TargetID, err)
+		}
+		if len(metaJSON) > 0 {
+			if err := json.Unmarshal(metaJSON, &c.Meta); err != nil {
+				return nil, fmt.Errorf("unmarshal meta: %w", err)
+			}
+		}
+		conflicts = append(conflicts, c)
+	}
+
+	return conflicts, rows.Err()
+}
+
+// LinkedMemory is a memory reached by following links from another memory.
+type LinkedMemory struct {
+	Memory
+	LinkType  LinkType `json:"link_type"` // type of the last link followed
+	Direction string   `json:"direction"` // "outgoing" if the last link points to this memory, else "incoming"
+	Depth     int      `json:"depth"`     // number of links followed
+}
+
+// TraverseLinks returns the memories reachable from memoryID by following up to
+// depth links in either direction, nearest first. Each memory is reported once,
+// at its shortest distance. If types is non-empty, only links of those types are followed.
+func (db *DB) TraverseLinks(ctx context.Context, memoryID int64, types []LinkType, depth, limit int) ([]LinkedMemory, error) {
+	if depth <= 0 {
+		depth = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	typeNames := make([]string, len(types))
+	for i, t := range types {
+		typeNames[i] = string(t)
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		WITH RECURSIVE walk(id, link_type, direction, depth, path) AS (
+			SELECT CASE WHEN l.source_id = $1 THEN l.target_id ELSE l.source_id END,
+				l.link_type,
+				CASE WHEN l.source_id = $1 THEN 'outgoing' ELSE 'incoming' END,
+				1,
+				ARRAY[$1::bigint]
+			FROM memory_links l
+			WHERE (l.source_id = $1 OR l.target_id = $1)
+				AND l.tenant_id = $2 AND l.workspace_id = $3
+				AND (cardinality($4::text[]) = 0 OR l.link_type = ANY($4))
+			UNION ALL
+			SELECT CASE WHEN l.source_id = w.id THEN l.target_id ELSE l.source_id END,
+				l.link_type,
+				CASE WHEN l.source_id = w.id THEN 'outgoing' ELSE 'incoming' END,
+				w.depth + 1,
+				w.path || w.id
+			FROM walk w
+			JOIN memory_links l ON l.source_id = w.id OR l.target_id = w.id
+			WHERE w.depth < $5
+				AND NOT (CASE WHEN l.source_id = w.id THEN l.target_id ELSE l.source_id END = ANY(w.path || w.id))
+				AND l.tenant_id = $2 AND l.workspace_id = $3
+				AND (cardinality($4::text[]) = 0 OR l.link_type = ANY($4))
+		),
+		nearest AS (
+			SELECT DISTINCT ON (id) id, link_type, direction, depth
+			FROM walk
+			WHERE id != $1
+			ORDER BY id, depth
+		)
+		SELECT `+memoryColumns+`, n.link_type, n.direction, n.depth
+		FROM nearest n
+		JOIN memories m ON m.id = n.id
+		WHERE m.tenant_id = $2 AND m.workspace_id = $3
+		ORDER BY n.depth, m.importance DESC, m.id
+		LIMIT $6
+	`, memoryID, db.tenantID, db.workspaceID, typeNames, depth, limit)
+	if err != nil {
+		return nil, fmt.Errorf("traverse links: %w", err)
+	}
+	defer rows.Close()
+
+	var linked []LinkedMemory
+	for rows.Next() {
+		var l LinkedMemory
+		if err := db.scanMemory(rows, &l.Memory, &l.LinkType, &l.Direction, &l.Depth); err != nil {
+			return nil, fmt.Errorf("scan linked memory: %w", err)
+		}
+		linked = append(linked, l)
+	}
+
+	return linked, rows.Err()
+}
+
+// SupersededBy returns, for each of the given memories that has been
+// superseded, the IDs of the memories superseding it, newest first.
+func (db *DB) SupersededBy(ctx context.Context, ids []int64) (map[int64][]int64, error) {
+	superseded := make(map[int64][]int64)
+	if len(ids) == 0 {
+		return superseded, nil
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT target_id, source_id
+		FROM memory_links
+		WHERE target_id = ANY($1) AND link_type = $2
+			AND tenant_id = $3 AND workspace_id = $4
+		ORDER BY source_id DESC
+	`, ids, LinkTypeSupersedes, db.tenantID, db.workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("get superseding memories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var target, source int64
+		if err := rows.Scan(&target, &source); err != nil {
+			return nil, fmt.Errorf("scan link: %w", err)
+		}
+		superseded[target] = append(superseded[target], source)
+	}
+
+	return superseded, rows.Err()
+}
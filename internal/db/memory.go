@@ -23,13 +23,62 @@ type Memory struct {
 	Tags        []string       `json:"tags"`
 	Importance  float32        `json:"importance"`
 	TTLDays     *int           `json:"ttl_days,omitempty"`
+	TTLFrom     string         `json:"ttl_from"` // what ttl_days counts from; see TTLFromCreated
 	Meta        map[string]any `json:"meta,omitempty"`
+	ParentID    *int64         `json:"parent_id,omitempty"`   // document this memory is a chunk of
+	ChunkIndex  *int           `json:"chunk_index,omitempty"` // position within the parent document
+
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"` // last returned by search or get
+	AccessCount    int64      `json:"access_count"`
+}
+
+// What a memory's ttl_days counts from. Memories that are refreshed by
+// updates or reads can count from their last refresh instead of creation.
+const (
+	TTLFromCreated  = "created"
+	TTLFromUpdated  = "updated"  // the last update
+	TTLFromAccessed = "accessed" // the last update or read, whichever is later
+)
+
+// ValidTTLFrom reports whether s is a TTL basis.
+func ValidTTLFrom(s string) bool {
+	switch s {
+	case TTLFromCreated, TTLFromUpdated, TTLFromAccessed:
+		return true
+	}
+	return false
+}
+
+// memoryColumns is the column list scanned by scanMemory, for queries aliasing memories as m.
+const memoryColumns = `m.id, m.tenant_id, m.workspace_id, m.kind, m.text, m.source,
+	m.created_at, m.updated_at, m.tags, m.importance, m.ttl_days, m.ttl_from, m.meta,
+	m.parent_id, m.chunk_index, m.last_accessed_at, m.access_count`
+
+// scanMemory scans a row selected with memoryColumns, followed by any extra
+// columns, and opens its text and meta if they are sealed.
+func (db *DB) scanMemory(row pgx.Row, m *Memory, extra ...any) error {
+	var metaJSON []byte
+	dest := []any{
+		&m.ID, &m.TenantID, &m.WorkspaceID, &m.Kind, &m.Text, &m.Source,
+		&m.CreatedAt, &m.UpdatedAt, &m.Tags, &m.Importance, &m.TTLDays, &m.TTLFrom, &metaJSON,
+		&m.ParentID, &m.ChunkIndex, &m.LastAccessedAt, &m.AccessCount,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	if len(metaJSON) > 0 {
+		if err := json.Unmarshal(metaJSON, &m.Meta); err != nil {
+			return fmt.Errorf("unmarshal meta: %w", err)
+		}
+	}
+	return db.openMemory(m)
 }
 
 // MemoryWithScore includes similarity score for search results.
 type MemoryWithScore struct {
 	Memory
-	Score float32 `json:"score"`
+	Score    float32 `json:"score"`
+	Archived bool    `json:"archived,omitempty"` // found in the archive tier
 }
 
 // AddMemoryParams contains parameters for adding a new memory.
@@ -40,29 +89,50 @@ type AddMemoryParams struct {
 	Tags       []string
 	Importance float32
 	TTLDays    *int
+	TTLFrom    string // empty means TTLFromCreated
 	Meta       map[string]any
+	ParentID   *int64 // set for document chunks
+	ChunkIndex *int
 }
 
 // AddMemory inserts a new memory and returns its ID.
 func (db *DB) AddMemory(ctx context.Context, params AddMemoryParams) (int64, error) {
+	return db.insertMemory(ctx, db.pool, params)
+}
+
+// rowQuerier is a pool or a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insertMemory inserts a new memory through q and returns its ID.
+func (db *DB) insertMemory(ctx context.Context, q rowQuerier, params AddMemoryParams) (int64, error) {
 	if params.Tags == nil {
 		params.Tags = []string{}
 	}
 	if params.Meta == nil {
 		params.Meta = map[string]any{}
 	}
+	if params.TTLFrom == "" {
+		params.TTLFrom = TTLFromCreated
+	}
 
-	metaJSON, err := json.Marshal(params.Meta)
+	text, meta, index, err := db.SealMemory(db.workspaceID, params.Text, params.Meta)
+	if err != nil {
+		return 0, err
+	}
+	metaJSON, err := json.Marshal(meta)
 	if err != nil {
 		return 0, fmt.Errorf("marshal meta: %w", err)
 	}
 
 	var id int64
-	err = db.pool.QueryRow(ctx, `
-		INSERT INTO memories (tenant_id, workspace_id, kind, text, source, tags, importance, ttl_days, meta)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	err = q.QueryRow(ctx, `
+		INSERT INTO memories (tenant_id, workspace_id, kind, text, source, tags, importance, ttl_days, ttl_from, meta, parent_id, chunk_index, blind_index)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id
-	`, db.tenantID, db.workspaceID, params.Kind, params.Text, params.Source, params.Tags, params.Importance, params.TTLDays, metaJSON).Scan(&id)
+	`, db.tenantID, db.workspaceID, params.Kind, text, params.Source, params.Tags, params.Importance, params.TTLDays, params.TTLFrom,
+		metaJSON, params.ParentID, params.ChunkIndex, index).Scan(&id)
 
 	if err != nil {
 		return 0, fmt.Errorf("insert memory: %w", err)
@@ -145,20 +215,56 @@ func (db *DB) DeleteEmbedding(ctx context.Context, memoryID int64, model string)
 	return nil
 }
 
+// DeleteDerivedData removes every embedding of a memory and its entity links,
+// for memories whose text may no longer be sent to the LLM that derived them.
+func (db *DB) DeleteDerivedData(ctx context.Context, memoryID int64) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM memory_embeddings WHERE memory_id = $1`, memoryID); err != nil {
+		return fmt.Errorf("delete embeddings: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM memory_entities WHERE memory_id = $1`, memoryID); err != nil {
+		return fmt.Errorf("delete entity links: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// ListMemories returns the workspace's memories in ID order, starting after afterID.
+func (db *DB) ListMemories(ctx context.Context, afterID int64, limit int) ([]Memory, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories m
+		WHERE m.tenant_id = $1 AND m.workspace_id = $2 AND m.id > $3
+		ORDER BY m.id
+		LIMIT $4
+	`, db.tenantID, db.workspaceID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list memories: %w", err)
+	}
+	defer rows.Close()
+
+	return db.scanMemories(rows)
+}
+
 // GetMemory retrieves a memory by ID.
 func (db *DB) GetMemory(ctx context.Context, id int64) (*Memory, error) {
 	var m Memory
-	var metaJSON []byte
 
-	err := db.pool.QueryRow(ctx, `
-		SELECT id, tenant_id, workspace_id, kind, text, source, created_at, updated_at, tags, importance, ttl_days, meta
-		FROM memories
-		WHERE id = $1 AND tenant_id = $2 AND workspace_id = $3
-	`, id, db.tenantID, db.workspaceID).Scan(
-		&m.ID, &m.TenantID, &m.WorkspaceID, &m.Kind, &m.Text, &m.Source,
-		&m.CreatedAt, &m.UpdatedAt, &m.Tags, &m.Importance, &m.TTLDays, &metaJSON,
-	)
+	row := db.pool.QueryRow(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories m
+		WHERE m.id = $1 AND m.tenant_id = $2 AND m.workspace_id = $3
+	`, id, db.tenantID, db.workspaceID)
 
+	err := db.scanMemory(row, &m)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -166,12 +272,6 @@ func (db *DB) GetMemory(ctx context.Context, id int64) (*Memory, error) {
 		return nil, fmt.Errorf("query memory: %w", err)
 	}
 
-	if len(metaJSON) > 0 {
-		if err := json.Unmarshal(metaJSON, &m.Meta); err != nil {
-			return nil, fmt.Errorf("unmarshal meta: %w", err)
-		}
-	}
-
 	return &m, nil
 }
 
@@ -183,6 +283,7 @@ type UpdateMemoryParams struct {
 	Tags       []string
 	Importance *float32
 	TTLDays    *int
+	TTLFrom    *string
 	Meta       map[string]any
 }
 
@@ -199,9 +300,13 @@ func (db *DB) UpdateMemory(ctx context.Context, id int64, params UpdateMemoryPar
 		argIdx++
 	}
 	if params.Text != nil {
-		setClauses = append(setClauses, fmt.Sprintf("text = $%d", argIdx))
-		args = append(args, *params.Text)
-		argIdx++
+		text, index, err := db.sealText(db.workspaceID, *params.Text)
+		if err != nil {
+			return err
+		}
+		setClauses = append(setClauses, fmt.Sprintf("text = $%d", argIdx), fmt.Sprintf("blind_index = $%d", argIdx+1))
+		args = append(args, text, index)
+		argIdx += 2
 	}
 	if params.Source != nil {
 		setClauses = append(setClauses, fmt.Sprintf("source = $%d", argIdx))
@@ -223,8 +328,17 @@ func (db *DB) UpdateMemory(ctx context.Context, id int64, params UpdateMemoryPar
 		args = append(args, *params.TTLDays)
 		argIdx++
 	}
+	if params.TTLFrom != nil {
+		setClauses = append(setClauses, fmt.Sprintf("ttl_from = $%d", argIdx))
+		args = append(args, *params.TTLFrom)
+		argIdx++
+	}
 	if params.Meta != nil {
-		metaJSON, err := json.Marshal(params.Meta)
+		meta, err := db.sealMeta(db.workspaceID, params.Meta)
+		if err != nil {
+			return err
+		}
+		metaJSON, err := json.Marshal(meta)
 		if err != nil {
 			return fmt.Errorf("marshal meta: %w", err)
 		}
@@ -271,9 +385,16 @@ type VectorSearchParams struct {
 	Embedding []float32
 	Limit     int
 	Model     string // Optional: filter by embedding model (empty = any model)
+	Archived  bool   // search the archive tier instead of live memories
+
+	// Workspaces of the tenant to search; empty means the DB's workspace.
+	// AllWorkspaces searches every workspace of the tenant.
+	Workspaces    []string
+	AllWorkspaces bool
 }
 
-// VectorSearch performs vector similarity search using cosine distance.
+// VectorSearch performs vector similarity search using cosine distance over
+// the selected workspaces. Archived todos are excluded.
 // If Model is specified, only embeddings from that model are searched.
 // If Model is empty, the first matching embedding is used (for backward compatibility).
 func (db *DB) VectorSearch(ctx context.Context, params VectorSearchParams) ([]MemoryWithScore, error) {
@@ -282,6 +403,8 @@ func (db *DB) VectorSearch(ctx context.Context, params VectorSearchParams) ([]Me
 	}
 
 	vec := pgvector.NewVector(params.Embedding)
+	memories, embeddings := searchTables(params.Archived)
+	workspaces := db.searchWorkspaces(params.Workspaces, params.AllWorkspaces)
 
 	var rows pgx.Rows
 	var err error
@@ -289,29 +412,27 @@ func (db *DB) VectorSearch(ctx context.Context, params VectorSearchParams) ([]Me
 	if params.Model != "" {
 		// Search only embeddings from the specified model
 		rows, err = db.pool.Query(ctx, `
-			SELECT
-				m.id, m.tenant_id, m.workspace_id, m.kind, m.text, m.source,
-				m.created_at, m.updated_at, m.tags, m.importance, m.ttl_days, m.meta,
+			SELECT `+memoryColumns+`,
 				1 - (e.embedding <=> $1) AS score
-			FROM memories m
-			JOIN memory_embeddings e ON m.id = e.memory_id
-			WHERE m.tenant_id = $2 AND m.workspace_id = $3 AND e.model = $4
+			FROM `+memories+` m
+			JOIN `+embeddings+` e ON m.id = e.memory_id
+			WHERE m.tenant_id = $2 AND `+inWorkspaces+` AND e.model = $4
+			  AND `+notArchivedTodo+`
 			ORDER BY e.embedding <=> $1
 			LIMIT $5
-		`, vec, db.tenantID, db.workspaceID, params.Model, params.Limit)
+		`, vec, db.tenantID, workspaces, params.Model, params.Limit)
 	} else {
 		// Search all embeddings (backward compatible - uses DISTINCT ON to avoid duplicates)
 		rows, err = db.pool.Query(ctx, `
-			SELECT DISTINCT ON (m.id)
-				m.id, m.tenant_id, m.workspace_id, m.kind, m.text, m.source,
-				m.created_at, m.updated_at, m.tags, m.importance, m.ttl_days, m.meta,
+			SELECT DISTINCT ON (m.id) `+memoryColumns+`,
 				1 - (e.embedding <=> $1) AS score
-			FROM memories m
-			JOIN memory_embeddings e ON m.id = e.memory_id
-			WHERE m.tenant_id = $2 AND m.workspace_id = $3
+			FROM `+memories+` m
+			JOIN `+embeddings+` e ON m.id = e.memory_id
+			WHERE m.tenant_id = $2 AND `+inWorkspaces+`
+			  AND `+notArchivedTodo+`
 			ORDER BY m.id, e.embedding <=> $1
 			LIMIT $4
-		`, vec, db.tenantID, db.workspaceID, params.Limit)
+		`, vec, db.tenantID, workspaces, params.Limit)
 	}
 
 	if err != nil {
@@ -319,62 +440,105 @@ func (db *DB) VectorSearch(ctx context.Context, params VectorSearchParams) ([]Me
 	}
 	defer rows.Close()
 
-	return scanMemoriesWithScore(rows)
+	return db.scanMemoriesWithScore(rows, params.Archived)
 }
 
 // LexicalSearchParams contains parameters for lexical (trigram) search.
 type LexicalSearchParams struct {
-	Query string
-	Limit int
+	Query    string
+	Limit    int
+	Archived bool // search the archive tier instead of live memories
+
+	// Workspaces and AllWorkspaces select the workspaces searched, as for VectorSearchParams.
+	Workspaces    []string
+	AllWorkspaces bool
 }
 
 // LexicalSearch performs trigram-based text similarity search.
+// Documents split into chunks are matched through their chunks only, and
+// archived todos are excluded. Sealed memories are matched through their
+// blind index instead, on exact words, scoring the share of query words found.
 func (db *DB) LexicalSearch(ctx context.Context, params LexicalSearchParams) ([]MemoryWithScore, error) {
 	if params.Limit <= 0 {
 		params.Limit = 10
 	}
 
+	memories, _ := searchTables(params.Archived)
+	workspaces := db.searchWorkspaces(params.Workspaces, params.AllWorkspaces)
+	index, tokens := db.lexicalIndex(params.Query)
+
 	rows, err := db.pool.Query(ctx, `
-		SELECT
-			m.id, m.tenant_id, m.workspace_id, m.kind, m.text, m.source,
-			m.created_at, m.updated_at, m.tags, m.importance, m.ttl_days, m.meta,
-			similarity(m.text, $1) AS score
-		FROM memories m
-		WHERE m.tenant_id = $2 AND m.workspace_id = $3 AND m.text % $1
+		SELECT `+memoryColumns+`,
+			CASE WHEN m.blind_index IS NULL THEN similarity(m.text, $1)
+			ELSE cardinality(ARRAY(SELECT unnest(m.blind_index) INTERSECT SELECT unnest($5::text[])))::real / GREATEST($6, 1)
+			END AS score
+		FROM `+memories+` m
+		WHERE m.tenant_id = $2 AND `+inWorkspaces+`
+		  AND ((m.blind_index IS NULL AND m.text % $1) OR m.blind_index && $5::text[])
+		  AND NOT EXISTS (SELECT 1 FROM `+memories+` c WHERE c.parent_id = m.id)
+		  AND `+notArchivedTodo+`
 		ORDER BY score DESC
 		LIMIT $4
-	`, params.Query, db.tenantID, db.workspaceID, params.Limit)
+	`, params.Query, db.tenantID, workspaces, params.Limit, index, tokens)
 
 	if err != nil {
 		return nil, fmt.Errorf("lexical search: %w", err)
 	}
 	defer rows.Close()
 
-	return scanMemoriesWithScore(rows)
+	return db.scanMemoriesWithScore(rows, params.Archived)
+}
+
+// inWorkspaces restricts a search to the workspaces in $3; NULL means every workspace.
+const inWorkspaces = `($3::text[] IS NULL OR m.workspace_id = ANY($3))`
+
+// searchWorkspaces returns the $3 argument of inWorkspaces: nil to search
+// every workspace of the tenant, else the given workspaces or the DB's own.
+func (db *DB) searchWorkspaces(workspaces []string, all bool) []string {
+	if all {
+		return nil
+	}
+	if len(workspaces) == 0 {
+		return []string{db.workspaceID}
+	}
+	return workspaces
 }
 
-func scanMemoriesWithScore(rows pgx.Rows) ([]MemoryWithScore, error) {
+// searchTables returns the memory and embedding tables searched for the
+// live or archive tier. The archive tables have the same columns.
+func searchTables(archived bool) (memories, embeddings string) {
+	if archived {
+		return "memories_archive", "memory_embeddings_archive"
+	}
+	return "memories", "memory_embeddings"
+}
+
+func (db *DB) scanMemoriesWithScore(rows pgx.Rows, archived bool) ([]MemoryWithScore, error) {
 	var results []MemoryWithScore
 
 	for rows.Next() {
-		var m MemoryWithScore
-		var metaJSON []byte
-
-		err := rows.Scan(
-			&m.ID, &m.TenantID, &m.WorkspaceID, &m.Kind, &m.Text, &m.Source,
-			&m.CreatedAt, &m.UpdatedAt, &m.Tags, &m.Importance, &m.TTLDays, &metaJSON,
-			&m.Score,
-		)
-		if err != nil {
+		m := MemoryWithScore{Archived: archived}
+		if err := db.scanMemory(rows, &m.Memory, &m.Score); err != nil {
 			return nil, fmt.Errorf("scan row: %w", err)
 		}
+		results = append(results, m)
+	}
 
-		if len(metaJSON) > 0 {
-			if err := json.Unmarshal(metaJSON, &m.Meta); err != nil {
-				return nil, fmt.Errorf("unmarshal meta: %w", err)
-			}
-		}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return results, nil
+}
+
+func (db *DB) scanMemories(rows pgx.Rows) ([]Memory, error) {
+	var results []Memory
 
+	for rows.Next() {
+		var m Memory
+		if err := db.scanMemory(rows, &m); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
 		results = append(results, m)
 	}
 
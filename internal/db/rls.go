@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Session settings read by the row-level security policies (migration 013).
+const (
+	settingTenantID   = "cortex.tenant_id"
+	settingAllTenants = "cortex.all_tenants"
+)
+
+// rowSecurityTables are the tables under row-level security (migrations 013 to 015).
+var rowSecurityTables = []string{
+	"memories", "memories_archive", "memory_embeddings", "memory_embeddings_archive",
+	"entities", "memory_entities", "entity_relations", "memory_links",
+	"jobs", "todos", "sweeper_runs", "sweeper_run_workspaces", "api_keys", "audit_log",
+}
+
+// IsolationCheck reports whether row-level security keeps other tenants'
+// rows out of reach of this tenant's connections.
+type IsolationCheck struct {
+	Role        string   // database role Cortex connects as
+	BypassesRLS bool     // the role is a superuser or has BYPASSRLS
+	Unprotected []string // tables without forced row-level security
+	Leaks       []string // cross-tenant reads and writes that succeeded
+}
+
+// Isolated reports whether the check found no way around tenant isolation.
+func (c *IsolationCheck) Isolated() bool {
+	return !c.BypassesRLS && len(c.Unprotected) == 0 && len(c.Leaks) == 0
+}
+
+// RoleBypassesRLS returns the role the DB connects as and whether row-level
+// security policies apply to it.
+func (db *DB) RoleBypassesRLS(ctx context.Context) (role string, bypass bool, err error) {
+	err = db.pool.QueryRow(ctx, `
+		SELECT rolname, rolsuper OR rolbypassrls
+		FROM pg_roles
+		WHERE rolname = current_user
+	`).Scan(&role, &bypass)
+	if err != nil {
+		return "", false, fmt.Errorf("query role: %w", err)
+	}
+	return role, bypass, nil
+}
+
+// CheckIsolation verifies tenant isolation on the DB's connections. Inside a
+// transaction that is rolled back, it writes a memory, embedding and entity
+// as another tenant, then tries to read, change and add rows of that tenant
+// through the DB's own tenant setting.
+func (db *DB) CheckIsolation(ctx context.Context) (*IsolationCheck, error) {
+	var check IsolationCheck
+	var err error
+	check.Role, check.BypassesRLS, err = db.RoleBypassesRLS(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT t
+		FROM unnest($1::text[]) AS t
+		LEFT JOIN pg_class c ON c.relname = t AND c.relkind = 'r'
+		WHERE NOT COALESCE(c.relrowsecurity AND c.relforcerowsecurity, false)
+		ORDER BY t
+	`, rowSecurityTables)
+	if err != nil {
+		return nil, fmt.Errorf("query row security: %w", err)
+	}
+	check.Unprotected, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("query row security: %w", err)
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	probeTenant := "isolation-probe:" + db.tenantID
+	if err := setTenant(ctx, tx, probeTenant); err != nil {
+		return nil, err
+	}
+
+	var memoryID, entityID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO memories (tenant_id, workspace_id, kind, text)
+		VALUES ($1, $2, 'note', 'isolation probe')
+		RETURNING id
+	`, probeTenant, db.workspaceID).Scan(&memoryID)
+	if err != nil {
+		return nil, fmt.Errorf("insert probe memory: %w", err)
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO entities (tenant_id, workspace_id, name, type)
+		VALUES ($1, $2, 'isolation probe', 'other')
+		RETURNING id
+	`, probeTenant, db.workspaceID).Scan(&entityID)
+	if err != nil {
+		return nil, fmt.Errorf("insert probe entity: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO memory_embeddings (memory_id, model, dims, embedding)
+		VALUES ($1, 'isolation-probe', 3, '[1,0,0]')
+	`, memoryID)
+	if err != nil {
+		return nil, fmt.Errorf("insert probe embedding: %w", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO memory_entities (memory_id, entity_id) VALUES ($1, $2)`, memoryID, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("insert probe entity link: %w", err)
+	}
+
+	// Everything below runs as the DB's own tenant
+	if err := setTenant(ctx, tx, db.tenantID); err != nil {
+		return nil, err
+	}
+
+	reads := []struct {
+		what string
+		sql  string
+		arg  int64
+	}{
+		{"read memories", `SELECT count(*) FROM memories WHERE id = $1`, memoryID},
+		{"read memory_embeddings", `SELECT count(*) FROM memory_embeddings WHERE memory_id = $1`, memoryID},
+		{"read entities", `SELECT count(*) FROM entities WHERE id = $1`, entityID},
+		{"read memory_entities", `SELECT count(*) FROM memory_entities WHERE memory_id = $1`, memoryID},
+	}
+	for _, r := range reads {
+		var n int64
+		if err := tx.QueryRow(ctx, r.sql, r.arg).Scan(&n); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.what, err)
+		}
+		if n > 0 {
+			check.Leaks = append(check.Leaks, r.what)
+		}
+	}
+
+	writes := []struct {
+		what string
+		sql  string
+		arg  int64
+	}{
+		{"update memories", `UPDATE memories SET importance = importance WHERE id = $1`, memoryID},
+		{"delete entities", `DELETE FROM entities WHERE id = $1`, entityID},
+	}
+	for _, w := range writes {
+		tag, err := tx.Exec(ctx, w.sql, w.arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", w.what, err)
+		}
+		if tag.RowsAffected() > 0 {
+			check.Leaks = append(check.Leaks, w.what)
+		}
+	}
+
+	// Writing a row for another tenant must violate the policy
+	blocked, err := violatesRowSecurity(ctx, tx, `
+		INSERT INTO memories (tenant_id, workspace_id, kind, text)
+		VALUES ($1, $2, 'note', 'isolation probe')
+	`, probeTenant, db.workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("insert memory for another tenant: %w", err)
+	}
+	if !blocked {
+		check.Leaks = append(check.Leaks, "insert memories for another tenant")
+	}
+
+	return &check, nil
+}
+
+// setTenant sets the tenant whose rows are visible for the rest of tx.
+func setTenant(ctx context.Context, tx pgx.Tx, tenantID string) error {
+	if _, err := tx.Exec(ctx, `SELECT set_config($1, $2, true)`, settingTenantID, tenantID); err != nil {
+		return fmt.Errorf("set tenant: %w", err)
+	}
+	return nil
+}
+
+// violatesRowSecurity runs a statement in a savepoint and reports whether a
+// row-level security policy rejected it.
+func violatesRowSecurity(ctx context.Context, tx pgx.Tx, sql string, args ...any) (bool, error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin savepoint: %w", err)
+	}
+	defer sp.Rollback(ctx)
+
+	_, err = sp.Exec(ctx, sql, args...)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42501" { // insufficient_privilege
+		return true, nil
+	}
+	return false, err
+}
@@ -0,0 +1,249 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/johnswift/cortex/internal/sweeper"
+)
+
+// TestTenantIsolation checks that row-level security keeps one tenant's rows
+// out of reach of another tenant's pool. It needs a database reached through
+// CORTEX_TEST_DATABASE_URL as a role that is neither a superuser nor has
+// BYPASSRLS, and is skipped without one.
+func TestTenantIsolation(t *testing.T) {
+	url := os.Getenv("CORTEX_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("CORTEX_TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	tenantA, tenantB := "rls-test-a-"+suffix, "rls-test-b-"+suffix
+
+	a, err := New(ctx, url, tenantA)
+	if err != nil {
+		t.Fatalf("connect as tenant A: %v", err)
+	}
+	defer a.Close()
+	if err := a.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	role, bypass, err := a.RoleBypassesRLS(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bypass {
+		t.Fatalf("role %s bypasses row-level security: connect as a NOSUPERUSER NOBYPASSRLS role", role)
+	}
+
+	b, err := New(ctx, url, tenantB)
+	if err != nil {
+		t.Fatalf("connect as tenant B: %v", err)
+	}
+	defer b.Close()
+
+	ids := seedTenant(ctx, t, a)
+	t.Cleanup(func() {
+		// Deleting the memories removes their links, todos and jobs. Audit
+		// entries are append-only and stay behind.
+		for _, table := range []string{"memories", "entities", "api_keys"} {
+			if _, err := a.pool.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE tenant_id = $1`, table), tenantA); err != nil {
+				t.Errorf("clean up %s: %v", table, err)
+			}
+		}
+	})
+
+	// Inserts of rows claiming tenant A, which B's policies must reject
+	inserts := map[string]struct {
+		sql  string
+		args []any
+	}{
+		"memories": {`INSERT INTO memories (tenant_id, workspace_id, kind, text) VALUES ($1, 'default', 'note', 'planted')`,
+			[]any{tenantA}},
+		"entities": {`INSERT INTO entities (tenant_id, workspace_id, name, type) VALUES ($1, 'default', 'planted', 'other')`,
+			[]any{tenantA}},
+		"memory_links": {`INSERT INTO memory_links (tenant_id, workspace_id, source_id, target_id, link_type) VALUES ($1, 'default', $2, $3, 'blocks')`,
+			[]any{tenantA, ids.todo, ids.note}},
+		"todos": {`INSERT INTO todos (memory_id, tenant_id, workspace_id) VALUES ($1, $2, 'default')`,
+			[]any{ids.note, tenantA}},
+		"jobs": {`INSERT INTO jobs (tenant_id, workspace_id, kind) VALUES ($1, 'default', 'embed')`,
+			[]any{tenantA}},
+		"audit_log": {`INSERT INTO audit_log (tenant_id, workspace_id, actor, tool) VALUES ($1, 'default', 'planted', 'planted')`,
+			[]any{tenantA}},
+		"api_keys": {`INSERT INTO api_keys (tenant_id, name, key_prefix, key_hash, role, workspaces) VALUES ($1, 'planted', 'planted', 'planted', 'admin', '{*}')`,
+			[]any{tenantA}},
+	}
+
+	for _, table := range []string{"memories", "entities", "memory_links", "todos", "jobs", "audit_log", "api_keys"} {
+		t.Run(table, func(t *testing.T) {
+			before := countRows(ctx, t, a.pool, table, tenantA)
+			if before == 0 {
+				t.Fatalf("tenant A has no %s rows to protect", table)
+			}
+
+			if n := countRows(ctx, t, b.pool, table, tenantA); n > 0 {
+				t.Errorf("tenant B sees %d of tenant A's %s rows", n, table)
+			}
+
+			writes := []struct {
+				what string
+				sql  string
+				args []any
+			}{
+				{"update", fmt.Sprintf(`UPDATE %s SET tenant_id = $2 WHERE tenant_id = $1`, table), []any{tenantA, tenantB}},
+				{"delete", fmt.Sprintf(`DELETE FROM %s WHERE tenant_id = $1`, table), []any{tenantA}},
+			}
+			for _, w := range writes {
+				tag, err := b.pool.Exec(ctx, w.sql, w.args...)
+				// audit_log rejects every update and delete statement outright
+				if err != nil && table != "audit_log" {
+					t.Errorf("%s as tenant B: %v", w.what, err)
+				}
+				if err == nil && tag.RowsAffected() > 0 {
+					t.Errorf("tenant B can %s %d of tenant A's %s rows", w.what, tag.RowsAffected(), table)
+				}
+			}
+
+			insert := inserts[table]
+			_, err := b.pool.Exec(ctx, insert.sql, insert.args...)
+			var pgErr *pgconn.PgError
+			if err == nil {
+				t.Errorf("tenant B can insert %s rows for tenant A", table)
+			} else if !errors.As(err, &pgErr) || pgErr.Code != "42501" { // insufficient_privilege
+				t.Errorf("insert as tenant B: %v", err)
+			}
+
+			if after := countRows(ctx, t, a.pool, table, tenantA); after != before {
+				t.Errorf("tenant A has %d %s rows after tenant B's writes, want %d", after, table, before)
+			}
+		})
+	}
+}
+
+// seededIDs are the memories written for the tenant under test.
+type seededIDs struct {
+	todo, note int64
+}
+
+// seedTenant writes a row of each protected table for the tenant of d.
+func seedTenant(ctx context.Context, t *testing.T, d *DB) seededIDs {
+	t.Helper()
+	var ids seededIDs
+	var err error
+
+	if ids.todo, err = d.AddMemory(ctx, AddMemoryParams{Kind: "todo", Text: "isolation test todo"}); err != nil {
+		t.Fatal(err)
+	}
+	if ids.note, err = d.AddMemory(ctx, AddMemoryParams{Kind: "note", Text: "isolation test note"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEntity(ctx, AddEntityParams{Name: "isolation test", Type: EntityTypeOther}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddMemoryLink(ctx, ids.todo, ids.note, LinkTypeRelatesTo, nil); err != nil {
+		t.Fatal(err)
+	}
+	priority := 2
+	if err := d.UpdateTodo(ctx, ids.todo, UpdateTodoParams{Priority: &priority}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.pool.Exec(ctx, `
+		INSERT INTO jobs (tenant_id, workspace_id, kind, memory_id) VALUES ($1, $2, 'embed', $3)
+	`, d.tenantID, d.workspaceID, ids.note); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RecordAudit(ctx, AuditEntry{Actor: "test", Tool: "isolation-test", MemoryIDs: []int64{ids.note}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := d.CreateAPIKey(ctx, "isolation test", RoleRead, []string{AllWorkspaces}); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+// countRows counts the rows of a table that the pool sees for tenantID.
+func countRows(ctx context.Context, t *testing.T, pool *pgxpool.Pool, table, tenantID string) int64 {
+	t.Helper()
+	var n int64
+	if err := pool.QueryRow(ctx, fmt.Sprintf(`SELECT count(*) FROM %s WHERE tenant_id = $1`, table), tenantID).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+// TestGlobalSweepRunsPerTenant checks that a global sweep run from one
+// tenant shows up in the sweeper history of every tenant it covered, which
+// row-level security would otherwise hide from them.
+func TestGlobalSweepRunsPerTenant(t *testing.T) {
+	url := os.Getenv("CORTEX_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("CORTEX_TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	tenantA, tenantB := "rls-sweep-a-"+suffix, "rls-sweep-b-"+suffix
+
+	a, err := New(ctx, url, tenantA)
+	if err != nil {
+		t.Fatalf("connect as tenant A: %v", err)
+	}
+	defer a.Close()
+	if err := a.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	b, err := New(ctx, url, tenantB)
+	if err != nil {
+		t.Fatalf("connect as tenant B: %v", err)
+	}
+	defer b.Close()
+	all, err := NewAllTenantsPool(ctx, url)
+	if err != nil {
+		t.Fatalf("connect for every tenant: %v", err)
+	}
+	defer all.Close()
+
+	// A global sweep covers the workspaces that have memories
+	if _, err := b.AddMemory(ctx, AddMemoryParams{Kind: "note", Text: "swept by another tenant"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, tenantID := range []string{tenantA, tenantB} {
+			if _, err := all.Exec(ctx, `DELETE FROM memories WHERE tenant_id = $1`, tenantID); err != nil {
+				t.Errorf("clean up memories: %v", err)
+			}
+			if _, err := all.Exec(ctx, `DELETE FROM sweeper_runs WHERE tenant_id = $1`, tenantID); err != nil {
+				t.Errorf("clean up sweeper runs: %v", err)
+			}
+		}
+	})
+
+	cfg := sweeper.DefaultConfig()
+	cfg.Global = true
+	run := sweeper.NewSweeper(all, tenantA, "default").WithConfig(cfg).RunOnce(ctx)
+	if run.Skipped || run.Error != "" {
+		t.Fatalf("global sweep did not run: skipped=%v error=%q", run.Skipped, run.Error)
+	}
+
+	for name, d := range map[string]*DB{"A": a, "B": b} {
+		runs, err := d.ListSweeperRuns(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, r := range runs {
+			found = found || (r.Global && r.StartedAt.Equal(run.StartedAt.Truncate(time.Microsecond)))
+		}
+		if !found {
+			t.Errorf("tenant %s does not see the global sweep started at %v", name, run.StartedAt)
+		}
+	}
+}
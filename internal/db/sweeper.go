@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SweeperRun is a recorded sweep that covered the workspace, with what it
+// changed there.
+type SweeperRun struct {
+	ID         int64     `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Global     bool      `json:"global"`            // swept every workspace
+	Skipped    bool      `json:"skipped,omitempty"` // another instance held the sweep lock
+	Error      *string   `json:"error,omitempty"`   // the sweep could not start
+
+	Expired       int64    `json:"expired"`
+	Evicted       int64    `json:"evicted"`
+	PolicyExpired int64    `json:"policy_expired"`
+	Decayed       int64    `json:"decayed"`
+	TodosArchived int64    `json:"todos_archived"`
+	Errors        []string `json:"errors,omitempty"` // steps that failed in the workspace
+}
+
+// Removed returns the number of memories the run deleted or archived in the workspace.
+func (r *SweeperRun) Removed() int64 {
+	return r.Expired + r.Evicted + r.PolicyExpired
+}
+
+// ListSweeperRuns returns the most recent sweeps that covered the workspace,
+// newest first: sweeps run from the workspace and global sweeps, which are
+// recorded for every tenant they covered.
+func (db *DB) ListSweeperRuns(ctx context.Context, limit int) ([]SweeperRun, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT r.id, r.started_at, r.finished_at, r.global, r.skipped, r.error,
+		       COALESCE(w.expired, 0), COALESCE(w.evicted, 0), COALESCE(w.policy_expired, 0),
+		       COALESCE(w.decayed, 0), COALESCE(w.todos_archived, 0), COALESCE(w.errors, '{}')
+		FROM sweeper_runs r
+		LEFT JOIN sweeper_run_workspaces w
+		  ON w.run_id = r.id AND w.tenant_id = $1 AND w.workspace_id = $2
+		WHERE r.tenant_id = $1 AND (r.global OR r.workspace_id = $2)
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT $3
+	`, db.tenantID, db.workspaceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list sweeper runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []SweeperRun
+	for rows.Next() {
+		var r SweeperRun
+		if err := rows.Scan(&r.ID, &r.StartedAt, &r.FinishedAt, &r.Global, &r.Skipped, &r.Error,
+			&r.Expired, &r.Evicted, &r.PolicyExpired, &r.Decayed, &r.TodosArchived, &r.Errors); err != nil {
+			return nil, fmt.Errorf("scan sweeper run: %w", err)
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
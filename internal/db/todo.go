@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KindTodo is the memory kind that carries todo fields.
+const KindTodo = "todo"
+
+// TodoStatus is the lifecycle state of a todo.
+type TodoStatus string
+
+const (
+	TodoOpen       TodoStatus = "open"
+	TodoInProgress TodoStatus = "in_progress"
+	TodoDone       TodoStatus = "done"
+	TodoArchived   TodoStatus = "archived" // done long enough ago to be hidden; set by the sweeper
+)
+
+// ParseTodoStatus validates a todo status name.
+func ParseTodoStatus(s string) (TodoStatus, error) {
+	switch TodoStatus(s) {
+	case TodoOpen, TodoInProgress, TodoDone, TodoArchived:
+		return TodoStatus(s), nil
+	default:
+		return "", fmt.Errorf("unknown todo status %q (expected open, in_progress, done or archived)", s)
+	}
+}
+
+// TodoPriorities names the todo priorities, indexed by their stored value.
+var TodoPriorities = []string{"low", "normal", "high", "urgent"}
+
+// ParseTodoPriority returns the stored value of a priority name.
+func ParseTodoPriority(s string) (int, error) {
+	for i, name := range TodoPriorities {
+		if name == s {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown todo priority %q (expected one of %v)", s, TodoPriorities)
+}
+
+// Todo is a todo memory with its lifecycle fields.
+type Todo struct {
+	Memory
+	Status      TodoStatus `json:"status"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	AssigneeID  *int64     `json:"assignee_id,omitempty"`
+	Assignee    *string    `json:"assignee,omitempty"` // assignee entity name
+	Priority    int        `json:"priority"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// PriorityName returns the name of the todo's priority.
+func (t *Todo) PriorityName() string {
+	if t.Priority < 0 || t.Priority >= len(TodoPriorities) {
+		return fmt.Sprintf("%d", t.Priority)
+	}
+	return TodoPriorities[t.Priority]
+}
+
+// UpdateTodoParams contains the todo fields to set. Nil fields are left unchanged.
+type UpdateTodoParams struct {
+	Status     *TodoStatus
+	DueAt      *time.Time
+	AssigneeID *int64
+	Priority   *int
+}
+
+// UpdateTodo sets the todo fields of a memory of kind todo. Setting the status
+// to done records the completion time; reopening clears it.
+func (db *DB) UpdateTodo(ctx context.Context, memoryID int64, params UpdateTodoParams) error {
+	var status *string
+	if params.Status != nil {
+		s := string(*params.Status)
+		status = &s
+	}
+
+	result, err := db.pool.Exec(ctx, `
+		INSERT INTO todos (memory_id, tenant_id, workspace_id, status, due_at, assignee_id, priority, completed_at)
+		SELECT m.id, m.tenant_id, m.workspace_id,
+			COALESCE($4::text, 'open'), $5::timestamptz, $6::bigint, COALESCE($7::smallint, 1),
+			CASE WHEN $4::text = 'done' THEN now() END
+		FROM memories m
+		WHERE m.id = $1 AND m.tenant_id = $2 AND m.workspace_id = $3 AND m.kind = 'todo'
+		ON CONFLICT (memory_id) DO UPDATE SET
+			status = COALESCE($4::text, todos.status),
+			due_at = COALESCE($5::timestamptz, todos.due_at),
+			assignee_id = COALESCE($6::bigint, todos.assignee_id),
+			priority = COALESCE($7::smallint, todos.priority),
+			completed_at = CASE
+				WHEN $4::text = 'done' AND todos.status <> 'done' THEN now()
+				WHEN $4::text IN ('open', 'in_progress') THEN NULL
+				ELSE todos.completed_at
+			END,
+			updated_at = now()
+	`, memoryID, db.tenantID, db.workspaceID, status, params.DueAt, params.AssigneeID, params.Priority)
+	if err != nil {
+		return fmt.Errorf("update todo: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("todo not found")
+	}
+
+	return nil
+}
+
+// notArchivedTodo is a search condition, for queries aliasing memories as m,
+// that excludes todos archived by the sweeper.
+const notArchivedTodo = `NOT EXISTS (SELECT 1 FROM todos ta WHERE ta.memory_id = m.id AND ta.status = 'archived')`
+
+// todoColumns is the column list scanned by queryTodos, for queries joining
+// memories m, todos t and the assignee entities a.
+const todoColumns = memoryColumns + `,
+	COALESCE(t.status, 'open'), t.due_at, t.assignee_id, a.name,
+	COALESCE(t.priority, 1), t.completed_at`
+
+const todoJoins = `FROM memories m
+	LEFT JOIN todos t ON t.memory_id = m.id
+	LEFT JOIN entities a ON a.id = t.assignee_id`
+
+// GetTodo retrieves a todo by memory ID, or nil if no todo memory has that ID.
+func (db *DB) GetTodo(ctx context.Context, id int64) (*Todo, error) {
+	todos, err := db.queryTodos(ctx, `
+		SELECT `+todoColumns+`
+		`+todoJoins+`
+		WHERE m.id = $1 AND m.tenant_id = $2 AND m.workspace_id = $3 AND m.kind = 'todo'
+	`, id, db.tenantID, db.workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(todos) == 0 {
+		return nil, nil
+	}
+	return &todos[0], nil
+}
+
+// TodoFilter selects todos to list.
+type TodoFilter struct {
+	Statuses   []TodoStatus // empty = open and in_progress
+	AssigneeID *int64
+	DueBefore  *time.Time // only todos due before this time
+	Overdue    bool       // only unfinished todos past their due date
+	Limit      int
+}
+
+// ListTodos returns todos matching the filter: overdue and soonest-due first,
+// then by priority, then oldest first. Todos without a due date come last.
+func (db *DB) ListTodos(ctx context.Context, filter TodoFilter) ([]Todo, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []TodoStatus{TodoOpen, TodoInProgress}
+	}
+	statusNames := make([]string, len(statuses))
+	for i, s := range statuses {
+		statusNames[i] = string(s)
+	}
+
+	conditions := []string{
+		"m.tenant_id = $1", "m.workspace_id = $2", "m.kind = 'todo'",
+		"COALESCE(t.status, 'open') = ANY($3)",
+	}
+	args := []any{db.tenantID, db.workspaceID, statusNames}
+
+	if filter.AssigneeID != nil {
+		args = append(args, *filter.AssigneeID)
+		conditions = append(conditions, fmt.Sprintf("t.assignee_id = $%d", len(args)))
+	}
+	if filter.DueBefore != nil {
+		args = append(args, *filter.DueBefore)
+		conditions = append(conditions, fmt.Sprintf("t.due_at < $%d", len(args)))
+	}
+	if filter.Overdue {
+		conditions = append(conditions, "t.due_at < now()", "t.status IN ('open', 'in_progress')")
+	}
+	args = append(args, filter.Limit)
+
+	return db.queryTodos(ctx, `
+		SELECT `+todoColumns+`
+		`+todoJoins+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY t.due_at ASC NULLS LAST, COALESCE(t.priority, 1) DESC, m.created_at ASC
+		LIMIT $`+fmt.Sprint(len(args)), args...)
+}
+
+func (db *DB) queryTodos(ctx context.Context, query string, args ...any) ([]Todo, error) {
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list todos: %w", err)
+	}
+	defer rows.Close()
+
+	var todos []Todo
+	for rows.Next() {
+		var t Todo
+		if err := db.scanMemory(rows, &t.Memory,
+			&t.Status, &t.DueAt, &t.AssigneeID, &t.Assignee, &t.Priority, &t.CompletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan todo: %w", err)
+		}
+		todos = append(todos, t)
+	}
+
+	return todos, rows.Err()
+}
@@ -0,0 +1,436 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Workspace summarizes one workspace of the tenant.
+type Workspace struct {
+	ID             string     `json:"id"`
+	Memories       int64      `json:"memories"` // including document chunks
+	Documents      int64      `json:"documents"`
+	Entities       int64      `json:"entities"`
+	Archived       int64      `json:"archived"`        // memories in the archive tier
+	TextBytes      int64      `json:"text_bytes"`      // size of the memory texts
+	EmbeddingBytes int64      `json:"embedding_bytes"` // stored size of the embeddings
+	LastActivity   *time.Time `json:"last_activity,omitempty"`
+}
+
+// WorkspaceCounts counts what a rename, clone or merge moved or copied.
+type WorkspaceCounts struct {
+	Memories       int64 `json:"memories"`
+	Entities       int64 `json:"entities"`
+	EntitiesMerged int64 `json:"entities_merged,omitempty"` // folded into an entity of the target with the same name and type
+	Relations      int64 `json:"relations"`
+	Links          int64 `json:"links"`
+}
+
+// ListWorkspaces returns every workspace of the tenant that holds memories,
+// archived memories or entities, ordered by ID.
+func (db *DB) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
+	rows, err := db.pool.Query(ctx, `
+		WITH ws AS (
+			SELECT workspace_id FROM memories WHERE tenant_id = $1
+			UNION SELECT workspace_id FROM entities WHERE tenant_id = $1
+			UNION SELECT workspace_id FROM memories_archive WHERE tenant_id = $1
+		)
+		SELECT ws.workspace_id,
+		       COALESCE(m.memories, 0), COALESCE(m.documents, 0), COALESCE(n.entities, 0),
+		       COALESCE(a.archived, 0), COALESCE(m.text_bytes, 0), COALESCE(e.embedding_bytes, 0),
+		       m.last_activity
+		FROM ws
+		LEFT JOIN (
+			SELECT workspace_id, count(*) AS memories,
+			       count(*) FILTER (WHERE kind = 'document') AS documents,
+			       sum(octet_length(text))::bigint AS text_bytes,
+			       GREATEST(max(updated_at), max(last_accessed_at)) AS last_activity
+			FROM memories WHERE tenant_id = $1
+			GROUP BY workspace_id
+		) m USING (workspace_id)
+		LEFT JOIN (
+			SELECT m.workspace_id, sum(pg_column_size(e.embedding))::bigint AS embedding_bytes
+			FROM memory_embeddings e
+			JOIN memories m ON m.id = e.memory_id
+			WHERE m.tenant_id = $1
+			GROUP BY m.workspace_id
+		) e USING (workspace_id)
+		LEFT JOIN (
+			SELECT workspace_id, count(*) AS entities
+			FROM entities WHERE tenant_id = $1
+			GROUP BY workspace_id
+		) n USING (workspace_id)
+		LEFT JOIN (
+			SELECT workspace_id, count(*) AS archived
+			FROM memories_archive WHERE tenant_id = $1
+			GROUP BY workspace_id
+		) a USING (workspace_id)
+		ORDER BY ws.workspace_id
+	`, db.tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	var workspaces []Workspace
+	for rows.Next() {
+		var w Workspace
+		if err := rows.Scan(&w.ID, &w.Memories, &w.Documents, &w.Entities, &w.Archived,
+			&w.TextBytes, &w.EmbeddingBytes, &w.LastActivity); err != nil {
+			return nil, fmt.Errorf("scan workspace: %w", err)
+		}
+		workspaces = append(workspaces, w)
+	}
+	return workspaces, rows.Err()
+}
+
+// RenameWorkspace moves everything in workspace from to workspace to, which
+// must not hold any memories or entities yet. Sweeper run history keeps the
+// old name.
+func (db *DB) RenameWorkspace(ctx context.Context, from, to string) (*WorkspaceCounts, error) {
+	var counts *WorkspaceCounts
+	err := db.workspaceTx(ctx, from, to, func(tx pgx.Tx) error {
+		if err := db.requireEmptyWorkspace(ctx, tx, to); err != nil {
+			return err
+		}
+		var err error
+		counts, err = db.moveWorkspace(ctx, tx, from, to)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rename workspace: %w", err)
+	}
+	return counts, nil
+}
+
+// MergeWorkspace moves everything in workspace from into workspace to and
+// leaves from empty. An entity of from with the same name and type as one in
+// to is folded into it: its memory links, relations and todo assignments move
+// to the target entity and its aliases are added to it.
+func (db *DB) MergeWorkspace(ctx context.Context, from, to string) (*WorkspaceCounts, error) {
+	var counts *WorkspaceCounts
+	err := db.workspaceTx(ctx, from, to, func(tx pgx.Tx) error {
+		var err error
+		counts, err = db.moveWorkspace(ctx, tx, from, to)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("merge workspace: %w", err)
+	}
+	return counts, nil
+}
+
+// CloneWorkspace copies the memories, embeddings, todos, entities, relations
+// and memory links of workspace from into workspace to, which must not hold
+// any memories or entities yet. Copies get new IDs. The archive tier and
+// queued jobs are not copied.
+func (db *DB) CloneWorkspace(ctx context.Context, from, to string) (*WorkspaceCounts, error) {
+	var counts *WorkspaceCounts
+	err := db.workspaceTx(ctx, from, to, func(tx pgx.Tx) error {
+		if err := db.requireEmptyWorkspace(ctx, tx, to); err != nil {
+			return err
+		}
+		var err error
+		counts, err = db.copyWorkspace(ctx, tx, from, to)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("clone workspace: %w", err)
+	}
+	return counts, nil
+}
+
+// workspaceTx validates a from/to pair and runs fn in a transaction.
+func (db *DB) workspaceTx(ctx context.Context, from, to string, fn func(tx pgx.Tx) error) error {
+	if from == "" || to == "" {
+		return fmt.Errorf("source and target workspaces are required")
+	}
+	if from == to {
+		return fmt.Errorf("source and target workspace are both %q", from)
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	exists, err := db.workspaceExists(ctx, tx, from)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("workspace %q not found", from)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// workspaceExists reports whether workspace holds memories, archived memories or entities.
+func (db *DB) workspaceExists(ctx context.Context, tx pgx.Tx, workspace string) (bool, error) {
+	var exists bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM memories WHERE tenant_id = $1 AND workspace_id = $2)
+		    OR EXISTS (SELECT 1 FROM entities WHERE tenant_id = $1 AND workspace_id = $2)
+		    OR EXISTS (SELECT 1 FROM memories_archive WHERE tenant_id = $1 AND workspace_id = $2)
+	`, db.tenantID, workspace).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check workspace %q: %w", workspace, err)
+	}
+	return exists, nil
+}
+
+// requireEmptyWorkspace fails if workspace already exists.
+func (db *DB) requireEmptyWorkspace(ctx context.Context, tx pgx.Tx, workspace string) error {
+	exists, err := db.workspaceExists(ctx, tx, workspace)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("workspace %q already exists; merge into it instead", workspace)
+	}
+	return nil
+}
+
+// moveWorkspace moves every row of workspace from to workspace to, folding
+// entities that collide with the unique (name, type) of an entity of to.
+func (db *DB) moveWorkspace(ctx context.Context, tx pgx.Tx, from, to string) (*WorkspaceCounts, error) {
+	counts := &WorkspaceCounts{}
+
+	// Map each colliding entity of from to its counterpart in to
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE merged_entities (old_id BIGINT PRIMARY KEY, new_id BIGINT NOT NULL) ON COMMIT DROP
+	`); err != nil {
+		return nil, fmt.Errorf("create entity map: %w", err)
+	}
+	merged, err := tx.Exec(ctx, `
+		INSERT INTO merged_entities (old_id, new_id)
+		SELECT s.id, t.id
+		FROM entities s
+		JOIN entities t ON t.tenant_id = s.tenant_id AND t.workspace_id = $3
+		                AND t.name = s.name AND t.type = s.type
+		WHERE s.tenant_id = $1 AND s.workspace_id = $2
+	`, db.tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("match entities: %w", err)
+	}
+	counts.EntitiesMerged = merged.RowsAffected()
+
+	steps := []struct {
+		name string
+		sql  string
+		args []any
+	}{
+		{"merge entity aliases", `
+			UPDATE entities t
+			SET aliases = ARRAY(SELECT DISTINCT a FROM unnest(t.aliases || s.aliases) AS a),
+			    description = COALESCE(t.description, s.description),
+			    meta = s.meta || t.meta,
+			    updated_at = now()
+			FROM merged_entities me
+			JOIN entities s ON s.id = me.old_id
+			WHERE t.id = me.new_id`, nil},
+		// A memory linked to both entities keeps its link to the target
+		{"merge memory entities", `
+			DELETE FROM memory_entities x
+			USING merged_entities me
+			WHERE x.entity_id = me.old_id
+			  AND EXISTS (SELECT 1 FROM memory_entities y WHERE y.memory_id = x.memory_id AND y.entity_id = me.new_id)`, nil},
+		{"merge memory entities", `
+			UPDATE memory_entities x SET entity_id = me.new_id
+			FROM merged_entities me
+			WHERE x.entity_id = me.old_id`, nil},
+		// Relations that would duplicate one of the target are dropped
+		{"merge relations", `
+			DELETE FROM entity_relations r
+			WHERE r.tenant_id = $1 AND r.workspace_id = $2
+			  AND EXISTS (
+				SELECT 1 FROM entity_relations t
+				WHERE t.tenant_id = $1 AND t.workspace_id = $3
+				  AND t.relation_type = r.relation_type
+				  AND t.source_id = COALESCE((SELECT new_id FROM merged_entities WHERE old_id = r.source_id), r.source_id)
+				  AND t.target_id = COALESCE((SELECT new_id FROM merged_entities WHERE old_id = r.target_id), r.target_id))`,
+			[]any{db.tenantID, from, to}},
+		{"merge todo assignees", `
+			UPDATE todos t SET assignee_id = me.new_id
+			FROM merged_entities me
+			WHERE t.assignee_id = me.old_id`, nil},
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(ctx, step.sql, step.args...); err != nil {
+			return nil, fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE entity_relations r
+		SET workspace_id = $3,
+		    source_id = COALESCE((SELECT new_id FROM merged_entities WHERE old_id = r.source_id), r.source_id),
+		    target_id = COALESCE((SELECT new_id FROM merged_entities WHERE old_id = r.target_id), r.target_id)
+		WHERE r.tenant_id = $1 AND r.workspace_id = $2
+	`, db.tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("move relations: %w", err)
+	}
+	counts.Relations = result.RowsAffected()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM entities WHERE id IN (SELECT old_id FROM merged_entities)`); err != nil {
+		return nil, fmt.Errorf("remove merged entities: %w", err)
+	}
+
+	result, err = tx.Exec(ctx, `
+		UPDATE entities SET workspace_id = $3, updated_at = now()
+		WHERE tenant_id = $1 AND workspace_id = $2
+	`, db.tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("move entities: %w", err)
+	}
+	counts.Entities = result.RowsAffected() + counts.EntitiesMerged
+
+	result, err = tx.Exec(ctx, `
+		UPDATE memories SET workspace_id = $3 WHERE tenant_id = $1 AND workspace_id = $2
+	`, db.tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("move memories: %w", err)
+	}
+	counts.Memories = result.RowsAffected()
+
+	result, err = tx.Exec(ctx, `
+		UPDATE memory_links SET workspace_id = $3 WHERE tenant_id = $1 AND workspace_id = $2
+	`, db.tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("move links: %w", err)
+	}
+	counts.Links = result.RowsAffected()
+
+	for _, table := range []string{"todos", "jobs", "memories_archive"} {
+		if _, err := tx.Exec(ctx, `
+			UPDATE `+table+` SET workspace_id = $3 WHERE tenant_id = $1 AND workspace_id = $2
+		`, db.tenantID, from, to); err != nil {
+			return nil, fmt.Errorf("move %s: %w", table, err)
+		}
+	}
+
+	return counts, nil
+}
+
+// copyWorkspace copies workspace from into the empty workspace to, giving
+// memories and entities new IDs from their sequences.
+func (db *DB) copyWorkspace(ctx context.Context, tx pgx.Tx, from, to string) (*WorkspaceCounts, error) {
+	counts := &WorkspaceCounts{}
+
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE cloned_memories (old_id BIGINT PRIMARY KEY, new_id BIGINT NOT NULL) ON COMMIT DROP
+	`); err != nil {
+		return nil, fmt.Errorf("create memory map: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO cloned_memories (old_id, new_id)
+		SELECT id, nextval(pg_get_serial_sequence('memories', 'id'))
+		FROM memories WHERE tenant_id = $1 AND workspace_id = $2
+	`, db.tenantID, from); err != nil {
+		return nil, fmt.Errorf("allocate memory ids: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE cloned_entities (old_id BIGINT PRIMARY KEY, new_id BIGINT NOT NULL) ON COMMIT DROP
+	`); err != nil {
+		return nil, fmt.Errorf("create entity map: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO cloned_entities (old_id, new_id)
+		SELECT id, nextval(pg_get_serial_sequence('entities', 'id'))
+		FROM entities WHERE tenant_id = $1 AND workspace_id = $2
+	`, db.tenantID, from); err != nil {
+		return nil, fmt.Errorf("allocate entity ids: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO memories (id, tenant_id, workspace_id, kind, text, source, created_at, updated_at, tags,
+			importance, ttl_days, ttl_from, meta, parent_id, chunk_index, last_accessed_at, access_count, decayed_at, blind_index)
+		SELECT c.new_id, m.tenant_id, $2, m.kind, m.text, m.source, m.created_at, m.updated_at, m.tags,
+			m.importance, m.ttl_days, m.ttl_from, m.meta, p.new_id, m.chunk_index, m.last_accessed_at, m.access_count, m.decayed_at, m.blind_index
+		FROM memories m
+		JOIN cloned_memories c ON c.old_id = m.id
+		LEFT JOIN cloned_memories p ON p.old_id = m.parent_id
+		WHERE m.tenant_id = $1
+	`, db.tenantID, to)
+	if err != nil {
+		return nil, fmt.Errorf("copy memories: %w", err)
+	}
+	counts.Memories = result.RowsAffected()
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO memory_embeddings (memory_id, model, dims, embedding, created_at)
+		SELECT c.new_id, e.model, e.dims, e.embedding, e.created_at
+		FROM memory_embeddings e
+		JOIN cloned_memories c ON c.old_id = e.memory_id
+	`); err != nil {
+		return nil, fmt.Errorf("copy embeddings: %w", err)
+	}
+
+	result, err = tx.Exec(ctx, `
+		INSERT INTO entities (id, tenant_id, workspace_id, name, type, aliases, description, meta, created_at, updated_at)
+		SELECT c.new_id, e.tenant_id, $2, e.name, e.type, e.aliases, e.description, e.meta, e.created_at, e.updated_at
+		FROM entities e
+		JOIN cloned_entities c ON c.old_id = e.id
+		WHERE e.tenant_id = $1
+	`, db.tenantID, to)
+	if err != nil {
+		return nil, fmt.Errorf("copy entities: %w", err)
+	}
+	counts.Entities = result.RowsAffected()
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO memory_entities (memory_id, entity_id, role, confidence, created_at)
+		SELECT cm.new_id, ce.new_id, me.role, me.confidence, me.created_at
+		FROM memory_entities me
+		JOIN cloned_memories cm ON cm.old_id = me.memory_id
+		JOIN cloned_entities ce ON ce.old_id = me.entity_id
+	`); err != nil {
+		return nil, fmt.Errorf("copy memory entities: %w", err)
+	}
+
+	result, err = tx.Exec(ctx, `
+		INSERT INTO entity_relations (tenant_id, workspace_id, source_id, target_id, relation_type, meta, created_at)
+		SELECT r.tenant_id, $3, s.new_id, t.new_id, r.relation_type, r.meta, r.created_at
+		FROM entity_relations r
+		JOIN cloned_entities s ON s.old_id = r.source_id
+		JOIN cloned_entities t ON t.old_id = r.target_id
+		WHERE r.tenant_id = $1 AND r.workspace_id = $2
+	`, db.tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("copy relations: %w", err)
+	}
+	counts.Relations = result.RowsAffected()
+
+	result, err = tx.Exec(ctx, `
+		INSERT INTO memory_links (tenant_id, workspace_id, source_id, target_id, link_type, meta, created_at)
+		SELECT l.tenant_id, $3, s.new_id, t.new_id, l.link_type, l.meta, l.created_at
+		FROM memory_links l
+		JOIN cloned_memories s ON s.old_id = l.source_id
+		JOIN cloned_memories t ON t.old_id = l.target_id
+		WHERE l.tenant_id = $1 AND l.workspace_id = $2
+	`, db.tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("copy links: %w", err)
+	}
+	counts.Links = result.RowsAffected()
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO todos (memory_id, tenant_id, workspace_id, status, due_at, assignee_id, priority, completed_at, created_at, updated_at)
+		SELECT cm.new_id, t.tenant_id, $2, t.status, t.due_at, ce.new_id, t.priority, t.completed_at, t.created_at, t.updated_at
+		FROM todos t
+		JOIN cloned_memories cm ON cm.old_id = t.memory_id
+		LEFT JOIN cloned_entities ce ON ce.old_id = t.assignee_id
+		WHERE t.tenant_id = $1
+	`, db.tenantID, to); err != nil {
+		return nil, fmt.Errorf("copy todos: %w", err)
+	}
+
+	return counts, nil
+}
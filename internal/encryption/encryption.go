@@ -0,0 +1,219 @@
+// Package encryption provides envelope encryption of memory text and meta at
+// rest. Each value is encrypted with its own data key, which is stored with it
+// wrapped by a key from a local keyfile, so the database alone cannot read it.
+package encryption
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefix marks a sealed value: prefix<key id>:<wrapped data key>:<ciphertext>,
+// both base64 encoded with their nonces prepended.
+const prefix = "cortex:enc:v1:"
+
+// keySize is the size of keyfile keys and data keys (AES-256).
+const keySize = 32
+
+// key is a keyfile entry.
+type key struct {
+	id    string
+	kek   cipher.AEAD // wraps data keys
+	index []byte      // HMAC key of the blind index
+}
+
+// Keyring holds the keys of a keyfile. The first key seals new values; the
+// others only open values sealed before a rotation.
+type Keyring struct {
+	keys []key
+	byID map[string]*key
+}
+
+// LoadKeyfile reads a keyfile: one key per line as "<id> <base64 key>", the
+// active key first. Blank lines and lines starting with # are ignored.
+func LoadKeyfile(path string) (*Keyring, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open keyfile: %w", err)
+	}
+	defer f.Close()
+
+	k := &Keyring{byID: make(map[string]*key)}
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("keyfile line %d: expected \"<id> <base64 key>\"", line)
+		}
+		id := fields[0]
+		if strings.Contains(id, ":") {
+			return nil, fmt.Errorf("keyfile line %d: key id %q must not contain ':'", line, id)
+		}
+		if _, ok := k.byID[id]; ok {
+			return nil, fmt.Errorf("keyfile line %d: duplicate key id %q", line, id)
+		}
+		secret, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(secret) != keySize {
+			return nil, fmt.Errorf("keyfile line %d: key must be %d bytes of base64", line, keySize)
+		}
+		entry, err := newKey(id, secret)
+		if err != nil {
+			return nil, err
+		}
+		k.keys = append(k.keys, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read keyfile: %w", err)
+	}
+	if len(k.keys) == 0 {
+		return nil, fmt.Errorf("keyfile %s has no keys", path)
+	}
+	for i := range k.keys {
+		k.byID[k.keys[i].id] = &k.keys[i]
+	}
+	return k, nil
+}
+
+func newKey(id string, secret []byte) (key, error) {
+	kek, err := newAEAD(secret)
+	if err != nil {
+		return key{}, err
+	}
+	// The blind index key is derived, so a keyfile entry is a single secret
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("cortex blind index"))
+	return key{id: id, kek: kek, index: mac.Sum(nil)}, nil
+}
+
+// GenerateKey returns a keyfile line with a new random key.
+func GenerateKey() (string, error) {
+	secret := make([]byte, keySize)
+	idBytes := make([]byte, 4)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generate key: %w", err)
+	}
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", fmt.Errorf("generate key id: %w", err)
+	}
+	return hex.EncodeToString(idBytes) + " " + base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// ActiveKey returns the ID of the key that seals new values.
+func (k *Keyring) ActiveKey() string {
+	return k.keys[0].id
+}
+
+// IsSealed reports whether a stored value was sealed by a Keyring.
+func IsSealed(s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+// KeyID returns the ID of the key a sealed value was sealed with.
+func KeyID(s string) string {
+	id, _, _ := strings.Cut(strings.TrimPrefix(s, prefix), ":")
+	return id
+}
+
+// Seal encrypts plaintext with a new data key, wrapped by the active key.
+// The same context must be given to Open; it binds the value to where it is
+// stored, such as the tenant and column.
+func (k *Keyring) Seal(plaintext []byte, context string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("generate data key: %w", err)
+	}
+	active := &k.keys[0]
+	wrapped, err := seal(active.kek, dataKey, []byte(active.id))
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(data, plaintext, []byte(context))
+	if err != nil {
+		return "", err
+	}
+	return prefix + active.id + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Open decrypts a value returned by Seal with the same context.
+func (k *Keyring) Open(sealed, context string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(sealed, prefix), ":")
+	if !IsSealed(sealed) || len(parts) != 3 {
+		return nil, fmt.Errorf("not a sealed value")
+	}
+	entry, ok := k.byID[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("value is sealed with key %q, which is not in the keyfile", parts[0])
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode data key: %w", err)
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode ciphertext: %w", err)
+	}
+	dataKey, err := open(entry.kek, wrapped, []byte(entry.id))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key with key %q: %w", entry.id, err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(data, ciphertext, []byte(context))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// seal encrypts plaintext with aead, prepending a random nonce.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// open decrypts a value returned by seal.
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additional)
+}
@@ -0,0 +1,62 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// minTokenLength drops one-character tokens, which match too much to be useful.
+const minTokenLength = 2
+
+// hashLength is the number of bytes of HMAC kept per token. Collisions at
+// this length are negligible and only cause extra search results.
+const hashLength = 12
+
+// Tokens splits text into the distinct lowercase words the blind index holds.
+func Tokens(text string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(t)) >= minTokenLength && !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// BlindIndex returns the keyed hashes of text's tokens under the active key.
+// Stored alongside sealed text, they let exact words be searched for without
+// revealing them.
+func (k *Keyring) BlindIndex(text string) []string {
+	tokens := Tokens(text)
+	hashes := make([]string, len(tokens))
+	for i, t := range tokens {
+		hashes[i] = indexHash(k.keys[0].index, t)
+	}
+	return hashes
+}
+
+// QueryIndex returns the hashes of a query's tokens under every key, so that
+// values indexed before a rotation still match, and the number of tokens.
+func (k *Keyring) QueryIndex(query string) ([]string, int) {
+	tokens := Tokens(query)
+	hashes := make([]string, 0, len(tokens)*len(k.keys))
+	for _, entry := range k.keys {
+		for _, t := range tokens {
+			hashes = append(hashes, indexHash(entry.index, t))
+		}
+	}
+	return hashes, len(tokens)
+}
+
+func indexHash(indexKey []byte, token string) string {
+	mac := hmac.New(sha256.New, indexKey)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil)[:hashLength])
+}
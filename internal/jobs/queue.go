@@ -0,0 +1,295 @@
+// Package jobs provides a Postgres-backed background job queue with retries.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Job kinds used by Cortex for post-write processing of memories.
+const (
+	KindEmbed           = "embed"
+	KindExtractEntities = "extract_entities"
+)
+
+// Job statuses. Completed jobs are deleted rather than kept.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDead    = "dead"
+)
+
+// DefaultMaxAttempts is the number of attempts before a job is dead-lettered.
+const DefaultMaxAttempts = 5
+
+// Job represents a queued unit of work.
+type Job struct {
+	ID          int64          `json:"id"`
+	TenantID    string         `json:"tenant_id"`
+	WorkspaceID string         `json:"workspace_id"`
+	Kind        string         `json:"kind"`
+	MemoryID    *int64         `json:"memory_id,omitempty"`
+	Payload     map[string]any `json:"payload,omitempty"`
+	Status      string         `json:"status"`
+	Attempts    int            `json:"attempts"`
+	MaxAttempts int            `json:"max_attempts"`
+	RunAt       time.Time      `json:"run_at"`
+	LastError   *string        `json:"last_error,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Queue stores jobs for a single tenant/workspace in the jobs table. A queue
+// created by NewTenantQueue spans every workspace of its tenant.
+type Queue struct {
+	pool        *pgxpool.Pool
+	tenantID    string
+	workspaceID string
+}
+
+// NewQueue creates a new Queue instance.
+func NewQueue(pool *pgxpool.Pool, tenantID, workspaceID string) *Queue {
+	return &Queue{
+		pool:        pool,
+		tenantID:    tenantID,
+		workspaceID: workspaceID,
+	}
+}
+
+// NewTenantQueue creates a Queue that claims and requeues the jobs of every
+// workspace of a tenant, so a single worker can run them all. Jobs cannot be
+// enqueued on it, since they belong to a workspace.
+func NewTenantQueue(pool *pgxpool.Pool, tenantID string) *Queue {
+	return &Queue{
+		pool:     pool,
+		tenantID: tenantID,
+	}
+}
+
+// Enqueue adds a job for the given memory. If a pending job of the same kind
+// already exists for the memory, no new job is created.
+func (q *Queue) Enqueue(ctx context.Context, kind string, memoryID int64, payload map[string]any) error {
+	if q.workspaceID == "" {
+		return fmt.Errorf("enqueue job: queue spans every workspace")
+	}
+	if payload == nil {
+		payload = map[string]any{}
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	err = pgx.BeginFunc(ctx, q.pool, func(tx pgx.Tx) error {
+		if err := lockJobKey(ctx, tx, kind, &memoryID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO jobs (tenant_id, workspace_id, kind, memory_id, payload, max_attempts)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (kind, memory_id) WHERE status = 'pending' DO NOTHING
+		`, q.tenantID, q.workspaceID, kind, memoryID, payloadJSON, DefaultMaxAttempts)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("enqueue job: %w", err)
+	}
+
+	return nil
+}
+
+// lockJobKey holds a transaction lock on the pending job slot of a kind and
+// memory. Only one job per kind and memory may be pending, so every change
+// that can make a job pending takes it before checking for one.
+func lockJobKey(ctx context.Context, tx pgx.Tx, kind string, memoryID *int64) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('cortex.jobs'), hashtext($1 || ':' || $2::text))`, kind, memoryID)
+	if err != nil {
+		return fmt.Errorf("lock job: %w", err)
+	}
+	return nil
+}
+
+// release moves a claimed job to status: pending to run at runAt, or dead.
+// A job superseded by a newer pending job for the same kind and memory is
+// deleted instead, since only one may be pending. Returns true if the job was
+// deleted.
+func release(ctx context.Context, tx pgx.Tx, job *Job, status string, runAt time.Time, lastError *string) (bool, error) {
+	if err := lockJobKey(ctx, tx, job.Kind, job.MemoryID); err != nil {
+		return false, err
+	}
+
+	superseded, err := tx.Exec(ctx, `
+		DELETE FROM jobs j
+		WHERE j.id = $1 AND EXISTS (
+			SELECT 1 FROM jobs p
+			WHERE p.status = 'pending' AND p.kind = j.kind AND p.memory_id = j.memory_id
+		)
+	`, job.ID)
+	if err != nil {
+		return false, err
+	}
+	if superseded.RowsAffected() > 0 {
+		return true, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE jobs SET
+			status = $2,
+			run_at = $3,
+			last_error = COALESCE($4, last_error),
+			locked_at = NULL,
+			updated_at = now()
+		WHERE id = $1
+	`, job.ID, status, runAt, lastError)
+	return false, err
+}
+
+// Claim locks the next runnable job and marks it running.
+// Returns nil if no job is ready.
+func (q *Queue) Claim(ctx context.Context) (*Job, error) {
+	var j Job
+	var payloadJSON []byte
+
+	err := q.pool.QueryRow(ctx, `
+		UPDATE jobs SET
+			status = 'running',
+			attempts = attempts + 1,
+			locked_at = now(),
+			updated_at = now()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE tenant_id = $1 AND ($2 = '' OR workspace_id = $2)
+			  AND status = 'pending' AND run_at <= now()
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, tenant_id, workspace_id, kind, memory_id, payload, status,
+			attempts, max_attempts, run_at, last_error, created_at
+	`, q.tenantID, q.workspaceID).Scan(
+		&j.ID, &j.TenantID, &j.WorkspaceID, &j.Kind, &j.MemoryID, &payloadJSON, &j.Status,
+		&j.Attempts, &j.MaxAttempts, &j.RunAt, &j.LastError, &j.CreatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim job: %w", err)
+	}
+
+	if len(payloadJSON) > 0 {
+		if err := json.Unmarshal(payloadJSON, &j.Payload); err != nil {
+			return nil, fmt.Errorf("unmarshal payload: %w", err)
+		}
+	}
+
+	return &j, nil
+}
+
+// Complete removes a finished job from the queue.
+func (q *Queue) Complete(ctx context.Context, jobID int64) error {
+	_, err := q.pool.Exec(ctx, `DELETE FROM jobs WHERE id = $1`, jobID)
+	if err != nil {
+		return fmt.Errorf("complete job: %w", err)
+	}
+	return nil
+}
+
+// Fail records a failed attempt. The job is rescheduled with exponential backoff,
+// or marked dead once it has used all of its attempts.
+// Returns true if the job was dead-lettered.
+func (q *Queue) Fail(ctx context.Context, job *Job, jobErr error) (bool, error) {
+	dead := job.Attempts >= job.MaxAttempts
+	status := StatusPending
+	if dead {
+		status = StatusDead
+	}
+	lastError := jobErr.Error()
+
+	var superseded bool
+	err := pgx.BeginFunc(ctx, q.pool, func(tx pgx.Tx) error {
+		var err error
+		superseded, err = release(ctx, tx, job, status, time.Now().Add(Backoff(job.Attempts)), &lastError)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("fail job: %w", err)
+	}
+
+	return dead && !superseded, nil
+}
+
+// RequeueStale returns running jobs that have been locked for longer than timeout
+// (e.g., because their worker crashed) to the pending state. A stale job whose
+// memory already has a pending job of its kind is deleted instead.
+func (q *Queue) RequeueStale(ctx context.Context, timeout time.Duration) (int64, error) {
+	rows, err := q.pool.Query(ctx, `
+		SELECT id, kind, memory_id FROM jobs
+		WHERE tenant_id = $1 AND ($2 = '' OR workspace_id = $2)
+		  AND status = 'running'
+		  AND locked_at < now() - $3 * INTERVAL '1 second'
+		ORDER BY id
+	`, q.tenantID, q.workspaceID, timeout.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("requeue stale jobs: %w", err)
+	}
+	stale, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Job, error) {
+		var j Job
+		err := row.Scan(&j.ID, &j.Kind, &j.MemoryID)
+		return j, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("requeue stale jobs: %w", err)
+	}
+
+	var requeued int64
+	for _, job := range stale {
+		err := pgx.BeginFunc(ctx, q.pool, func(tx pgx.Tx) error {
+			if err := lockJobKey(ctx, tx, job.Kind, job.MemoryID); err != nil {
+				return err
+			}
+			// Skip jobs a worker finished since they were listed
+			var running bool
+			if err := tx.QueryRow(ctx, `
+				SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $1 AND status = 'running')
+			`, job.ID).Scan(&running); err != nil || !running {
+				return err
+			}
+			deleted, err := release(ctx, tx, &job, StatusPending, time.Now(), nil)
+			if err == nil && !deleted {
+				requeued++
+			}
+			return err
+		})
+		if err != nil {
+			return requeued, fmt.Errorf("requeue stale job %d: %w", job.ID, err)
+		}
+	}
+	return requeued, nil
+}
+
+// Backoff returns the delay before retrying a job after the given number of attempts:
+// 5s, 10s, 20s, ... capped at 10 minutes.
+func Backoff(attempts int) time.Duration {
+	const (
+		base     = 5 * time.Second
+		maxDelay = 10 * time.Minute
+	)
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,219 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// HandlerFunc processes a single job. Returning an error schedules a retry.
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+// WorkerConfig holds configuration for the worker pool.
+type WorkerConfig struct {
+	// Concurrency is the number of worker goroutines.
+	Concurrency int
+	// PollInterval is how long an idle worker waits before checking for new jobs.
+	PollInterval time.Duration
+	// StaleTimeout is how long a job may stay running before it is returned to the queue.
+	StaleTimeout time.Duration
+	// StaleCheckInterval is how often running jobs are checked against StaleTimeout.
+	StaleCheckInterval time.Duration
+}
+
+// finishTimeout bounds recording a job's outcome, which still happens after
+// the worker's context is cancelled so the job is not left running.
+const finishTimeout = 10 * time.Second
+
+// DefaultWorkerConfig returns a sensible default configuration.
+func DefaultWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		Concurrency:        2,
+		PollInterval:       time.Second,
+		StaleTimeout:       10 * time.Minute,
+		StaleCheckInterval: time.Minute,
+	}
+}
+
+// Worker runs queued jobs on a pool of goroutines.
+type Worker struct {
+	queue    *Queue
+	config   WorkerConfig
+	handlers map[string]HandlerFunc
+
+	mu      sync.Mutex
+	running bool
+	wg      sync.WaitGroup
+}
+
+// NewWorker creates a new worker pool for the given queue.
+func NewWorker(queue *Queue) *Worker {
+	return &Worker{
+		queue:    queue,
+		config:   DefaultWorkerConfig(),
+		handlers: make(map[string]HandlerFunc),
+	}
+}
+
+// WithConfig sets the configuration and returns the Worker for chaining.
+func (w *Worker) WithConfig(cfg WorkerConfig) *Worker {
+	w.config = cfg
+	return w
+}
+
+// Register registers the handler for a job kind. Must be called before Start.
+func (w *Worker) Register(kind string, handler HandlerFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[kind] = handler
+}
+
+// Start launches the worker goroutines. They run until ctx is cancelled.
+func (w *Worker) Start(ctx context.Context) {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		log.Printf("[jobs] already running")
+		return
+	}
+	w.running = true
+	w.mu.Unlock()
+
+	concurrency := w.config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Recover jobs left running by a crashed process, now and periodically
+	w.requeueStale(ctx)
+	w.wg.Add(1)
+	go w.staleLoop(ctx)
+
+	for i := 0; i < concurrency; i++ {
+		w.wg.Add(1)
+		go w.loop(ctx)
+	}
+
+	log.Printf("[jobs] started %d workers", concurrency)
+}
+
+// Stop waits for the worker goroutines to finish their current jobs.
+// The context passed to Start must be cancelled first.
+func (w *Worker) Stop() {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+
+	w.mu.Lock()
+	w.running = false
+	w.mu.Unlock()
+	log.Printf("[jobs] stopped")
+}
+
+// staleLoop returns stale jobs to the queue every StaleCheckInterval.
+func (w *Worker) staleLoop(ctx context.Context) {
+	defer w.wg.Done()
+
+	interval := w.config.StaleCheckInterval
+	if interval <= 0 {
+		interval = DefaultWorkerConfig().StaleCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.requeueStale(ctx)
+		}
+	}
+}
+
+// requeueStale returns jobs running for longer than StaleTimeout to the queue.
+func (w *Worker) requeueStale(ctx context.Context) {
+	if n, err := w.queue.RequeueStale(ctx, w.config.StaleTimeout); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[jobs] error requeueing stale jobs: %v", err)
+		}
+	} else if n > 0 {
+		log.Printf("[jobs] requeued %d stale jobs", n)
+	}
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		worked, err := w.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("[jobs] error: %v", err)
+		}
+		if worked {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.config.PollInterval):
+		}
+	}
+}
+
+// RunOnce claims and runs a single job.
+// Returns false if no job was ready.
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	job, err := w.queue.Claim(ctx)
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	w.mu.Lock()
+	handler, ok := w.handlers[job.Kind]
+	w.mu.Unlock()
+
+	var jobErr error
+	if !ok {
+		jobErr = fmt.Errorf("no handler for job kind %q", job.Kind)
+	} else {
+		jobErr = handler(ctx, job)
+	}
+
+	// Record the outcome even if ctx was cancelled meanwhile (shutdown)
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+	defer cancel()
+
+	if jobErr == nil {
+		return true, w.queue.Complete(finishCtx, job.ID)
+	}
+
+	dead, err := w.queue.Fail(finishCtx, job, jobErr)
+	if err != nil {
+		return true, err
+	}
+	if dead {
+		log.Printf("[jobs] job %d (%s) failed permanently after %d attempts: %v", job.ID, job.Kind, job.Attempts, jobErr)
+	} else {
+		log.Printf("[jobs] job %d (%s) attempt %d failed, will retry: %v", job.ID, job.Kind, job.Attempts, jobErr)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultAnthropicChatModel is the default chat model for Anthropic.
+	DefaultAnthropicChatModel = "claude-3-5-haiku-latest"
+	// anthropicBaseURL is the address of the Anthropic API.
+	anthropicBaseURL = "https://api.anthropic.com"
+	// anthropicVersion is the version of the Messages API requests are written for.
+	anthropicVersion = "2023-06-01"
+	// anthropicMaxTokens bounds completions, which must state a limit. It
+	// leaves room for the JSON of entity and memory extraction.
+	anthropicMaxTokens = 4096
+)
+
+// AnthropicProvider implements ChatProvider using the Anthropic Messages API.
+// Anthropic serves no embeddings, so it is paired with an embedding backend.
+type AnthropicProvider struct {
+	client    *http.Client
+	apiKey    string
+	chatModel string
+}
+
+// NewAnthropicProvider creates a new Anthropic chat provider.
+func NewAnthropicProvider(apiKey, chatModel string) (*AnthropicProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("anthropic: API key is required")
+	}
+
+	if chatModel == "" || chatModel == "auto" {
+		chatModel = DefaultAnthropicChatModel
+	}
+
+	return &AnthropicProvider{
+		client:    &http.Client{},
+		apiKey:    apiKey,
+		chatModel: chatModel,
+	}, nil
+}
+
+// Model returns the name of the chat model being used.
+func (p *AnthropicProvider) Model() string {
+	return p.chatModel
+}
+
+// Complete generates a text completion for the given prompt.
+func (p *AnthropicProvider) Complete(ctx context.Context, prompt string) (string, error) {
+	payload, err := json.Marshal(map[string]any{
+		"model":      p.chatModel,
+		"max_tokens": anthropicMaxTokens,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("anthropic complete: marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicBaseURL+"/v1/messages", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("anthropic complete: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", p.apiKey)
+	req.Header.Set("Anthropic-Version", anthropicVersion)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("anthropic complete: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("anthropic complete: read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Errors are reported as {"type": "error", "error": {"type": ..., "message": ...}}
+		var apiErr struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return "", fmt.Errorf("anthropic complete: %s: %s: %s", resp.Status, apiErr.Error.Type, apiErr.Error.Message)
+		}
+		return "", fmt.Errorf("anthropic complete: %s", resp.Status)
+	}
+
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("anthropic complete: decode response: %w", err)
+	}
+
+	// Concatenate all text blocks
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("anthropic complete: no text in response")
+	}
+
+	return text.String(), nil
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// DefaultCompatibleBaseURL is the default base URL of an OpenAI-compatible
+// server, the address of a local llama.cpp server.
+const DefaultCompatibleBaseURL = "http://localhost:8080/v1"
+
+// CompatibleProvider implements Provider using a server that serves the OpenAI
+// API, such as llama.cpp, vLLM or LM Studio. Model names are whatever the
+// server serves, and embedding dimensions are discovered from its responses.
+type CompatibleProvider struct {
+	client     *openai.Client
+	chatModel  string
+	embedModel string
+	embedDims  discoveredDims
+}
+
+// NewCompatibleProvider creates a provider for the OpenAI-compatible server at
+// baseURL. apiKey may be empty for servers that do not require one. Servers
+// often serve only one kind of model, so either model may be empty; Embed or
+// Complete then return an error.
+func NewCompatibleProvider(baseURL, apiKey, chatModel, embedModel string) (*CompatibleProvider, error) {
+	if baseURL == "" {
+		baseURL = DefaultCompatibleBaseURL
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("openai-compatible: invalid base URL %q", baseURL)
+	}
+	// Request paths are resolved relative to the base URL
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	// Served model names cannot be guessed
+	if chatModel == "auto" {
+		chatModel = ""
+	}
+	if embedModel == "auto" {
+		embedModel = ""
+	}
+
+	// The API key is always set so OPENAI_API_KEY is never sent to the server
+	client := openai.NewClient(option.WithBaseURL(baseURL), option.WithAPIKey(apiKey))
+
+	return &CompatibleProvider{
+		client:     client,
+		chatModel:  chatModel,
+		embedModel: embedModel,
+	}, nil
+}
+
+// Embed generates a vector embedding for the given text.
+func (p *CompatibleProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	if p.embedModel == "" {
+		return nil, fmt.Errorf("openai-compatible embed: no embedding model configured")
+	}
+	resp, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: openai.F(p.embedModel),
+		Input: openai.F(openai.EmbeddingNewParamsInputUnion(openai.EmbeddingNewParamsInputArrayOfStrings{text})),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai-compatible embed: %w", err)
+	}
+
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("openai-compatible embed: no embedding returned")
+	}
+
+	embedding := make([]float32, len(resp.Data[0].Embedding))
+	for i, v := range resp.Data[0].Embedding {
+		embedding[i] = float32(v)
+	}
+	p.embedDims.record(embedding)
+
+	return embedding, nil
+}
+
+// Model returns the name of the chat model being used.
+func (p *CompatibleProvider) Model() string {
+	return p.chatModel
+}
+
+// Dimensions returns the dimensionality of the embeddings, discovered from the
+// server. It returns 0 if the server cannot be reached.
+func (p *CompatibleProvider) Dimensions() int {
+	return p.embedDims.get(p.Embed)
+}
+
+// Complete generates a text completion for the given prompt.
+func (p *CompatibleProvider) Complete(ctx context.Context, prompt string) (string, error) {
+	if p.chatModel == "" {
+		return "", fmt.Errorf("openai-compatible complete: no chat model configured")
+	}
+	resp, err := p.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: openai.F(p.chatModel),
+		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(prompt),
+		}),
+	})
+	if err != nil {
+		return "", fmt.Errorf("openai-compatible complete: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("openai-compatible complete: no completion returned")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// EmbedModel returns the embedding model name.
+func (p *CompatibleProvider) EmbedModel() string {
+	return p.embedModel
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// dimensionProbeTimeout bounds the embedding request that discovers the
+// dimensions of a model served locally, which may first have to be loaded.
+const dimensionProbeTimeout = 2 * time.Minute
+
+// discoveredDims holds the dimensionality of a model whose dimensions are not
+// known ahead of time. It is learned from the first embedding returned.
+type discoveredDims struct {
+	mu   sync.Mutex
+	dims int
+}
+
+// record notes the dimensionality of an embedding returned by the model.
+func (d *discoveredDims) record(embedding []float32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dims == 0 {
+		d.dims = len(embedding)
+	}
+}
+
+// get returns the dimensionality, embedding a probe text to discover it if no
+// embedding has been returned yet. It returns 0 if the model cannot be reached.
+func (d *discoveredDims) get(embed func(ctx context.Context, text string) ([]float32, error)) int {
+	d.mu.Lock()
+	dims := d.dims
+	d.mu.Unlock()
+	if dims > 0 {
+		return dims
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dimensionProbeTimeout)
+	defer cancel()
+	embedding, err := embed(ctx, "dimension probe")
+	if err != nil {
+		return 0
+	}
+	d.record(embedding)
+	return len(embedding)
+}
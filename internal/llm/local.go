@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultLocalDimensions is the default dimensionality of local embeddings.
+	DefaultLocalDimensions = 512
+	// MinLocalDimensions and MaxLocalDimensions bound the dimensionality of
+	// local embeddings. pgvector indexes at most 2000 dimensions.
+	MinLocalDimensions = 16
+	MaxLocalDimensions = 2000
+
+	// localModelPrefix starts the names of local embedding models, which end
+	// in their dimensionality.
+	localModelPrefix = "local-ngram-"
+)
+
+// Weights of the features of a text. Words carry the most weight; word pairs
+// reward matching phrases, and character trigrams match inflections and typos.
+const (
+	wordWeight    = 1.0
+	bigramWeight  = 0.5
+	trigramWeight = 0.25
+)
+
+// LocalEmbedModel returns the name of the local embedding model with the
+// given dimensionality.
+func LocalEmbedModel(dims int) string {
+	return localModelPrefix + strconv.Itoa(dims)
+}
+
+// LocalEmbedder implements EmbeddingProvider without a model server. It
+// hashes the words, word pairs and character trigrams of a text into a vector
+// of fixed dimensionality (feature hashing), so texts sharing vocabulary are
+// similar. Embeddings are deterministic and need no network, which suits
+// tests and air-gapped machines, but capture no meaning beyond shared words.
+type LocalEmbedder struct {
+	dims int
+}
+
+// NewLocalEmbedder creates a local embedder for a model named by
+// LocalEmbedModel, or of DefaultLocalDimensions for "" or "auto".
+func NewLocalEmbedder(model string) (*LocalEmbedder, error) {
+	if model == "" || model == "auto" {
+		return &LocalEmbedder{dims: DefaultLocalDimensions}, nil
+	}
+	suffix, ok := strings.CutPrefix(model, localModelPrefix)
+	dims, err := strconv.Atoi(suffix)
+	if !ok || err != nil {
+		return nil, fmt.Errorf("local: model %q must be named %s<dimensions>", model, localModelPrefix)
+	}
+	if dims < MinLocalDimensions || dims > MaxLocalDimensions {
+		return nil, fmt.Errorf("local: dimensions must be between %d and %d, got %d", MinLocalDimensions, MaxLocalDimensions, dims)
+	}
+	return &LocalEmbedder{dims: dims}, nil
+}
+
+// Embed generates a vector embedding for the given text.
+func (e *LocalEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vec := make([]float64, e.dims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		e.add(vec, "w:"+word, wordWeight)
+		if i > 0 {
+			e.add(vec, "b:"+words[i-1]+" "+word, bigramWeight)
+		}
+		padded := []rune("<" + word + ">")
+		for j := 0; j+3 <= len(padded); j++ {
+			e.add(vec, "c:"+string(padded[j:j+3]), trigramWeight)
+		}
+	}
+	if len(words) == 0 {
+		// Cosine distance is undefined for the zero vector
+		e.add(vec, "t:"+strings.TrimSpace(text), wordWeight)
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	embedding := make([]float32, e.dims)
+	for i, v := range vec {
+		if norm > 0 {
+			embedding[i] = float32(v / norm)
+		}
+	}
+	if norm == 0 {
+		// Every feature cancelled out
+		embedding[0] = 1
+	}
+	return embedding, nil
+}
+
+// add hashes a feature into vec. A bit of the hash picks the sign, so
+// features colliding in a dimension cancel out rather than pile up.
+func (e *LocalEmbedder) add(vec []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vec[sum%uint64(e.dims)] += weight
+}
+
+// Model returns the name of the embedding model.
+func (e *LocalEmbedder) Model() string {
+	return LocalEmbedModel(e.dims)
+}
+
+// Dimensions returns the dimensionality of the embeddings.
+func (e *LocalEmbedder) Dimensions() int {
+	return e.dims
+}
+
+// noChat is the ChatProvider of a backend without a chat model.
+type noChat struct {
+	backend string
+}
+
+func (n noChat) Complete(ctx context.Context, prompt string) (string, error) {
+	return "", fmt.Errorf("the %s backend has no chat model: select a chat backend", n.backend)
+}
+
+func (n noChat) Model() string {
+	return "none"
+}
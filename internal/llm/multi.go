@@ -17,7 +17,10 @@ type MultiEmbedder struct {
 // Format: "model1,model2,model3" - first model is the primary.
 // For OpenAI: "text-embedding-3-small,text-embedding-3-large"
 // For Gemini: "gemini-embedding-exp-03-07"
-func NewMultiEmbedder(backend, apiKey, modelList string) (*MultiEmbedder, error) {
+// For Ollama: "nomic-embed-text,mxbai-embed-large"
+// For local: "local-ngram-256,local-ngram-1024"
+// baseURL is the server of a local backend (empty for its default).
+func NewMultiEmbedder(backend, apiKey, baseURL, modelList string) (*MultiEmbedder, error) {
 	if modelList == "" || modelList == "auto" {
 		// Default to single model based on backend
 		switch backend {
@@ -25,6 +28,10 @@ func NewMultiEmbedder(backend, apiKey, modelList string) (*MultiEmbedder, error)
 			modelList = DefaultOpenAIEmbedModel
 		case "gemini":
 			modelList = DefaultGeminiEmbedModel
+		case "ollama":
+			modelList = DefaultOllamaEmbedModel
+		case "local":
+			modelList = LocalEmbedModel(DefaultLocalDimensions)
 		default:
 			return nil, fmt.Errorf("unsupported backend: %s", backend)
 		}
@@ -40,18 +47,7 @@ func NewMultiEmbedder(backend, apiKey, modelList string) (*MultiEmbedder, error)
 			continue
 		}
 
-		var provider EmbeddingProvider
-		var err error
-
-		switch backend {
-		case "openai":
-			provider, err = NewOpenAIProvider(apiKey, "", model)
-		case "gemini":
-			provider, err = NewGeminiProvider(apiKey, "", model)
-		default:
-			return nil, fmt.Errorf("unsupported backend: %s", backend)
-		}
-
+		provider, err := NewEmbeddingProvider(backend, apiKey, baseURL, model)
 		if err != nil {
 			return nil, fmt.Errorf("create provider for model %s: %w", model, err)
 		}
@@ -118,6 +114,15 @@ func (m *MultiEmbedder) EmbedAll(ctx context.Context, text string) (map[string][
 	return results, nil
 }
 
+// Provider returns the embedding provider for a specific model.
+func (m *MultiEmbedder) Provider(model string) (EmbeddingProvider, error) {
+	provider, ok := m.providers[model]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding model: %s", model)
+	}
+	return provider, nil
+}
+
 // Dimensions returns the dimensionality of the primary model's embeddings.
 func (m *MultiEmbedder) Dimensions() int {
 	return m.providers[m.primary].Dimensions()
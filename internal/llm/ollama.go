@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// DefaultOllamaBaseURL is the address Ollama listens on by default.
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	// DefaultOllamaChatModel is the default chat model for Ollama.
+	DefaultOllamaChatModel = "llama3.2"
+	// DefaultOllamaEmbedModel is the default embedding model for Ollama.
+	DefaultOllamaEmbedModel = "nomic-embed-text"
+)
+
+// OllamaProvider implements Provider using the API of a local Ollama server.
+// Embedding dimensions are discovered from its responses.
+type OllamaProvider struct {
+	client     *http.Client
+	baseURL    string
+	chatModel  string
+	embedModel string
+	embedDims  discoveredDims
+}
+
+// NewOllamaProvider creates a provider for the Ollama server at baseURL.
+func NewOllamaProvider(baseURL, chatModel, embedModel string) (*OllamaProvider, error) {
+	if baseURL == "" {
+		baseURL = DefaultOllamaBaseURL
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("ollama: invalid base URL %q", baseURL)
+	}
+
+	if chatModel == "" || chatModel == "auto" {
+		chatModel = DefaultOllamaChatModel
+	}
+	if embedModel == "" || embedModel == "auto" {
+		embedModel = DefaultOllamaEmbedModel
+	}
+
+	return &OllamaProvider{
+		client:     &http.Client{},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		chatModel:  chatModel,
+		embedModel: embedModel,
+	}, nil
+}
+
+// Embed generates a vector embedding for the given text.
+func (p *OllamaProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	err := p.post(ctx, "/api/embed", map[string]any{
+		"model": p.embedModel,
+		"input": text,
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("ollama embed: %w", err)
+	}
+
+	if len(resp.Embeddings) == 0 || len(resp.Embeddings[0]) == 0 {
+		return nil, fmt.Errorf("ollama embed: no embedding returned")
+	}
+	p.embedDims.record(resp.Embeddings[0])
+
+	return resp.Embeddings[0], nil
+}
+
+// Model returns the name of the chat model being used.
+func (p *OllamaProvider) Model() string {
+	return p.chatModel
+}
+
+// Dimensions returns the dimensionality of the embeddings, discovered from the
+// server. It returns 0 if the server cannot be reached.
+func (p *OllamaProvider) Dimensions() int {
+	return p.embedDims.get(p.Embed)
+}
+
+// Complete generates a text completion for the given prompt.
+func (p *OllamaProvider) Complete(ctx context.Context, prompt string) (string, error) {
+	var resp struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}
+	err := p.post(ctx, "/api/chat", map[string]any{
+		"model": p.chatModel,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"stream": false,
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("ollama complete: %w", err)
+	}
+
+	if resp.Message.Content == "" {
+		return "", fmt.Errorf("ollama complete: no completion returned")
+	}
+
+	return resp.Message.Content, nil
+}
+
+// EmbedModel returns the embedding model name.
+func (p *OllamaProvider) EmbedModel() string {
+	return p.embedModel
+}
+
+// post sends a JSON request to the Ollama API and decodes its response into out.
+func (p *OllamaProvider) post(ctx context.Context, path string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Ollama reports errors as {"error": "..."}
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, apiErr.Error)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -60,12 +60,23 @@ func (m *multiProvider) EmbedModel() string {
 	return m.embed.Model()
 }
 
+// embedder presents a Provider as an EmbeddingProvider whose Model is the
+// embedding model.
+type embedder struct {
+	Provider
+}
+
+func (e embedder) Model() string {
+	return e.EmbedModel()
+}
+
 // NewProvider creates a provider based on backend type.
-// backend: "openai" or "gemini"
-// apiKey: the API key for the chosen backend
+// backend: "openai", "gemini", "ollama", "openai-compatible" or "local"
+// apiKey: the API key for the chosen backend (optional for local backends)
+// baseURL: the server of a local backend (empty for its default)
 // chatModel: model for chat (empty for default)
 // embedModel: model for embeddings (empty for default)
-func NewProvider(backend, apiKey, chatModel, embedModel string) (Provider, error) {
+func NewProvider(backend, apiKey, baseURL, chatModel, embedModel string) (Provider, error) {
 	backend = strings.ToLower(strings.TrimSpace(backend))
 
 	switch backend {
@@ -73,7 +84,80 @@ func NewProvider(backend, apiKey, chatModel, embedModel string) (Provider, error
 		return NewOpenAIProvider(apiKey, chatModel, embedModel)
 	case "gemini":
 		return NewGeminiProvider(apiKey, chatModel, embedModel)
+	case "ollama":
+		return NewOllamaProvider(baseURL, chatModel, embedModel)
+	case "openai-compatible":
+		return NewCompatibleProvider(baseURL, apiKey, chatModel, embedModel)
+	case "local":
+		embed, err := NewLocalEmbedder(embedModel)
+		if err != nil {
+			return nil, err
+		}
+		return NewSplitProvider(noChat{backend}, embed), nil
+	case "anthropic":
+		return nil, fmt.Errorf("anthropic serves no embeddings: pair it with an embedding backend using NewSplitProvider")
+	default:
+		return nil, fmt.Errorf("unsupported LLM backend: %q (supported: openai, gemini, ollama, openai-compatible, local)", backend)
+	}
+}
+
+// NewChatProvider creates a chat provider based on backend type.
+// backend: "openai", "gemini", "ollama", "openai-compatible" or "anthropic".
+// The "local" backend has no chat model; its Complete returns an error.
+func NewChatProvider(backend, apiKey, baseURL, model string) (ChatProvider, error) {
+	backend = strings.ToLower(strings.TrimSpace(backend))
+
+	switch backend {
+	case "openai":
+		return NewOpenAIProvider(apiKey, model, "")
+	case "gemini":
+		return NewGeminiProvider(apiKey, model, "")
+	case "ollama":
+		return NewOllamaProvider(baseURL, model, "")
+	case "openai-compatible":
+		return NewCompatibleProvider(baseURL, apiKey, model, "")
+	case "anthropic":
+		return NewAnthropicProvider(apiKey, model)
+	case "local":
+		return noChat{backend}, nil
 	default:
-		return nil, fmt.Errorf("unsupported LLM backend: %q (supported: openai, gemini)", backend)
+		return nil, fmt.Errorf("unsupported chat backend: %q (supported: openai, gemini, ollama, openai-compatible, anthropic)", backend)
 	}
 }
+
+// NewEmbeddingProvider creates an embedding provider based on backend type.
+// backend: "openai", "gemini", "ollama", "openai-compatible" or "local"
+func NewEmbeddingProvider(backend, apiKey, baseURL, model string) (EmbeddingProvider, error) {
+	backend = strings.ToLower(strings.TrimSpace(backend))
+	if backend == "local" {
+		return NewLocalEmbedder(model)
+	}
+
+	var provider Provider
+	var err error
+	switch backend {
+	case "openai":
+		provider, err = NewOpenAIProvider(apiKey, "", model)
+	case "gemini":
+		provider, err = NewGeminiProvider(apiKey, "", model)
+	case "ollama":
+		provider, err = NewOllamaProvider(baseURL, "", model)
+	case "openai-compatible":
+		if model == "" || model == "auto" {
+			return nil, fmt.Errorf("openai-compatible: an embedding model is required")
+		}
+		provider, err = NewCompatibleProvider(baseURL, apiKey, "", model)
+	default:
+		return nil, fmt.Errorf("unsupported embedding backend: %q (supported: openai, gemini, ollama, openai-compatible, local)", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return embedder{provider}, nil
+}
+
+// NewSplitProvider combines a chat provider and an embedding provider, which
+// may use different backends, into a Provider.
+func NewSplitProvider(chat ChatProvider, embed EmbeddingProvider) Provider {
+	return &multiProvider{embed: embed, chat: chat}
+}
@@ -15,11 +15,13 @@ type HealthResponse struct {
 	Status string `json:"status"`
 }
 
-// HealthServer provides an HTTP health check endpoint.
+// HealthServer provides an HTTP health check endpoint, and a metrics
+// endpoint when metrics have been added.
 type HealthServer struct {
 	port     string
 	server   *http.Server
 	listener net.Listener
+	metrics  map[string]func() any
 }
 
 // NewHealthServer creates a new health server on the specified port.
@@ -29,11 +31,23 @@ func NewHealthServer(port string) *HealthServer {
 	}
 }
 
+// AddMetrics reports the value returned by fn under name at /metrics.
+// It must be called before Start.
+func (h *HealthServer) AddMetrics(name string, fn func() any) {
+	if h.metrics == nil {
+		h.metrics = make(map[string]func() any)
+	}
+	h.metrics[name] = fn
+}
+
 // Start starts the health server in a background goroutine.
 // Returns an error if the server fails to start.
 func (h *HealthServer) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", h.handleHealth)
+	if len(h.metrics) > 0 {
+		mux.HandleFunc("/metrics", h.handleMetrics)
+	}
 
 	h.server = &http.Server{
 		Addr:         ":" + h.port,
@@ -80,3 +94,22 @@ func (h *HealthServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 		log.Printf("cortex: failed to encode health response: %v", err)
 	}
 }
+
+func (h *HealthServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := make(map[string]any, len(h.metrics))
+	for name, fn := range h.metrics {
+		resp[name] = fn()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("cortex: failed to encode metrics response: %v", err)
+	}
+}
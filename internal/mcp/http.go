@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxHTTPRequestSize matches the largest request accepted over stdio.
+const maxHTTPRequestSize = 10 * 1024 * 1024
+
+type apiKeyContextKey struct{}
+
+// ContextWithAPIKey returns a context carrying the API key a request was made with.
+func ContextWithAPIKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext returns the API key a request was made with, if any.
+func APIKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// ServeHTTP handles one JSON-RPC message per POST request, authenticated by
+// an "Authorization: Bearer <key>" header. Responses are returned in the
+// response body; notifications and client responses get 202 Accepted. The
+// server cannot send requests of its own (such as roots/list) over HTTP.
+//
+// Initialize starts a session, whose ID is returned in the Mcp-Session-Id
+// header; later requests carrying it are attributed to that client. A
+// request for an unknown session gets 404 Not Found, and DELETE ends one.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ref := &sessionRef{id: r.Header.Get(SessionHeader)}
+	if ref.id != "" && !s.hasSession(ref.id) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		if ref.id == "" {
+			http.Error(w, "Missing "+SessionHeader+" header", http.StatusBadRequest)
+			return
+		}
+		s.endSession(ref.id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", http.MethodPost+", "+http.MethodDelete)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPRequestSize))
+	if err != nil {
+		http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx := contextWithSession(r.Context(), ref)
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		ctx = ContextWithAPIKey(ctx, strings.TrimSpace(key))
+	}
+
+	response := s.handleRequest(ctx, body)
+	if ref.id != "" {
+		w.Header().Set(SessionHeader, ref.id)
+	}
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logError("failed to write response: %v", err)
+	}
+}
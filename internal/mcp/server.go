@@ -13,16 +13,50 @@ import (
 // Handler is a function that handles an MCP method call.
 type Handler func(ctx context.Context, params json.RawMessage) (any, error)
 
+// Authorizer decides whether the caller identified by ctx may call a tool
+// with the given arguments. A non-nil error rejects the call; otherwise the
+// returned context, which may carry the caller's identity, is passed to the
+// tool's handler.
+type Authorizer func(ctx context.Context, tool string, args json.RawMessage) (context.Context, error)
+
+// ToolCall describes the tool call a handler is serving.
+type ToolCall struct {
+	Name      string
+	Arguments json.RawMessage
+	Client    ClientInfo // from the initialize request of the caller's session
+}
+
+type toolCallContextKey struct{}
+
+// ToolCallFromContext returns the tool call a handler's context belongs to.
+func ToolCallFromContext(ctx context.Context) (ToolCall, bool) {
+	call, ok := ctx.Value(toolCallContextKey{}).(ToolCall)
+	return call, ok
+}
+
+// RootsHandler receives the client's roots after initialization and whenever
+// the client reports that they changed.
+type RootsHandler func(ctx context.Context, roots []Root)
+
 // Server is an MCP server that communicates over stdio using JSON-RPC 2.0.
 type Server struct {
 	name    string
 	version string
 
-	tools    []Tool
-	handlers map[string]Handler
+	tools     []Tool
+	handlers  map[string]Handler
+	authorize Authorizer
+
+	mu           sync.RWMutex
+	initialized  bool
+	sessions     map[string]*session // by session ID; stdioSession is the stdio client
+	rootsHandler RootsHandler
 
-	mu          sync.RWMutex
-	initialized bool
+	// Requests sent to the client, awaiting its response
+	nextID  int64
+	pending map[string]func(result json.RawMessage, err *Error)
+
+	writeMu sync.Mutex
 
 	stdin  io.Reader
 	stdout io.Writer
@@ -36,6 +70,8 @@ func NewServer(name, version string) *Server {
 		version:  version,
 		tools:    make([]Tool, 0),
 		handlers: make(map[string]Handler),
+		sessions: make(map[string]*session),
+		pending:  make(map[string]func(json.RawMessage, *Error)),
 		stdin:    os.Stdin,
 		stdout:   os.Stdout,
 		stderr:   os.Stderr,
@@ -58,6 +94,23 @@ func (s *Server) RegisterTool(tool Tool, handler Handler) {
 	s.handlers[tool.Name] = handler
 }
 
+// SetAuthorizer sets the authorizer every tool call must pass.
+func (s *Server) SetAuthorizer(authorize Authorizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.authorize = authorize
+}
+
+// OnRoots sets the handler that receives the client's roots. Roots are only
+// requested from clients that declare the roots capability.
+func (s *Server) OnRoots(handler RootsHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rootsHandler = handler
+}
+
 // Run starts the server and processes requests from stdin until ctx is canceled or EOF.
 func (s *Server) Run(ctx context.Context) error {
 	scanner := bufio.NewScanner(s.stdin)
@@ -114,6 +167,12 @@ func (s *Server) handleRequest(ctx context.Context, data []byte) *Response {
 		}
 	}
 
+	// Messages without a method are responses to requests the server sent
+	if req.Method == "" && req.ID != nil {
+		s.handleResponse(data)
+		return nil
+	}
+
 	// Route the request
 	result, err := s.route(ctx, req.Method, req.Params)
 	if err != nil {
@@ -148,8 +207,11 @@ func (s *Server) route(ctx context.Context, method string, params json.RawMessag
 	switch method {
 	case "initialize":
 		return s.handleInitialize(ctx, params)
-	case "initialized":
+	case "initialized", "notifications/initialized":
 		return s.handleInitialized(ctx, params)
+	case "notifications/roots/list_changed":
+		s.requestRoots(ctx)
+		return nil, nil
 	case "tools/list":
 		return s.handleToolsList(ctx, params)
 	case "tools/call":
@@ -170,6 +232,9 @@ func (s *Server) handleInitialize(ctx context.Context, params json.RawMessage) (
 		}
 	}
 
+	if err := s.startSession(ctx, initParams.ClientInfo, initParams.Capabilities.Roots != nil); err != nil {
+		return nil, fmt.Errorf("start session: %w", err)
+	}
 	s.mu.Lock()
 	s.initialized = true
 	s.mu.Unlock()
@@ -191,9 +256,87 @@ func (s *Server) handleInitialize(ctx context.Context, params json.RawMessage) (
 // handleInitialized handles the initialized notification.
 func (s *Server) handleInitialized(ctx context.Context, params json.RawMessage) (any, error) {
 	// This is a notification, no response needed
+	s.requestRoots(ctx)
 	return nil, nil
 }
 
+// requestRoots asks the client for its roots and passes them to the roots
+// handler, if one is set and the client supports roots.
+func (s *Server) requestRoots(ctx context.Context) {
+	sess, _ := s.session(ctx)
+	s.mu.RLock()
+	handler := s.rootsHandler
+	s.mu.RUnlock()
+	if handler == nil || !sess.roots {
+		return
+	}
+
+	err := s.call("roots/list", nil, func(result json.RawMessage, rpcErr *Error) {
+		if rpcErr != nil {
+			s.logError("roots/list failed: %v", rpcErr)
+			return
+		}
+		var roots ListRootsResult
+		if err := json.Unmarshal(result, &roots); err != nil {
+			s.logError("invalid roots/list result: %v", err)
+			return
+		}
+		handler(ctx, roots.Roots)
+	})
+	if err != nil {
+		s.logError("failed to request roots: %v", err)
+	}
+}
+
+// call sends a request to the client. onResult runs from Run when the
+// client's response arrives.
+func (s *Server) call(method string, params any, onResult func(result json.RawMessage, err *Error)) error {
+	s.mu.Lock()
+	s.nextID++
+	id := fmt.Sprintf("cortex-%d", s.nextID)
+	s.pending[id] = onResult
+	s.mu.Unlock()
+
+	req := struct {
+		JSONRPC string `json:"jsonrpc"`
+		ID      string `json:"id"`
+		Method  string `json:"method"`
+		Params  any    `json:"params,omitempty"`
+	}{JSONRPCVersion, id, method, params}
+	if err := s.writeMessage(req); err != nil {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// handleResponse passes a client's response to the request it answers.
+func (s *Server) handleResponse(data []byte) {
+	var resp struct {
+		ID     any             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		s.logError("invalid response from client: %v", err)
+		return
+	}
+
+	id := fmt.Sprint(resp.ID)
+	s.mu.Lock()
+	onResult, ok := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+
+	if !ok {
+		s.logError("response to unknown request %s", id)
+		return
+	}
+	onResult(resp.Result, resp.Error)
+}
+
 // handleToolsList handles the tools/list request.
 func (s *Server) handleToolsList(ctx context.Context, params json.RawMessage) (any, error) {
 	s.mu.RLock()
@@ -213,12 +356,26 @@ func (s *Server) handleToolsCall(ctx context.Context, params json.RawMessage) (a
 
 	s.mu.RLock()
 	handler, exists := s.handlers[callParams.Name]
+	authorize := s.authorize
 	s.mu.RUnlock()
+	sess, _ := s.session(ctx)
 
 	if !exists {
 		return nil, NewError(MethodNotFound, fmt.Sprintf("Tool not found: %s", callParams.Name))
 	}
 
+	ctx = context.WithValue(ctx, toolCallContextKey{}, ToolCall{
+		Name:      callParams.Name,
+		Arguments: callParams.Arguments,
+		Client:    sess.client,
+	})
+	if authorize != nil {
+		var err error
+		if ctx, err = authorize(ctx, callParams.Name, callParams.Arguments); err != nil {
+			return nil, NewError(Unauthorized, "Unauthorized: "+err.Error())
+		}
+	}
+
 	result, err := handler(ctx, callParams.Arguments)
 	if err != nil {
 		// Return error as tool result, not as JSON-RPC error
@@ -250,12 +407,19 @@ func (s *Server) handlePing(ctx context.Context, params json.RawMessage) (any, e
 
 // writeResponse writes a JSON-RPC response to stdout.
 func (s *Server) writeResponse(resp *Response) error {
-	data, err := json.Marshal(resp)
+	return s.writeMessage(resp)
+}
+
+// writeMessage writes a JSON-RPC message to stdout.
+func (s *Server) writeMessage(msg any) error {
+	data, err := json.Marshal(msg)
 	if err != nil {
-		return fmt.Errorf("marshal response: %w", err)
+		return fmt.Errorf("marshal message: %w", err)
 	}
 
 	data = append(data, '\n')
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	_, err = s.stdout.Write(data)
 	return err
 }
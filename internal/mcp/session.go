@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// SessionHeader carries the ID of an HTTP client's session. The server
+// returns it in the response to initialize, and the client sends it with
+// every later request.
+const SessionHeader = "Mcp-Session-Id"
+
+// maxSessions bounds the sessions kept at once. Starting another evicts the
+// least recently used one, whose client has to initialize again.
+const maxSessions = 1024
+
+// stdioSession is the ID of the session of the stdio client.
+const stdioSession = ""
+
+// session is what a client told the server when it initialized.
+type session struct {
+	client   ClientInfo
+	roots    bool // client declared the roots capability
+	lastUsed time.Time
+}
+
+// sessionRef names the session an HTTP request belongs to. Initialize
+// starts a new session and sets id to its ID.
+type sessionRef struct {
+	id string
+}
+
+type sessionContextKey struct{}
+
+// contextWithSession returns a context for a request of the HTTP session ref.
+func contextWithSession(ctx context.Context, ref *sessionRef) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, ref)
+}
+
+// sessionFromContext returns the HTTP session a request belongs to, or nil
+// for requests of the stdio client.
+func sessionFromContext(ctx context.Context) *sessionRef {
+	ref, _ := ctx.Value(sessionContextKey{}).(*sessionRef)
+	return ref
+}
+
+// newSessionID returns a random session ID.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// startSession records the client of a request to initialize. Over HTTP it
+// starts a new session and stores its ID in the request's session.
+func (s *Server) startSession(ctx context.Context, client ClientInfo, roots bool) error {
+	id := stdioSession
+	ref := sessionFromContext(ctx)
+	if ref != nil {
+		var err error
+		if id, err = newSessionID(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[id]; !ok && len(s.sessions) >= maxSessions {
+		s.evictSession()
+	}
+	s.sessions[id] = &session{client: client, roots: roots, lastUsed: time.Now()}
+	if ref != nil {
+		ref.id = id
+	}
+	return nil
+}
+
+// evictSession removes the least recently used session. s.mu must be held.
+func (s *Server) evictSession() {
+	var oldest string
+	var oldestUsed time.Time
+	for id, sess := range s.sessions {
+		if oldestUsed.IsZero() || sess.lastUsed.Before(oldestUsed) {
+			oldest, oldestUsed = id, sess.lastUsed
+		}
+	}
+	delete(s.sessions, oldest)
+}
+
+// session returns the session of the client making a request, marking it
+// used. HTTP requests outside a session have none.
+func (s *Server) session(ctx context.Context) (session, bool) {
+	id := stdioSession
+	if ref := sessionFromContext(ctx); ref != nil {
+		if ref.id == "" {
+			return session{}, false
+		}
+		id = ref.id
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return session{}, false
+	}
+	sess.lastUsed = time.Now()
+	return *sess, true
+}
+
+// hasSession reports whether an HTTP session is active.
+func (s *Server) hasSession(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.sessions[id]
+	return ok
+}
+
+// endSession forgets an HTTP session.
+func (s *Server) endSession(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
@@ -54,10 +54,27 @@ func MemoryAddTool() Tool {
 					Description: "Optional time-to-live in days. After this period, the memory may be automatically cleaned up.",
 					Minimum:     &minK,
 				},
+				"ttl_from": {
+					Type:        "string",
+					Description: "What ttl_days counts from: 'created' (default), 'updated' (the last update) or 'accessed' (the last update or read, whichever is later). Use 'updated' or 'accessed' for memories that should stay while they are in use.",
+					Enum:        []string{"created", "updated", "accessed"},
+					Default:     "created",
+				},
 				"source": {
 					Type:        "string",
 					Description: "Optional source identifier (e.g., 'chat', 'file:/path/to/file').",
 				},
+				"normalize": {
+					Type:        "boolean",
+					Description: "Rewrite the text as a concise, factual statement before storing it. The raw input is kept in meta.original_text. Defaults to the server's NORMALIZE_MEMORIES setting.",
+				},
+				"todo": todoFieldsSchema("Todo fields, for kind 'todo'. Todos start open with normal priority."),
+				"scope": {
+					Type:        "string",
+					Description: "Where to store the memory: 'workspace' (default) for this project only, or 'shared' for the tenant's shared workspace, which searches in every workspace include by default. Use 'shared' for personal preferences and org-wide facts.",
+					Enum:        []string{"workspace", "shared"},
+					Default:     "workspace",
+				},
 			},
 			Required:             []string{"text"},
 			AdditionalProperties: &falseVal,
@@ -98,6 +115,22 @@ func MemorySearchTool() Tool {
 					Type:        "string",
 					Description: "Optional: filter search to a specific embedding model (e.g., 'text-embedding-3-small'). Leave empty to search all models.",
 				},
+				"include_archived": {
+					Type:        "boolean",
+					Description: "If true, also search memories the sweeper moved to the archive tier. Archived results are marked with archived: true.",
+					Default:     false,
+				},
+				"scope": {
+					Type:        "string",
+					Description: "Which workspaces to search: 'workspace' (default) for this workspace and the workspaces it inherits, such as the shared workspace, or 'tenant' for every workspace of the tenant. Each result reports its workspace.",
+					Enum:        []string{"workspace", "tenant"},
+					Default:     "workspace",
+				},
+				"workspaces": {
+					Type:        "array",
+					Description: "Search exactly these workspaces instead of using scope.",
+					Items:       &JSONSchema{Type: "string"},
+				},
 			},
 			Required:             []string{"query"},
 			AdditionalProperties: &falseVal,
@@ -152,10 +185,20 @@ func MemoryUpdateTool() Tool {
 							Description: "New time-to-live in days.",
 							Minimum:     &minTTL,
 						},
+						"ttl_from": {
+							Type:        "string",
+							Description: "What ttl_days counts from: 'created', 'updated' or 'accessed'.",
+							Enum:        []string{"created", "updated", "accessed"},
+						},
 						"source": {
 							Type:        "string",
 							Description: "New source identifier.",
 						},
+						"normalize": {
+							Type:        "boolean",
+							Description: "Normalize the new text before storing it (raw input kept in meta.original_text). Defaults to the server's NORMALIZE_MEMORIES setting.",
+						},
+						"todo": todoFieldsSchema("Todo fields to change, for memories of kind 'todo'."),
 					},
 					AdditionalProperties: &falseVal,
 				},
@@ -166,6 +209,60 @@ func MemoryUpdateTool() Tool {
 	}
 }
 
+// todoFieldsSchema returns the schema of the todo fields accepted by memory.add and memory.update.
+func todoFieldsSchema(description string) JSONSchema {
+	falseVal := false
+
+	return JSONSchema{
+		Type:        "object",
+		Description: description,
+		Properties: map[string]JSONSchema{
+			"status": {
+				Type:        "string",
+				Description: "Todo status.",
+				Enum:        []string{"open", "in_progress", "done"},
+			},
+			"due_at": {
+				Type:        "string",
+				Description: "Due date as RFC 3339 (2025-03-01T17:00:00Z) or a date (2025-03-01, meaning the end of that day in UTC).",
+			},
+			"assignee": {
+				Type:        "string",
+				Description: "Name of the person responsible. Stored as a person entity linked to the todo.",
+			},
+			"priority": {
+				Type:        "string",
+				Description: "Todo priority.",
+				Enum:        []string{"low", "normal", "high", "urgent"},
+			},
+		},
+		AdditionalProperties: &falseVal,
+	}
+}
+
+// MemoryGetTool returns the tool definition for memory.get.
+func MemoryGetTool() Tool {
+	falseVal := false
+	minID := 1.0
+
+	return Tool{
+		Name:        "memory.get",
+		Description: "Get a memory by ID, with all of its fields and access statistics.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"id": {
+					Type:        "integer",
+					Description: "The ID of the memory to get.",
+					Minimum:     &minID,
+				},
+			},
+			Required:             []string{"id"},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
 // MemoryDeleteTool returns the tool definition for memory.delete.
 func MemoryDeleteTool() Tool {
 	falseVal := false
@@ -191,17 +288,33 @@ func MemoryDeleteTool() Tool {
 
 // MemoryAddArgs contains the arguments for memory.add.
 type MemoryAddArgs struct {
-	Text       string   `json:"text"`
-	Kind       string   `json:"kind,omitempty"`
-	Importance *float32 `json:"importance,omitempty"`
-	Tags       []string `json:"tags,omitempty"`
-	TTLDays    *int     `json:"ttl_days,omitempty"`
-	Source     *string  `json:"source,omitempty"`
+	Text       string      `json:"text"`
+	Kind       string      `json:"kind,omitempty"`
+	Importance *float32    `json:"importance,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	TTLDays    *int        `json:"ttl_days,omitempty"`
+	TTLFrom    *string     `json:"ttl_from,omitempty"`
+	Source     *string     `json:"source,omitempty"`
+	Normalize  *bool       `json:"normalize,omitempty"`
+	Todo       *TodoFields `json:"todo,omitempty"`
+	Scope      string      `json:"scope,omitempty"` // workspace or shared
+}
+
+// TodoFields contains the todo fields accepted by memory.add and memory.update.
+type TodoFields struct {
+	Status   string `json:"status,omitempty"`
+	DueAt    string `json:"due_at,omitempty"`
+	Assignee string `json:"assignee,omitempty"`
+	Priority string `json:"priority,omitempty"`
 }
 
 // MemoryAddResult is the result of memory.add.
 type MemoryAddResult struct {
-	ID int64 `json:"id"`
+	ID        int64    `json:"id"`
+	Workspace string   `json:"workspace"`           // workspace the memory was stored in
+	Conflicts []int64  `json:"conflicts,omitempty"` // IDs of existing memories this one contradicts
+	Warnings  []string `json:"warnings,omitempty"`
+	Pending   []string `json:"pending,omitempty"` // Processing queued in the background (e.g., "embed")
 }
 
 // MemorySearchArgs contains the arguments for memory.search.
@@ -210,16 +323,33 @@ type MemorySearchArgs struct {
 	K      *int    `json:"k,omitempty"`
 	Hybrid *bool   `json:"hybrid,omitempty"`
 	Model  *string `json:"model,omitempty"` // Optional: filter by embedding model
+
+	IncludeArchived bool `json:"include_archived,omitempty"`
+
+	Scope      string   `json:"scope,omitempty"`      // workspace or tenant
+	Workspaces []string `json:"workspaces,omitempty"` // explicit workspaces, overriding scope
 }
 
 // MemorySearchResult is a single search result.
 type MemorySearchResult struct {
-	ID         int64    `json:"id"`
-	Text       string   `json:"text"`
-	Score      float32  `json:"score"`
-	Source     *string  `json:"source,omitempty"`
-	Tags       []string `json:"tags"`
-	Importance float32  `json:"importance"`
+	ID             int64       `json:"id"`
+	Text           string      `json:"text"`
+	Score          float32     `json:"score"`
+	Source         *string     `json:"source,omitempty"`
+	Tags           []string    `json:"tags"`
+	Importance     float32     `json:"importance"`
+	Chunk          *ChunkMatch `json:"chunk,omitempty"`            // Set when a document matched through a chunk; text is the chunk's text
+	SupersededBy   []int64     `json:"superseded_by,omitempty"`    // Newer memories that supersede this one
+	AccessCount    int64       `json:"access_count"`               // Times returned by search or get, excluding this one
+	LastAccessedAt string      `json:"last_accessed_at,omitempty"` // Previous access
+	Archived       bool        `json:"archived,omitempty"`         // Found in the archive tier
+	Workspace      string      `json:"workspace"`                  // Workspace the memory belongs to
+}
+
+// ChunkMatch identifies the chunk of a document that matched a search.
+type ChunkMatch struct {
+	ID    int64 `json:"id"`
+	Index int   `json:"index"`
 }
 
 // MemoryUpdateArgs contains the arguments for memory.update.
@@ -230,17 +360,47 @@ type MemoryUpdateArgs struct {
 
 // MemoryUpdatePatch contains the fields that can be updated.
 type MemoryUpdatePatch struct {
-	Text       *string  `json:"text,omitempty"`
-	Kind       *string  `json:"kind,omitempty"`
-	Importance *float32 `json:"importance,omitempty"`
-	Tags       []string `json:"tags,omitempty"`
-	TTLDays    *int     `json:"ttl_days,omitempty"`
-	Source     *string  `json:"source,omitempty"`
+	Text       *string     `json:"text,omitempty"`
+	Kind       *string     `json:"kind,omitempty"`
+	Importance *float32    `json:"importance,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	TTLDays    *int        `json:"ttl_days,omitempty"`
+	TTLFrom    *string     `json:"ttl_from,omitempty"`
+	Source     *string     `json:"source,omitempty"`
+	Normalize  *bool       `json:"normalize,omitempty"`
+	Todo       *TodoFields `json:"todo,omitempty"`
 }
 
 // MemoryUpdateResult is the result of memory.update.
 type MemoryUpdateResult struct {
-	OK bool `json:"ok"`
+	OK        bool     `json:"ok"`
+	Conflicts []int64  `json:"conflicts,omitempty"` // IDs of existing memories the new text contradicts
+	Warnings  []string `json:"warnings,omitempty"`
+	Pending   []string `json:"pending,omitempty"` // Processing queued in the background (e.g., "embed")
+}
+
+// MemoryGetArgs contains the arguments for memory.get.
+type MemoryGetArgs struct {
+	ID int64 `json:"id"`
+}
+
+// MemoryGetResult is the result of memory.get.
+type MemoryGetResult struct {
+	ID             int64          `json:"id"`
+	Text           string         `json:"text"`
+	Kind           string         `json:"kind"`
+	Source         *string        `json:"source,omitempty"`
+	Tags           []string       `json:"tags"`
+	Importance     float32        `json:"importance"`
+	TTLDays        *int           `json:"ttl_days,omitempty"`
+	TTLFrom        string         `json:"ttl_from"`
+	Meta           map[string]any `json:"meta,omitempty"`
+	ParentID       *int64         `json:"parent_id,omitempty"`
+	ChunkIndex     *int           `json:"chunk_index,omitempty"`
+	CreatedAt      string         `json:"created_at"`
+	UpdatedAt      string         `json:"updated_at"`
+	AccessCount    int64          `json:"access_count"`               // Times returned by search or get, excluding this one
+	LastAccessedAt string         `json:"last_accessed_at,omitempty"` // Previous access
 }
 
 // MemoryDeleteArgs contains the arguments for memory.delete.
@@ -380,10 +540,13 @@ func MemoryRelatedTool() Tool {
 	minK := 1.0
 	maxK := 100.0
 	defaultK := 10.0
+	minDepth := 1.0
+	maxDepth := 3.0
+	defaultDepth := 1.0
 
 	return Tool{
 		Name:        "memory.related",
-		Description: "Find memories that share entities with the given memory. This discovers connections between memories based on common people, organizations, technologies, or concepts.",
+		Description: "Find memories connected to the given memory: memories reached by following explicit links (supersedes, relates_to, derived_from, blocks, contradicts), followed by memories that share entities such as people, organizations, technologies, or concepts.",
 		InputSchema: JSONSchema{
 			Type: "object",
 			Properties: map[string]JSONSchema{
@@ -399,6 +562,21 @@ func MemoryRelatedTool() Tool {
 					Maximum:     &maxK,
 					Default:     defaultK,
 				},
+				"link_types": {
+					Type:        "array",
+					Description: "Only follow links of these types (default: all).",
+					Items: &JSONSchema{
+						Type: "string",
+						Enum: []string{"supersedes", "relates_to", "derived_from", "blocks", "contradicts"},
+					},
+				},
+				"depth": {
+					Type:        "integer",
+					Description: "Number of links to follow from the memory (1-3).",
+					Minimum:     &minDepth,
+					Maximum:     &maxDepth,
+					Default:     defaultDepth,
+				},
 			},
 			Required:             []string{"memory_id"},
 			AdditionalProperties: &falseVal,
@@ -427,8 +605,10 @@ type MemoryEntitiesResult struct {
 
 // MemoryRelatedArgs contains the arguments for memory.related.
 type MemoryRelatedArgs struct {
-	MemoryID int64 `json:"memory_id"`
-	K        *int  `json:"k,omitempty"`
+	MemoryID  int64    `json:"memory_id"`
+	K         *int     `json:"k,omitempty"`
+	LinkTypes []string `json:"link_types,omitempty"`
+	Depth     *int     `json:"depth,omitempty"`
 }
 
 // MemoryRelatedResult is a single related memory in the response.
@@ -436,8 +616,700 @@ type MemoryRelatedResult struct {
 	ID         int64    `json:"id"`
 	Text       string   `json:"text"`
 	Kind       string   `json:"kind"`
-	Score      float32  `json:"score"` // Entity overlap score
+	Score      float32  `json:"score"` // Entity overlap score, or 1/depth for linked memories
 	Source     *string  `json:"source,omitempty"`
 	Tags       []string `json:"tags"`
 	Importance float32  `json:"importance"`
+	Via        string   `json:"via"`                 // "entities", or the type of the last link followed
+	Direction  string   `json:"direction,omitempty"` // For links: "outgoing" or "incoming"
+	Depth      int      `json:"depth,omitempty"`     // For links: number of links followed
+}
+
+// MemoryConflictsTool returns the tool definition for memory.conflicts.
+func MemoryConflictsTool() Tool {
+	falseVal := false
+	minK := 1.0
+	maxK := 100.0
+	defaultK := 50.0
+
+	return Tool{
+		Name:        "memory.conflicts",
+		Description: "List unresolved contradictions between memories. A contradiction is recorded when a new fact or preference conflicts with an existing one; resolve it by updating or deleting one of the pair.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"k": {
+					Type:        "integer",
+					Description: "Maximum number of conflicts to return (1-100).",
+					Minimum:     &minK,
+					Maximum:     &maxK,
+					Default:     defaultK,
+				},
+			},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryConflictsArgs contains the arguments for memory.conflicts.
+type MemoryConflictsArgs struct {
+	K *int `json:"k,omitempty"`
+}
+
+// ConflictResult is a single unresolved contradiction between two memories.
+type ConflictResult struct {
+	MemoryID        int64   `json:"memory_id"`
+	MemoryText      string  `json:"memory_text"`
+	ConflictsWithID int64   `json:"conflicts_with_id"`
+	ConflictsWith   string  `json:"conflicts_with_text"`
+	Reason          string  `json:"reason,omitempty"`
+	Confidence      float32 `json:"confidence,omitempty"`
+	DetectedAt      string  `json:"detected_at"`
+}
+
+// MemoryConflictsResult is the result of memory.conflicts.
+type MemoryConflictsResult struct {
+	Conflicts []ConflictResult `json:"conflicts"`
+}
+
+// MemoryIngestTool returns the tool definition for memory.ingest.
+func MemoryIngestTool() Tool {
+	falseVal := false
+	minImportance := 0.0
+	maxImportance := 1.0
+	defaultImportance := 0.5
+
+	return Tool{
+		Name:        "memory.ingest",
+		Description: "Ingest a long document (design doc, notes, source file) by splitting it into overlapping chunks that are embedded and searched individually. Search results for chunks collapse back to the document. Re-ingesting a document with the same source replaces it.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"text": {
+					Type:        "string",
+					Description: "The document text. Exactly one of text or path is required.",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Path of a file on the server to ingest. The source defaults to 'file:<absolute path>' and the format is detected from the extension.",
+				},
+				"format": {
+					Type:        "string",
+					Description: "How to split the document: text, markdown or go.",
+					Enum:        []string{"text", "markdown", "go"},
+				},
+				"title": {
+					Type:        "string",
+					Description: "Optional document title (defaults to the file name for paths).",
+				},
+				"source": {
+					Type:        "string",
+					Description: "Optional source identifier. An existing document with the same source is replaced.",
+				},
+				"tags": {
+					Type:        "array",
+					Description: "Optional tags, applied to the document and its chunks.",
+					Items: &JSONSchema{
+						Type: "string",
+					},
+				},
+				"importance": {
+					Type:        "number",
+					Description: "Importance score from 0.0 to 1.0.",
+					Minimum:     &minImportance,
+					Maximum:     &maxImportance,
+					Default:     defaultImportance,
+				},
+			},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryIngestArgs contains the arguments for memory.ingest.
+type MemoryIngestArgs struct {
+	Text       string   `json:"text,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	Format     string   `json:"format,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Source     *string  `json:"source,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Importance *float32 `json:"importance,omitempty"`
+}
+
+// MemoryIngestResult is the result of memory.ingest.
+type MemoryIngestResult struct {
+	ID       int64    `json:"id"`     // Document ID
+	Chunks   int      `json:"chunks"` // Number of chunks stored
+	Replaced bool     `json:"replaced,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	Pending  []string `json:"pending,omitempty"`
+}
+
+// MemoryBackfillTool returns the tool definition for memory.backfill.
+func MemoryBackfillTool() Tool {
+	falseVal := false
+	minBatch := 1.0
+	maxBatch := 1000.0
+	defaultBatch := 100.0
+
+	return Tool{
+		Name:        "memory.backfill",
+		Description: "Admin: process memories that are missing an embedding for a configured model or have no extracted entities (e.g., because a provider call failed when they were written). With neither flag set, both are backfilled.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"embeddings": {
+					Type:        "boolean",
+					Description: "Embed memories missing an embedding for any configured model.",
+					Default:     false,
+				},
+				"entities": {
+					Type:        "boolean",
+					Description: "Extract entities for memories without linked entities. Requires ENTITY_EXTRACTION.",
+					Default:     false,
+				},
+				"batch_size": {
+					Type:        "integer",
+					Description: "Number of memories to process per batch (1-1000).",
+					Minimum:     &minBatch,
+					Maximum:     &maxBatch,
+					Default:     defaultBatch,
+				},
+			},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryBackfillArgs contains the arguments for memory.backfill.
+type MemoryBackfillArgs struct {
+	Embeddings bool `json:"embeddings,omitempty"`
+	Entities   bool `json:"entities,omitempty"`
+	BatchSize  *int `json:"batch_size,omitempty"`
+}
+
+// BackfillStats reports the outcome of one backfill step.
+type BackfillStats struct {
+	Model      string `json:"model,omitempty"` // set for embedding steps
+	Total      int64  `json:"total"`
+	Processed  int64  `json:"processed"`
+	Errors     int64  `json:"errors"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// MemoryBackfillResult is the result of memory.backfill.
+type MemoryBackfillResult struct {
+	Embeddings []BackfillStats `json:"embeddings,omitempty"`
+	Entities   *BackfillStats  `json:"entities,omitempty"`
+}
+
+// MemoryIngestConversationTool returns the tool definition for memory.ingest_conversation.
+func MemoryIngestConversationTool() Tool {
+	falseVal := false
+	minImportance := 0.0
+	maxImportance := 1.0
+
+	return Tool{
+		Name:        "memory.ingest_conversation",
+		Description: "Extract durable facts, decisions, preferences and todos from a Claude Code session transcript (JSONL) and store them as memories. Candidates that duplicate an existing memory are reported but not stored. Use dry_run to review candidates first.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"transcript": {
+					Type:        "string",
+					Description: "The transcript content, one JSON object per line. Exactly one of transcript or path is required.",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Path of a transcript file on the server (e.g., ~/.claude/projects/<project>/<session>.jsonl).",
+				},
+				"tags": {
+					Type:        "array",
+					Description: "Optional tags added to every extracted memory.",
+					Items: &JSONSchema{
+						Type: "string",
+					},
+				},
+				"min_importance": {
+					Type:        "number",
+					Description: "Drop candidates the LLM rated below this importance.",
+					Minimum:     &minImportance,
+					Maximum:     &maxImportance,
+					Default:     0.0,
+				},
+				"dry_run": {
+					Type:        "boolean",
+					Description: "Extract and check for duplicates, but store nothing.",
+					Default:     false,
+				},
+			},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryIngestConversationArgs contains the arguments for memory.ingest_conversation.
+type MemoryIngestConversationArgs struct {
+	Transcript    string   `json:"transcript,omitempty"`
+	Path          string   `json:"path,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	MinImportance *float32 `json:"min_importance,omitempty"`
+	DryRun        bool     `json:"dry_run,omitempty"`
+}
+
+// ExtractedMemory is a memory extracted from a conversation.
+type ExtractedMemory struct {
+	ID         int64    `json:"id,omitempty"` // Stored memory, or the existing duplicate; unset in a dry run
+	Text       string   `json:"text"`
+	Kind       string   `json:"kind"`
+	Importance float32  `json:"importance"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// MemoryIngestConversationResult is the result of memory.ingest_conversation.
+type MemoryIngestConversationResult struct {
+	SessionID  string            `json:"session_id,omitempty"`
+	Turns      int               `json:"turns"`      // Messages read from the transcript
+	Added      []ExtractedMemory `json:"added"`      // Stored (or, in a dry run, would be stored)
+	Duplicates []ExtractedMemory `json:"duplicates"` // Already present; ID is the existing memory
+	Skipped    int               `json:"skipped,omitempty"`
+	DryRun     bool              `json:"dry_run,omitempty"`
+	Conflicts  []int64           `json:"conflicts,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	Pending    []string          `json:"pending,omitempty"`
+}
+
+// MemoryLinkTool returns the tool definition for memory.link.
+func MemoryLinkTool() Tool {
+	falseVal := false
+	minID := 1.0
+
+	return Tool{
+		Name:        "memory.link",
+		Description: "Create a typed, directed link from one memory to another: supersedes (source replaces the outdated target), relates_to, derived_from (source was derived from target), blocks (source must be resolved before target), or contradicts. Superseded memories are flagged in search results.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"source_id": {
+					Type:        "integer",
+					Description: "The ID of the memory the link starts from.",
+					Minimum:     &minID,
+				},
+				"target_id": {
+					Type:        "integer",
+					Description: "The ID of the memory the link points to.",
+					Minimum:     &minID,
+				},
+				"type": {
+					Type:        "string",
+					Description: "The link type.",
+					Enum:        []string{"supersedes", "relates_to", "derived_from", "blocks", "contradicts"},
+				},
+				"reason": {
+					Type:        "string",
+					Description: "Optional explanation, stored in the link's meta.",
+				},
+			},
+			Required:             []string{"source_id", "target_id", "type"},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryUnlinkTool returns the tool definition for memory.unlink.
+func MemoryUnlinkTool() Tool {
+	falseVal := false
+	minID := 1.0
+
+	return Tool{
+		Name:        "memory.unlink",
+		Description: "Remove a link from one memory to another.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"source_id": {
+					Type:        "integer",
+					Description: "The ID of the memory the link starts from.",
+					Minimum:     &minID,
+				},
+				"target_id": {
+					Type:        "integer",
+					Description: "The ID of the memory the link points to.",
+					Minimum:     &minID,
+				},
+				"type": {
+					Type:        "string",
+					Description: "The link type to remove (default: all links from source to target).",
+					Enum:        []string{"supersedes", "relates_to", "derived_from", "blocks", "contradicts"},
+				},
+			},
+			Required:             []string{"source_id", "target_id"},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryLinkArgs contains the arguments for memory.link.
+type MemoryLinkArgs struct {
+	SourceID int64  `json:"source_id"`
+	TargetID int64  `json:"target_id"`
+	Type     string `json:"type"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// MemoryLinkResult is the result of memory.link.
+type MemoryLinkResult struct {
+	OK bool `json:"ok"`
+}
+
+// MemoryUnlinkArgs contains the arguments for memory.unlink.
+type MemoryUnlinkArgs struct {
+	SourceID int64  `json:"source_id"`
+	TargetID int64  `json:"target_id"`
+	Type     string `json:"type,omitempty"`
+}
+
+// MemoryUnlinkResult is the result of memory.unlink.
+type MemoryUnlinkResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// MemoryTodoListTool returns the tool definition for memory.todo_list.
+func MemoryTodoListTool() Tool {
+	falseVal := false
+	minK := 1.0
+	maxK := 200.0
+	defaultK := 50.0
+	minDays := 0.0
+
+	return Tool{
+		Name:        "memory.todo_list",
+		Description: "List todos, soonest due first (todos without a due date last), then by priority. By default only open and in-progress todos are listed.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"status": {
+					Type:        "array",
+					Description: "Statuses to include (default: open and in_progress).",
+					Items: &JSONSchema{
+						Type: "string",
+						Enum: []string{"open", "in_progress", "done", "archived"},
+					},
+				},
+				"assignee": {
+					Type:        "string",
+					Description: "Only todos assigned to this person.",
+				},
+				"overdue": {
+					Type:        "boolean",
+					Description: "Only unfinished todos past their due date.",
+					Default:     false,
+				},
+				"due_within_days": {
+					Type:        "integer",
+					Description: "Only todos due within this many days from now (including overdue ones).",
+					Minimum:     &minDays,
+				},
+				"k": {
+					Type:        "integer",
+					Description: "Maximum number of todos to return (1-200).",
+					Minimum:     &minK,
+					Maximum:     &maxK,
+					Default:     defaultK,
+				},
+			},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryTodoCompleteTool returns the tool definition for memory.todo_complete.
+func MemoryTodoCompleteTool() Tool {
+	falseVal := false
+	minID := 1.0
+
+	return Tool{
+		Name:        "memory.todo_complete",
+		Description: "Mark a todo as done and record when it was completed.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"id": {
+					Type:        "integer",
+					Description: "The ID of the todo memory.",
+					Minimum:     &minID,
+				},
+			},
+			Required:             []string{"id"},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryTodoListArgs contains the arguments for memory.todo_list.
+type MemoryTodoListArgs struct {
+	Status        []string `json:"status,omitempty"`
+	Assignee      string   `json:"assignee,omitempty"`
+	Overdue       bool     `json:"overdue,omitempty"`
+	DueWithinDays *int     `json:"due_within_days,omitempty"`
+	K             *int     `json:"k,omitempty"`
+}
+
+// TodoResult is a todo in the response.
+type TodoResult struct {
+	ID          int64    `json:"id"`
+	Text        string   `json:"text"`
+	Status      string   `json:"status"`
+	Priority    string   `json:"priority"`
+	DueAt       string   `json:"due_at,omitempty"`
+	Overdue     bool     `json:"overdue,omitempty"`
+	Assignee    *string  `json:"assignee,omitempty"`
+	Tags        []string `json:"tags"`
+	CreatedAt   string   `json:"created_at"`
+	CompletedAt string   `json:"completed_at,omitempty"`
+}
+
+// MemoryTodoListResult is the result of memory.todo_list.
+type MemoryTodoListResult struct {
+	Todos []TodoResult `json:"todos"`
+}
+
+// MemoryTodoCompleteArgs contains the arguments for memory.todo_complete.
+type MemoryTodoCompleteArgs struct {
+	ID int64 `json:"id"`
+}
+
+// MemorySweeperStatusTool returns the tool definition for memory.sweeper_status.
+func MemorySweeperStatusTool() Tool {
+	falseVal := false
+	minK := 1.0
+	maxK := 100.0
+	defaultK := 10.0
+
+	return Tool{
+		Name:        "memory.sweeper_status",
+		Description: "Show how this server's sweeper is configured and the most recent sweeps of this workspace, with what each removed, decayed and archived here and any errors. Sweeps by other instances and global sweeps are included.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"k": {
+					Type:        "integer",
+					Description: "Maximum number of runs to return (1-100).",
+					Minimum:     &minK,
+					Maximum:     &maxK,
+					Default:     defaultK,
+				},
+			},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemorySweeperStatusArgs contains the arguments for memory.sweeper_status.
+type MemorySweeperStatusArgs struct {
+	K *int `json:"k,omitempty"`
+}
+
+// SweeperRunResult is a recorded sweep, with what it changed in the workspace.
+type SweeperRunResult struct {
+	ID            int64    `json:"id"`
+	StartedAt     string   `json:"started_at"`
+	FinishedAt    string   `json:"finished_at"`
+	Global        bool     `json:"global,omitempty"`  // swept every workspace
+	Skipped       bool     `json:"skipped,omitempty"` // another instance was sweeping
+	Error         string   `json:"error,omitempty"`   // the sweep could not start
+	Removed       int64    `json:"removed"`           // deleted or archived
+	Expired       int64    `json:"expired"`
+	Evicted       int64    `json:"evicted"`
+	PolicyExpired int64    `json:"policy_expired"`
+	Decayed       int64    `json:"decayed"`
+	TodosArchived int64    `json:"todos_archived"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// MemorySweeperStatusResult is the result of memory.sweeper_status.
+type MemorySweeperStatusResult struct {
+	Enabled     bool               `json:"enabled"` // this server runs the sweeper
+	Interval    string             `json:"interval,omitempty"`
+	Global      bool               `json:"global,omitempty"`
+	ArchiveMode string             `json:"archive_mode,omitempty"`
+	Policies    []string           `json:"policies,omitempty"` // retention policy names
+	Runs        []SweeperRunResult `json:"runs"`
+}
+
+// MemoryWorkspacesTool returns the tool definition for memory.workspaces.
+func MemoryWorkspacesTool() Tool {
+	falseVal := false
+
+	return Tool{
+		Name:        "memory.workspaces",
+		Description: "List the workspaces of this tenant that calls may select, with their memory, document, entity and archived counts, text and embedding sizes, and last activity.",
+		InputSchema: JSONSchema{
+			Type:                 "object",
+			Properties:           map[string]JSONSchema{},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// workspaceMoveSchema returns the input schema shared by the workspace rename, clone and merge tools.
+func workspaceMoveSchema(fromDesc, toDesc string) JSONSchema {
+	falseVal := false
+
+	return JSONSchema{
+		Type: "object",
+		Properties: map[string]JSONSchema{
+			"from": {
+				Type:        "string",
+				Description: fromDesc,
+			},
+			"to": {
+				Type:        "string",
+				Description: toDesc,
+			},
+		},
+		Required:             []string{"from", "to"},
+		AdditionalProperties: &falseVal,
+	}
+}
+
+// MemoryWorkspaceRenameTool returns the tool definition for memory.workspace_rename.
+func MemoryWorkspaceRenameTool() Tool {
+	return Tool{
+		Name:        "memory.workspace_rename",
+		Description: "Admin tool: rename a workspace, moving its memories, embeddings, entities, relations, links and todos. The new name must not be in use. Servers configured with the old WORKSPACE_ID keep using the old name.",
+		InputSchema: workspaceMoveSchema("The workspace to rename.", "The new workspace name."),
+	}
+}
+
+// MemoryWorkspaceCloneTool returns the tool definition for memory.workspace_clone.
+func MemoryWorkspaceCloneTool() Tool {
+	return Tool{
+		Name:        "memory.workspace_clone",
+		Description: "Admin tool: copy a workspace's memories, embeddings, entities, relations, links and todos into a new workspace. Copies get new IDs; archived memories are not copied.",
+		InputSchema: workspaceMoveSchema("The workspace to copy.", "The new workspace, which must not be in use."),
+	}
+}
+
+// MemoryWorkspaceMergeTool returns the tool definition for memory.workspace_merge.
+func MemoryWorkspaceMergeTool() Tool {
+	return Tool{
+		Name:        "memory.workspace_merge",
+		Description: "Admin tool: move everything in one workspace into another, leaving the first empty. Entities with the same name and type are combined, keeping their memory links, relations and aliases.",
+		InputSchema: workspaceMoveSchema("The workspace to merge and empty.", "The workspace to merge into."),
+	}
+}
+
+// WorkspaceResult summarizes a workspace in memory.workspaces.
+type WorkspaceResult struct {
+	ID             string `json:"id"`
+	Memories       int64  `json:"memories"`
+	Documents      int64  `json:"documents"`
+	Entities       int64  `json:"entities"`
+	Archived       int64  `json:"archived"`
+	TextBytes      int64  `json:"text_bytes"`
+	EmbeddingBytes int64  `json:"embedding_bytes"`
+	LastActivity   string `json:"last_activity,omitempty"`
+}
+
+// MemoryWorkspacesResult is the result of memory.workspaces.
+type MemoryWorkspacesResult struct {
+	Current    string            `json:"current"` // the workspace this server uses
+	Workspaces []WorkspaceResult `json:"workspaces"`
+}
+
+// MemoryWorkspaceMoveArgs contains the arguments for memory.workspace_rename,
+// memory.workspace_clone and memory.workspace_merge.
+type MemoryWorkspaceMoveArgs struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MemoryWorkspaceMoveResult counts what a workspace rename, clone or merge moved or copied.
+type MemoryWorkspaceMoveResult struct {
+	Memories       int64 `json:"memories"`
+	Entities       int64 `json:"entities"`
+	EntitiesMerged int64 `json:"entities_merged,omitempty"`
+	Relations      int64 `json:"relations"`
+	Links          int64 `json:"links"`
+}
+
+// MemoryAuditTool returns the tool definition for memory.audit.
+func MemoryAuditTool() Tool {
+	falseVal := false
+	minK := 1.0
+	maxK := 500.0
+	defaultK := 50.0
+
+	return Tool{
+		Name:        "memory.audit",
+		Description: "List the audit log of the workspace, newest first: every add, update, delete, import, ingest, link and sweep, with who made it, from which client, and which memories it affected.",
+		InputSchema: JSONSchema{
+			Type: "object",
+			Properties: map[string]JSONSchema{
+				"memory_id": {
+					Type:        "integer",
+					Description: "Only list entries that affected this memory.",
+				},
+				"tool": {
+					Type:        "string",
+					Description: "Only list entries of this tool (e.g. memory.delete, sweeper or cli:import).",
+				},
+				"since": {
+					Type:        "string",
+					Description: "Only list entries at or after this time: an RFC 3339 timestamp, a date (YYYY-MM-DD), or a duration ago such as 24h.",
+				},
+				"k": {
+					Type:        "integer",
+					Description: "Maximum number of entries to return (1-500).",
+					Minimum:     &minK,
+					Maximum:     &maxK,
+					Default:     defaultK,
+				},
+			},
+			AdditionalProperties: &falseVal,
+		},
+	}
+}
+
+// MemoryAuditArgs contains the arguments for memory.audit.
+type MemoryAuditArgs struct {
+	MemoryID *int64 `json:"memory_id,omitempty"`
+	Tool     string `json:"tool,omitempty"`
+	Since    string `json:"since,omitempty"`
+	K        *int   `json:"k,omitempty"`
+}
+
+// AuditEntryResult is a single entry of the audit log.
+type AuditEntryResult struct {
+	ID            int64          `json:"id"`
+	At            string         `json:"at"`
+	Actor         string         `json:"actor"`
+	Tool          string         `json:"tool"`
+	ArgsDigest    string         `json:"args_digest,omitempty"`
+	ClientName    string         `json:"client_name,omitempty"`
+	ClientVersion string         `json:"client_version,omitempty"`
+	MemoryIDs     []int64        `json:"memory_ids"`
+	Detail        map[string]any `json:"detail,omitempty"`
+}
+
+// MemoryAuditResult is the result of memory.audit.
+type MemoryAuditResult struct {
+	Entries []AuditEntryResult `json:"entries"`
+}
+
+// WithWorkspaceArg returns a copy of tool that accepts an optional
+// "workspace" argument selecting the workspace the call works on.
+func WithWorkspaceArg(tool Tool) Tool {
+	properties := make(map[string]JSONSchema, len(tool.InputSchema.Properties)+1)
+	for name, prop := range tool.InputSchema.Properties {
+		properties[name] = prop
+	}
+	properties["workspace"] = JSONSchema{
+		Type:        "string",
+		Description: "Workspace of this tenant to work on instead of the server's workspace. Must be the server's workspace or listed in ALLOWED_WORKSPACES.",
+	}
+	tool.InputSchema.Properties = properties
+	return tool
 }
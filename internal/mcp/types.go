@@ -20,6 +20,13 @@ const (
 	InternalError = -32603
 )
 
+// Server-defined error codes.
+const (
+	// Unauthorized indicates the caller's API key is missing, unknown, or does
+	// not grant the tool call.
+	Unauthorized = -32001
+)
+
 // Request represents a JSON-RPC 2.0 request.
 type Request struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -60,6 +67,17 @@ func NewErrorWithData(code int, message string, data any) *Error {
 	}
 }
 
+// Root is a directory or file the client exposes to the server.
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// ListRootsResult is the client's response to a roots/list request.
+type ListRootsResult struct {
+	Roots []Root `json:"roots"`
+}
+
 // ServerInfo contains information about the MCP server.
 type ServerInfo struct {
 	Name    string `json:"name"`
@@ -128,6 +146,7 @@ type JSONSchema struct {
 	Default              any                   `json:"default,omitempty"`
 	Minimum              *float64              `json:"minimum,omitempty"`
 	Maximum              *float64              `json:"maximum,omitempty"`
+	Enum                 []string              `json:"enum,omitempty"`
 }
 
 // ToolsListResult is the response to a tools/list request.
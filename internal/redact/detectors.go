@@ -0,0 +1,129 @@
+package redact
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Detector names.
+const (
+	DetectorSecrets = "secrets" // known token formats, private keys and credential assignments
+	DetectorEntropy = "entropy" // long random-looking tokens
+	DetectorEmail   = "email"
+	DetectorPhone   = "phone"
+)
+
+// Detectors lists every detector, in the order they are documented.
+var Detectors = []string{DetectorSecrets, DetectorEntropy, DetectorEmail, DetectorPhone}
+
+type detector interface {
+	scan(text string) []Finding
+}
+
+var detectorsByName = map[string]detector{
+	DetectorSecrets: patternDetector{name: DetectorSecrets, patterns: secretPatterns},
+	DetectorEntropy: entropyDetector{},
+	DetectorEmail: patternDetector{name: DetectorEmail, patterns: []pattern{
+		{"email", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)},
+	}},
+	DetectorPhone: patternDetector{name: DetectorPhone, patterns: []pattern{
+		// Digits must be grouped by separators, so IDs and version numbers are not matched
+		{"phone", regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)[ .-]?|\b\d{3}[ .-])\d{3}[ .-]\d{4}\b`)},
+		{"phone", regexp.MustCompile(`\+\d{10,15}\b`)},
+	}},
+}
+
+// pattern is a regular expression for one kind of finding. If it has a
+// capture group, only the group is flagged, e.g. the value of "password=...".
+type pattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+var secretPatterns = []pattern{
+	{"private_key", regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`)},
+	{"aws_access_key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"anthropic_key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"openai_key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`)},
+	{"stripe_key", regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{"google_api_key", regexp.MustCompile(`\bAIza[A-Za-z0-9_-]{35}`)},
+	{"slack_token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"cortex_key", regexp.MustCompile(`\bcortex_[0-9a-f]{64}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"url_password", regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:([^\s@/]+)@`)},
+	{"credential", regexp.MustCompile(`(?i)\b\w*(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)\b["']?\s*[:=]\s*["']?([^\s"',;]{8,})`)},
+}
+
+// patternDetector flags matches of regular expressions.
+type patternDetector struct {
+	name     string
+	patterns []pattern
+}
+
+func (d patternDetector) scan(text string) []Finding {
+	var findings []Finding
+	for _, p := range d.patterns {
+		for _, m := range p.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			findings = append(findings, Finding{Detector: d.name, Kind: p.kind, Start: start, End: end})
+		}
+	}
+	return findings
+}
+
+// Entropy detector thresholds. Random base64 of this length scores about 4.3
+// bits per character; English words and identifiers score well below 4.
+const (
+	minEntropyTokenLength = 20
+	minEntropy            = 4.0
+)
+
+var entropyTokens = regexp.MustCompile(`[A-Za-z0-9+/_-]{20,}={0,2}`)
+
+// entropyDetector flags long tokens of letters and digits whose characters
+// are close to random. Hex strings, such as commit hashes and UUIDs, are not
+// flagged; hex secrets of known formats are caught by the secrets detector.
+type entropyDetector struct{}
+
+func (entropyDetector) scan(text string) []Finding {
+	var findings []Finding
+	for _, m := range entropyTokens.FindAllStringIndex(text, -1) {
+		token := text[m[0]:m[1]]
+		if len(token) < minEntropyTokenLength || isHex(token) || !hasLetterAndDigit(token) {
+			continue
+		}
+		if shannonEntropy(token) >= minEntropy {
+			findings = append(findings, Finding{Detector: DetectorEntropy, Kind: "high_entropy", Start: m[0], End: m[1]})
+		}
+	}
+	return findings
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+func isHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdefABCDEF-") == ""
+}
+
+func hasLetterAndDigit(s string) bool {
+	return strings.ContainsAny(s, "0123456789") &&
+		strings.ContainsAny(strings.ToLower(s), "abcdefghijklmnopqrstuvwxyz")
+}
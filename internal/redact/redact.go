@@ -0,0 +1,187 @@
+// Package redact detects secrets and personal data in memory text, so they can
+// be rejected, masked or kept away from LLM providers before a memory is stored.
+package redact
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Mode selects what happens to text in which a detector finds something.
+type Mode string
+
+const (
+	ModeOff    Mode = "off"    // store text as is
+	ModeReject Mode = "reject" // refuse to store it
+	ModeMask   Mode = "mask"   // replace each finding with [REDACTED:<kind>]
+	ModeNoLLM  Mode = "no-llm" // store it, but never send it to an LLM provider
+)
+
+// ParseMode validates a mode name. The empty string means ModeOff.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeOff:
+		return ModeOff, nil
+	case ModeReject, ModeMask, ModeNoLLM:
+		return Mode(s), nil
+	default:
+		return "", fmt.Errorf("unknown redaction mode %q (expected off, reject, mask or no-llm)", s)
+	}
+}
+
+// Meta keys recording what redaction did to a memory.
+const (
+	MetaRedacted = "redacted" // kinds masked in the stored text
+	MetaNoLLM    = "no_llm"   // kinds found in text that is never sent to an LLM
+)
+
+// SkipsLLM reports whether a memory's meta marks it as never to be sent to an
+// LLM provider, for embedding, entity extraction, normalization or conflict checks.
+func SkipsLLM(meta map[string]any) bool {
+	_, ok := meta[MetaNoLLM]
+	return ok
+}
+
+// Finding is a span of text a detector flagged. It does not hold the text itself.
+type Finding struct {
+	Detector string `json:"detector"` // secrets, entropy, email or phone
+	Kind     string `json:"kind"`     // what was found, e.g. aws_access_key or email
+	Start    int    `json:"start"`    // byte offsets in the text
+	End      int    `json:"end"`
+}
+
+// Redactor scans text with a set of detectors and applies a mode to findings.
+type Redactor struct {
+	mode      Mode
+	detectors []detector
+}
+
+// New returns a redactor running the named detectors (see Detectors).
+func New(mode Mode, names []string) (*Redactor, error) {
+	r := &Redactor{mode: mode}
+	for _, name := range names {
+		d, ok := detectorsByName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction detector %q (expected %s)", name, strings.Join(Detectors, ", "))
+		}
+		r.detectors = append(r.detectors, d)
+	}
+	return r, nil
+}
+
+// Mode returns the redactor's mode.
+func (r *Redactor) Mode() Mode {
+	return r.mode
+}
+
+// Scan returns the findings in text, in order and without overlaps. Where
+// findings overlap, the one starting first wins, then the longest.
+func (r *Redactor) Scan(text string) []Finding {
+	var all []Finding
+	for _, d := range r.detectors {
+		all = append(all, d.scan(text)...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Start != all[j].Start {
+			return all[i].Start < all[j].Start
+		}
+		return all[i].End > all[j].End
+	})
+
+	findings := all[:0]
+	end := -1
+	for _, f := range all {
+		if f.Start >= end {
+			findings = append(findings, f)
+			end = f.End
+		}
+	}
+	return findings
+}
+
+// Result is text prepared for storage.
+type Result struct {
+	Text     string    // the text to store
+	Findings []Finding // what the detectors found in the original text
+	NoLLM    bool      // the text must not be sent to an LLM provider
+}
+
+// Apply scans text and applies the mode to what it finds. In ModeReject a
+// finding is an error, which names the kinds found but not the text.
+func (r *Redactor) Apply(text string) (Result, error) {
+	if r == nil || r.mode == ModeOff {
+		return Result{Text: text}, nil
+	}
+	result := Result{Text: text, Findings: r.Scan(text)}
+	if len(result.Findings) == 0 {
+		return result, nil
+	}
+
+	switch r.mode {
+	case ModeReject:
+		return Result{}, fmt.Errorf("text contains %s; remove it or store a reference instead", describe(result.Findings))
+	case ModeMask:
+		result.Text = Mask(text, result.Findings)
+	case ModeNoLLM:
+		result.NoLLM = true
+	}
+	return result, nil
+}
+
+// Annotate records the result in meta: the kinds masked, or that the memory
+// must not be sent to an LLM. Marks left by earlier writes are cleared. It
+// returns meta, allocating it if needed.
+func (res Result) Annotate(meta map[string]any) map[string]any {
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	delete(meta, MetaRedacted)
+	delete(meta, MetaNoLLM)
+	if len(res.Findings) == 0 {
+		return meta
+	}
+	if res.NoLLM {
+		meta[MetaNoLLM] = Kinds(res.Findings)
+	} else {
+		meta[MetaRedacted] = Kinds(res.Findings)
+	}
+	return meta
+}
+
+// Mask replaces each finding in text with [REDACTED:<kind>]. Findings must be
+// in order and must not overlap, as returned by Scan.
+func Mask(text string, findings []Finding) string {
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		b.WriteString(text[last:f.Start])
+		b.WriteString("[REDACTED:" + f.Kind + "]")
+		last = f.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Kinds returns the distinct kinds of findings, in order of first appearance.
+func Kinds(findings []Finding) []string {
+	var kinds []string
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		if !seen[f.Kind] {
+			seen[f.Kind] = true
+			kinds = append(kinds, f.Kind)
+		}
+	}
+	return kinds
+}
+
+// describe summarizes findings without revealing them, e.g. "a possible
+// email" or "3 possible secrets or personal data (aws_access_key, email)".
+func describe(findings []Finding) string {
+	kinds := Kinds(findings)
+	if len(findings) == 1 {
+		return "a possible " + kinds[0]
+	}
+	return fmt.Sprintf("%d possible secrets or personal data (%s)", len(findings), strings.Join(kinds, ", "))
+}
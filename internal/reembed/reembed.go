@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
 )
 
 // EmbeddingProvider generates embeddings for text.
@@ -50,6 +51,7 @@ type Reembedder struct {
 	tenantID    string
 	workspaceID string
 	config      Config
+	openText    func(string) (string, error)
 }
 
 // NewReembedder creates a new batch re-embedder.
@@ -69,6 +71,24 @@ func (r *Reembedder) WithConfig(cfg Config) *Reembedder {
 	return r
 }
 
+// WithTextOpener sets a function returning the plaintext of stored text, such
+// as db.DB.OpenText for encrypted workspaces, and returns the Reembedder for chaining.
+func (r *Reembedder) WithTextOpener(open func(string) (string, error)) *Reembedder {
+	r.openText = open
+	return r
+}
+
+// embedText embeds the plaintext of stored text.
+func (r *Reembedder) embedText(ctx context.Context, text string) ([]float32, error) {
+	if r.openText != nil {
+		var err error
+		if text, err = r.openText(text); err != nil {
+			return nil, err
+		}
+	}
+	return r.provider.Embed(ctx, text)
+}
+
 // Stats holds statistics about the re-embedding process.
 type Stats struct {
 	Total     int64
@@ -79,17 +99,61 @@ type Stats struct {
 }
 
 // ReembedAll re-embeds all memories for the tenant/workspace.
+// Documents split into chunks are skipped; their chunks are embedded instead.
+// Memories marked no_llm by redaction are skipped too.
 // The progress callback is called after each memory is processed.
 func (r *Reembedder) ReembedAll(ctx context.Context, progress ProgressCallback) (*Stats, error) {
+	return r.run(ctx, selection{replace: true}, progress)
+}
+
+// ReembedMissing embeds only memories that have no embedding for the target model,
+// such as memories whose embedding failed when they were added. Memories are
+// visited in ID order so that batches stay stable while embeddings are written.
+// Memories marked no_llm by redaction are never embedded, so they are not missing.
+func (r *Reembedder) ReembedMissing(ctx context.Context, progress ProgressCallback) (*Stats, error) {
+	return r.run(ctx, selection{
+		where: `NOT EXISTS (
+			SELECT 1 FROM memory_embeddings e
+			WHERE e.memory_id = m.id AND e.model = $3
+		  )`,
+		args:   []any{r.model()},
+		keyset: true,
+	}, progress)
+}
+
+// selection picks the memories a batch run visits, on top of the chunked
+// documents and no_llm memories that are never embedded.
+type selection struct {
+	// where is an extra predicate on memories m. Its placeholders start at
+	// $3, after the tenant and workspace.
+	where string
+	args  []any
+	// keyset pages by the last visited ID rather than by offset, for
+	// selections that shrink as embeddings are written.
+	keyset bool
+	// replace applies Config.SkipExisting and Config.DeleteOldEmbeddings.
+	// Backfills leave them off, since they only add missing embeddings and
+	// run once per model.
+	replace bool
+}
+
+// run embeds the memories of sel in batches of Config.BatchSize.
+func (r *Reembedder) run(ctx context.Context, sel selection, progress ProgressCallback) (*Stats, error) {
 	start := time.Now()
 	stats := &Stats{}
+	model := r.model()
+
+	where := `m.tenant_id = $1 AND m.workspace_id = $2
+		  AND NOT EXISTS (SELECT 1 FROM memories c WHERE c.parent_id = m.id)
+		  AND NOT COALESCE(m.meta ? 'no_llm', false)`
+	if sel.where != "" {
+		where += "\n\t\t  AND " + sel.where
+	}
+	args := append([]any{r.tenantID, r.workspaceID}, sel.args...)
 
 	// Get total count
 	var total int64
-	err := r.pool.QueryRow(ctx, `
-		SELECT COUNT(*) FROM memories
-		WHERE tenant_id = $1 AND workspace_id = $2
-	`, r.tenantID, r.workspaceID).Scan(&total)
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM memories m WHERE `+where, args...).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("count memories: %w", err)
 	}
@@ -99,13 +163,14 @@ func (r *Reembedder) ReembedAll(ctx context.Context, progress ProgressCallback)
 		return stats, nil
 	}
 
-	model := r.config.TargetModel
-	if model == "" {
-		model = r.provider.Model()
+	// Page by offset, or resume after the last visited ID
+	n := len(args)
+	query := fmt.Sprintf(`SELECT m.id, m.text FROM memories m WHERE %s ORDER BY m.id LIMIT $%d OFFSET $%d`, where, n+1, n+2)
+	if sel.keyset {
+		query = fmt.Sprintf(`SELECT m.id, m.text FROM memories m WHERE %s AND m.id > $%d ORDER BY m.id LIMIT $%d`, where, n+1, n+2)
 	}
 
-	// Process in batches
-	var offset int64
+	var offset, lastID int64
 	for {
 		select {
 		case <-ctx.Done():
@@ -114,13 +179,11 @@ func (r *Reembedder) ReembedAll(ctx context.Context, progress ProgressCallback)
 		default:
 		}
 
-		// Get batch of memories
-		rows, err := r.pool.Query(ctx, `
-			SELECT id, text FROM memories
-			WHERE tenant_id = $1 AND workspace_id = $2
-			ORDER BY id
-			LIMIT $3 OFFSET $4
-		`, r.tenantID, r.workspaceID, r.config.BatchSize, offset)
+		pageArgs := append(args[:n:n], r.config.BatchSize, offset)
+		if sel.keyset {
+			pageArgs = append(args[:n:n], lastID, r.config.BatchSize)
+		}
+		rows, err := r.pool.Query(ctx, query, pageArgs...)
 		if err != nil {
 			return nil, fmt.Errorf("query memories: %w", err)
 		}
@@ -151,7 +214,7 @@ func (r *Reembedder) ReembedAll(ctx context.Context, progress ProgressCallback)
 			var processErr error
 
 			// Check if embedding exists and skip if configured
-			if r.config.SkipExisting {
+			if sel.replace && r.config.SkipExisting {
 				var exists bool
 				err := r.pool.QueryRow(ctx, `
 					SELECT EXISTS(
@@ -169,34 +232,16 @@ func (r *Reembedder) ReembedAll(ctx context.Context, progress ProgressCallback)
 				}
 			}
 
-			// Generate embedding
-			embedding, err := r.provider.Embed(ctx, m.Text)
+			embedding, err := r.embedText(ctx, m.Text)
 			if err != nil {
 				processErr = fmt.Errorf("embed memory %d: %w", m.ID, err)
 				stats.Errors++
-			} else {
-				// Store embedding
-				_, err = r.pool.Exec(ctx, `
-					INSERT INTO memory_embeddings (memory_id, model, dims, embedding)
-					VALUES ($1, $2, $3, $4)
-					ON CONFLICT (memory_id, model) DO UPDATE SET
-						dims = EXCLUDED.dims,
-						embedding = EXCLUDED.embedding
-				`, m.ID, model, len(embedding), embedding)
-				if err != nil {
-					processErr = fmt.Errorf("store embedding for memory %d: %w", m.ID, err)
-					stats.Errors++
-				}
-
-				// Delete old embeddings if configured
-				if r.config.DeleteOldEmbeddings && processErr == nil {
-					_, err = r.pool.Exec(ctx, `
-						DELETE FROM memory_embeddings
-						WHERE memory_id = $1 AND model != $2
-					`, m.ID, model)
-					if err != nil {
-						log.Printf("reembed: warning: failed to delete old embeddings for memory %d: %v", m.ID, err)
-					}
+			} else if err := r.store(ctx, m.ID, model, embedding); err != nil {
+				processErr = fmt.Errorf("store embedding for memory %d: %w", m.ID, err)
+				stats.Errors++
+			} else if sel.replace && r.config.DeleteOldEmbeddings {
+				if err := r.deleteOld(ctx, m.ID, model); err != nil {
+					log.Printf("reembed: warning: failed to delete old embeddings for memory %d: %v", m.ID, err)
 				}
 			}
 
@@ -207,6 +252,7 @@ func (r *Reembedder) ReembedAll(ctx context.Context, progress ProgressCallback)
 		}
 
 		offset += int64(len(memories))
+		lastID = memories[len(memories)-1].ID
 
 		// Rate limiting delay between batches
 		if r.config.DelayBetweenBatches > 0 {
@@ -218,12 +264,38 @@ func (r *Reembedder) ReembedAll(ctx context.Context, progress ProgressCallback)
 	return stats, nil
 }
 
+// model returns the model embeddings are stored under.
+func (r *Reembedder) model() string {
+	if r.config.TargetModel != "" {
+		return r.config.TargetModel
+	}
+	return r.provider.Model()
+}
+
+// store writes the embedding of a memory for model, replacing any earlier one.
+func (r *Reembedder) store(ctx context.Context, memoryID int64, model string, embedding []float32) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO memory_embeddings (memory_id, model, dims, embedding)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (memory_id, model) DO UPDATE SET
+			dims = EXCLUDED.dims,
+			embedding = EXCLUDED.embedding
+	`, memoryID, model, len(embedding), pgvector.NewVector(embedding))
+	return err
+}
+
+// deleteOld removes the embeddings of a memory from models other than model.
+func (r *Reembedder) deleteOld(ctx context.Context, memoryID int64, model string) error {
+	_, err := r.pool.Exec(ctx, `
+		DELETE FROM memory_embeddings
+		WHERE memory_id = $1 AND model != $2
+	`, memoryID, model)
+	return err
+}
+
 // ReembedMemory re-embeds a single memory.
 func (r *Reembedder) ReembedMemory(ctx context.Context, memoryID int64) error {
-	model := r.config.TargetModel
-	if model == "" {
-		model = r.provider.Model()
-	}
+	model := r.model()
 
 	// Get memory text
 	var text string
@@ -236,30 +308,18 @@ func (r *Reembedder) ReembedMemory(ctx context.Context, memoryID int64) error {
 	}
 
 	// Generate embedding
-	embedding, err := r.provider.Embed(ctx, text)
+	embedding, err := r.embedText(ctx, text)
 	if err != nil {
 		return fmt.Errorf("embed: %w", err)
 	}
 
-	// Store embedding
-	_, err = r.pool.Exec(ctx, `
-		INSERT INTO memory_embeddings (memory_id, model, dims, embedding)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (memory_id, model) DO UPDATE SET
-			dims = EXCLUDED.dims,
-			embedding = EXCLUDED.embedding
-	`, memoryID, model, len(embedding), embedding)
-	if err != nil {
+	if err := r.store(ctx, memoryID, model, embedding); err != nil {
 		return fmt.Errorf("store embedding: %w", err)
 	}
 
 	// Delete old embeddings if configured
 	if r.config.DeleteOldEmbeddings {
-		_, err = r.pool.Exec(ctx, `
-			DELETE FROM memory_embeddings
-			WHERE memory_id = $1 AND model != $2
-		`, memoryID, model)
-		if err != nil {
+		if err := r.deleteOld(ctx, memoryID, model); err != nil {
 			return fmt.Errorf("delete old embeddings: %w", err)
 		}
 	}
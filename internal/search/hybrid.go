@@ -4,6 +4,7 @@ package search
 import (
 	"context"
 	"sort"
+	"time"
 
 	"github.com/johnswift/cortex/internal/db"
 )
@@ -15,9 +16,10 @@ type EmbeddingProvider interface {
 
 // HybridSearcher combines vector and lexical search with score fusion.
 type HybridSearcher struct {
-	db    *db.DB
-	embed EmbeddingProvider
-	alpha float32 // vector weight, default 0.7
+	db      *db.DB
+	embed   EmbeddingProvider
+	alpha   float32         // vector weight, default 0.7
+	ranking *RankingOptions // optional boosts applied after fusion
 }
 
 // NewHybridSearcher creates a new hybrid searcher.
@@ -36,9 +38,32 @@ func NewHybridSearcher(database *db.DB, embedder EmbeddingProvider) *HybridSearc
 // WithAlpha returns a new HybridSearcher with the specified alpha value.
 func (h *HybridSearcher) WithAlpha(alpha float32) *HybridSearcher {
 	return &HybridSearcher{
-		db:    h.db,
-		embed: h.embed,
-		alpha: alpha,
+		db:      h.db,
+		embed:   h.embed,
+		alpha:   alpha,
+		ranking: h.ranking,
+	}
+}
+
+// WithRanking returns a new HybridSearcher that boosts fused scores by
+// importance, recency and access count before truncating results.
+func (h *HybridSearcher) WithRanking(opts RankingOptions) *HybridSearcher {
+	return &HybridSearcher{
+		db:      h.db,
+		embed:   h.embed,
+		alpha:   h.alpha,
+		ranking: &opts,
+	}
+}
+
+// WithDB returns a new HybridSearcher that searches through database, e.g.
+// one bound to another workspace of the tenant.
+func (h *HybridSearcher) WithDB(database *db.DB) *HybridSearcher {
+	return &HybridSearcher{
+		db:      database,
+		embed:   h.embed,
+		alpha:   h.alpha,
+		ranking: h.ranking,
 	}
 }
 
@@ -49,6 +74,13 @@ type SearchParams struct {
 	Hybrid bool    // false = vector only, true = hybrid fusion
 	Alpha  float32 // 0 = lexical only, 1 = vector only (overrides default if > 0)
 	Model  string  // Optional: filter by embedding model (empty = any model)
+
+	IncludeArchived bool // also search memories the sweeper moved to the archive tier
+
+	// Workspaces of the tenant to search; empty means the searcher's workspace.
+	// AllWorkspaces searches every workspace of the tenant.
+	Workspaces    []string
+	AllWorkspaces bool
 }
 
 // SearchResult is a memory with fused score.
@@ -60,6 +92,32 @@ type SearchResult struct {
 	Tags       []string `json:"tags"`
 	Importance float32  `json:"importance"`
 	Score      float32  `json:"score"`
+
+	// Chunk is set when a document matched through one of its chunks.
+	// ID and metadata then describe the document, and Text is the chunk's text.
+	Chunk *ChunkMatch `json:"chunk,omitempty"`
+
+	// SupersededBy lists the memories that supersede this one, newest first.
+	SupersededBy []int64 `json:"superseded_by,omitempty"`
+
+	AccessCount    int64      `json:"access_count"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+
+	// Archived is set for memories found in the archive tier.
+	Archived bool `json:"archived,omitempty"`
+
+	// Workspace is the workspace the memory belongs to.
+	Workspace string `json:"workspace"`
+
+	createdAt  time.Time
+	parentID   *int64
+	chunkIndex *int
+}
+
+// ChunkMatch identifies the chunk through which a document matched.
+type ChunkMatch struct {
+	ID    int64 `json:"id"`
+	Index int   `json:"index"`
 }
 
 // Search performs hybrid search with score fusion.
@@ -84,29 +142,68 @@ func (h *HybridSearcher) Search(ctx context.Context, params SearchParams) ([]Sea
 
 	// Vector-only search
 	if !params.Hybrid {
-		return h.vectorOnlySearch(ctx, embedding, params.Limit, params.Model)
+		return h.vectorOnlySearch(ctx, embedding, params)
 	}
 
 	// Hybrid search with score fusion
-	return h.hybridSearch(ctx, params.Query, embedding, params.Limit, alpha, params.Model)
+	return h.hybridSearch(ctx, embedding, alpha, params)
+}
+
+// vectorSearch runs a vector search over live memories and, if includeArchived,
+// the archive tier.
+func (h *HybridSearcher) vectorSearch(ctx context.Context, params db.VectorSearchParams, includeArchived bool) ([]db.MemoryWithScore, error) {
+	results, err := h.db.VectorSearch(ctx, params)
+	if err != nil || !includeArchived {
+		return results, err
+	}
+
+	params.Archived = true
+	archived, err := h.db.VectorSearch(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return append(results, archived...), nil
+}
+
+// lexicalSearch runs a lexical search over live memories and, if includeArchived,
+// the archive tier.
+func (h *HybridSearcher) lexicalSearch(ctx context.Context, params db.LexicalSearchParams, includeArchived bool) ([]db.MemoryWithScore, error) {
+	results, err := h.db.LexicalSearch(ctx, params)
+	if err != nil || !includeArchived {
+		return results, err
+	}
+
+	params.Archived = true
+	archived, err := h.db.LexicalSearch(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return append(results, archived...), nil
 }
 
 // vectorOnlySearch performs pure vector similarity search.
-func (h *HybridSearcher) vectorOnlySearch(ctx context.Context, embedding []float32, limit int, model string) ([]SearchResult, error) {
-	results, err := h.db.VectorSearch(ctx, db.VectorSearchParams{
-		Embedding: embedding,
-		Limit:     limit,
-		Model:     model,
-	})
+func (h *HybridSearcher) vectorOnlySearch(ctx context.Context, embedding []float32, params SearchParams) ([]SearchResult, error) {
+	limit := params.Limit
+
+	// Fetch extra results since several chunks of one document collapse into one
+	results, err := h.vectorSearch(ctx, db.VectorSearchParams{
+		Embedding:     embedding,
+		Limit:         limit * 3,
+		Model:         params.Model,
+		Workspaces:    params.Workspaces,
+		AllWorkspaces: params.AllWorkspaces,
+	}, params.IncludeArchived)
 	if err != nil {
 		return nil, err
 	}
 
-	return memoriesToResults(results), nil
+	return h.collapseAndTruncate(ctx, memoriesToResults(results), limit)
 }
 
 // hybridSearch performs combined vector and lexical search with score fusion.
-func (h *HybridSearcher) hybridSearch(ctx context.Context, query string, embedding []float32, limit int, alpha float32, model string) ([]SearchResult, error) {
+func (h *HybridSearcher) hybridSearch(ctx context.Context, embedding []float32, alpha float32, params SearchParams) ([]SearchResult, error) {
+	limit := params.Limit
+
 	// Fetch more results than needed to improve fusion quality
 	fetchLimit := limit * 3
 	if fetchLimit < 20 {
@@ -114,19 +211,23 @@ func (h *HybridSearcher) hybridSearch(ctx context.Context, query string, embeddi
 	}
 
 	// Run vector and lexical searches
-	vectorResults, err := h.db.VectorSearch(ctx, db.VectorSearchParams{
-		Embedding: embedding,
-		Limit:     fetchLimit,
-		Model:     model,
-	})
+	vectorResults, err := h.vectorSearch(ctx, db.VectorSearchParams{
+		Embedding:     embedding,
+		Limit:         fetchLimit,
+		Model:         params.Model,
+		Workspaces:    params.Workspaces,
+		AllWorkspaces: params.AllWorkspaces,
+	}, params.IncludeArchived)
 	if err != nil {
 		return nil, err
 	}
 
-	lexicalResults, err := h.db.LexicalSearch(ctx, db.LexicalSearchParams{
-		Query: query,
-		Limit: fetchLimit,
-	})
+	lexicalResults, err := h.lexicalSearch(ctx, db.LexicalSearchParams{
+		Query:         params.Query,
+		Limit:         fetchLimit,
+		Workspaces:    params.Workspaces,
+		AllWorkspaces: params.AllWorkspaces,
+	}, params.IncludeArchived)
 	if err != nil {
 		return nil, err
 	}
@@ -138,12 +239,10 @@ func (h *HybridSearcher) hybridSearch(ctx context.Context, query string, embeddi
 
 	// If one set is empty, return the other
 	if len(vectorResults) == 0 {
-		results := memoriesToResults(lexicalResults)
-		return truncateResults(results, limit), nil
+		return h.collapseAndTruncate(ctx, memoriesToResults(lexicalResults), limit)
 	}
 	if len(lexicalResults) == 0 {
-		results := memoriesToResults(vectorResults)
-		return truncateResults(results, limit), nil
+		return h.collapseAndTruncate(ctx, memoriesToResults(vectorResults), limit)
 	}
 
 	// Normalize scores to 0-1 range
@@ -189,23 +288,147 @@ func (h *HybridSearcher) hybridSearch(ctx context.Context, query string, embeddi
 	// Convert to slice and sort by fused score
 	results := make([]SearchResult, 0, len(merged))
 	for _, fr := range merged {
-		results = append(results, SearchResult{
-			ID:         fr.memory.ID,
-			Text:       fr.memory.Text,
-			Kind:       fr.memory.Kind,
-			Source:     fr.memory.Source,
-			Tags:       fr.memory.Tags,
-			Importance: fr.memory.Importance,
-			Score:      fr.score,
-		})
-	}
-
-	// Sort by score descending
-	sort.Slice(results, func(i, j int) bool {
+		r := memoryToResult(fr.memory)
+		r.Score = fr.score
+		results = append(results, r)
+	}
+
+	return h.collapseAndTruncate(ctx, results, limit)
+}
+
+// collapseAndTruncate sorts results by score, collapses chunks into their
+// documents, applies ranking boosts if configured and returns at most limit
+// results, annotated with the memories that supersede them.
+func (h *HybridSearcher) collapseAndTruncate(ctx context.Context, results []SearchResult, limit int) ([]SearchResult, error) {
+	sort.SliceStable(results, func(i, j int) bool {
 		return results[i].Score > results[j].Score
 	})
 
-	return truncateResults(results, limit), nil
+	collapsed, err := h.collapseChunks(ctx, results)
+	if err != nil {
+		return nil, err
+	}
+	if h.ranking != nil {
+		createdTimes := make(map[int64]time.Time, len(collapsed))
+		for _, r := range collapsed {
+			createdTimes[r.ID] = r.createdAt
+		}
+		collapsed = ApplyBoosts(collapsed, createdTimes, *h.ranking)
+	}
+
+	results = truncateResults(collapsed, limit)
+
+	if err := h.annotateSuperseded(ctx, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// annotateSuperseded sets SupersededBy on results that have been superseded.
+// Links are looked up in the workspace of each result.
+func (h *HybridSearcher) annotateSuperseded(ctx context.Context, results []SearchResult) error {
+	ids := make(map[string][]int64)
+	for _, r := range results {
+		ids[r.Workspace] = append(ids[r.Workspace], r.ID)
+	}
+
+	superseded := make(map[int64][]int64)
+	for workspace, wsIDs := range ids {
+		found, err := h.db.WithWorkspace(workspace).SupersededBy(ctx, wsIDs)
+		if err != nil {
+			return err
+		}
+		for id, by := range found {
+			superseded[id] = by
+		}
+	}
+	for i := range results {
+		results[i].SupersededBy = superseded[results[i].ID]
+	}
+	return nil
+}
+
+// collapseChunks replaces chunk results with their parent document, keeping
+// only the best-scoring chunk of each document. Results must be sorted by
+// score descending.
+func (h *HybridSearcher) collapseChunks(ctx context.Context, results []SearchResult) ([]SearchResult, error) {
+	// Documents are in the workspace of their chunks
+	parentIDs := make(map[string][]int64)
+	archivedParentIDs := make(map[string][]int64)
+	for _, r := range results {
+		if r.parentID == nil {
+			continue
+		}
+		if r.Archived {
+			archivedParentIDs[r.Workspace] = append(archivedParentIDs[r.Workspace], *r.parentID)
+		} else {
+			parentIDs[r.Workspace] = append(parentIDs[r.Workspace], *r.parentID)
+		}
+	}
+	if len(parentIDs) == 0 && len(archivedParentIDs) == 0 {
+		return results, nil
+	}
+
+	parents := make(map[int64]db.Memory)
+	for workspace, ids := range parentIDs {
+		found, err := h.db.WithWorkspace(workspace).GetMemories(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for id, m := range found {
+			parents[id] = m
+		}
+	}
+	archivedParents := make(map[int64]db.Memory)
+	for workspace, ids := range archivedParentIDs {
+		found, err := h.db.WithWorkspace(workspace).GetArchivedMemories(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for id, m := range found {
+			archivedParents[id] = m
+		}
+	}
+
+	collapsed := make([]SearchResult, 0, len(results))
+	seen := make(map[int64]bool, len(results))
+	for _, r := range results {
+		if r.parentID != nil {
+			lookup := parents
+			if r.Archived {
+				lookup = archivedParents
+			}
+			if parent, ok := lookup[*r.parentID]; ok {
+				index := 0
+				if r.chunkIndex != nil {
+					index = *r.chunkIndex
+				}
+				r = SearchResult{
+					ID:         parent.ID,
+					Text:       r.Text,
+					Kind:       parent.Kind,
+					Source:     parent.Source,
+					Tags:       parent.Tags,
+					Importance: parent.Importance,
+					Score:      r.Score,
+					Chunk:      &ChunkMatch{ID: r.ID, Index: index},
+
+					AccessCount:    parent.AccessCount,
+					LastAccessedAt: parent.LastAccessedAt,
+					Archived:       r.Archived,
+					Workspace:      parent.WorkspaceID,
+					createdAt:      parent.CreatedAt,
+				}
+			}
+		}
+		if seen[r.ID] {
+			continue
+		}
+		seen[r.ID] = true
+		collapsed = append(collapsed, r)
+	}
+
+	return collapsed, nil
 }
 
 // fusedResult holds a memory with its fused score during merging.
@@ -243,19 +466,31 @@ func normalizeScores(results []db.MemoryWithScore) {
 func memoriesToResults(memories []db.MemoryWithScore) []SearchResult {
 	results := make([]SearchResult, len(memories))
 	for i, m := range memories {
-		results[i] = SearchResult{
-			ID:         m.ID,
-			Text:       m.Text,
-			Kind:       m.Kind,
-			Source:     m.Source,
-			Tags:       m.Tags,
-			Importance: m.Importance,
-			Score:      m.Score,
-		}
+		results[i] = memoryToResult(m)
 	}
 	return results
 }
 
+// memoryToResult converts a MemoryWithScore to a SearchResult.
+func memoryToResult(m db.MemoryWithScore) SearchResult {
+	return SearchResult{
+		ID:             m.ID,
+		Text:           m.Text,
+		Kind:           m.Kind,
+		Source:         m.Source,
+		Tags:           m.Tags,
+		Importance:     m.Importance,
+		Score:          m.Score,
+		AccessCount:    m.AccessCount,
+		LastAccessedAt: m.LastAccessedAt,
+		Archived:       m.Archived,
+		Workspace:      m.WorkspaceID,
+		createdAt:      m.CreatedAt,
+		parentID:       m.ParentID,
+		chunkIndex:     m.ChunkIndex,
+	}
+}
+
 // truncateResults returns at most limit results.
 func truncateResults(results []SearchResult, limit int) []SearchResult {
 	if len(results) <= limit {
@@ -11,18 +11,22 @@ type RankingOptions struct {
 	ImportanceWeight float32       // boost multiplier for importance (0 = disabled)
 	RecencyWeight    float32       // boost multiplier for recency (0 = disabled)
 	RecencyHalfLife  time.Duration // decay rate for recency boost
+	AccessWeight     float32       // boost multiplier for access count (0 = disabled)
+	AccessSaturation int64         // access count at which the access boost reaches ~63% of its weight
 }
 
 // DefaultRankingOptions returns sensible default ranking options.
 func DefaultRankingOptions() RankingOptions {
 	return RankingOptions{
-		ImportanceWeight: 0.2,              // 20% boost for max importance
-		RecencyWeight:    0.1,              // 10% boost for recent items
+		ImportanceWeight: 0.2,                // 20% boost for max importance
+		RecencyWeight:    0.1,                // 10% boost for recent items
 		RecencyHalfLife:  7 * 24 * time.Hour, // 1 week half-life
+		AccessWeight:     0,                  // disabled; enable to favor frequently retrieved memories
+		AccessSaturation: 10,
 	}
 }
 
-// ApplyBoosts applies importance and recency boosts to search results.
+// ApplyBoosts applies importance, recency and access boosts to search results.
 // The createdTimes map should contain creation timestamps keyed by memory ID.
 // Results are re-sorted by boosted score.
 //
@@ -30,7 +34,8 @@ func DefaultRankingOptions() RankingOptions {
 //   - importance_boost = 1 + opts.ImportanceWeight * importance
 //   - age = now - created_at
 //   - recency_boost = 1 + opts.RecencyWeight * exp(-age/halfLife)
-//   - final_score = score * importance_boost * recency_boost
+//   - access_boost = 1 + opts.AccessWeight * (1 - exp(-access_count/saturation))
+//   - final_score = score * importance_boost * recency_boost * access_boost
 func ApplyBoosts(results []SearchResult, createdTimes map[int64]time.Time, opts RankingOptions) []SearchResult {
 	if len(results) == 0 {
 		return results
@@ -54,7 +59,7 @@ func ApplyBoosts(results []SearchResult, createdTimes map[int64]time.Time, opts
 	return boosted
 }
 
-// calculateBoostedScore computes the final score with importance and recency boosts.
+// calculateBoostedScore computes the final score with importance, recency and access boosts.
 func calculateBoostedScore(result SearchResult, createdTimes map[int64]time.Time, opts RankingOptions, now time.Time) float32 {
 	score := result.Score
 
@@ -79,7 +84,19 @@ func calculateBoostedScore(result SearchResult, createdTimes map[int64]time.Time
 		}
 	}
 
-	return score * importanceBoost * recencyBoost
+	// Apply access boost: 1 + weight * (1 - exp(-count/saturation))
+	// Saturates so that a handful of reads matters more than the thousandth
+	accessBoost := float32(1.0)
+	if opts.AccessWeight > 0 && result.AccessCount > 0 {
+		saturation := opts.AccessSaturation
+		if saturation <= 0 {
+			saturation = 10
+		}
+		usage := 1 - math.Exp(-float64(result.AccessCount)/float64(saturation))
+		accessBoost = 1.0 + opts.AccessWeight*float32(usage)
+	}
+
+	return score * importanceBoost * recencyBoost * accessBoost
 }
 
 // ApplyBoostsWithTime is a convenience function that uses a custom "now" time.
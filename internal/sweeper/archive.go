@@ -0,0 +1,217 @@
+package sweeper
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/johnswift/cortex/internal/transfer"
+)
+
+// ArchiveMode selects what happens to memories the sweeper removes.
+type ArchiveMode string
+
+const (
+	ArchiveNone  ArchiveMode = "delete" // delete them
+	ArchiveTable ArchiveMode = "table"  // move them, with chunks and embeddings, to memories_archive
+	ArchiveFile  ArchiveMode = "file"   // write them to gzip-compressed JSONL in ArchiveDir, then delete them
+)
+
+// ParseArchiveMode validates an archive mode name. The empty string means ArchiveNone.
+func ParseArchiveMode(s string) (ArchiveMode, error) {
+	switch ArchiveMode(s) {
+	case "", ArchiveNone:
+		return ArchiveNone, nil
+	case ArchiveTable, ArchiveFile:
+		return ArchiveMode(s), nil
+	default:
+		return "", fmt.Errorf("unknown archive mode %q (expected delete, table or file)", s)
+	}
+}
+
+// archiveColumns are the columns copied from memories to memories_archive.
+const archiveColumns = `id, tenant_id, workspace_id, kind, text, source, created_at, updated_at,
+	tags, importance, ttl_days, ttl_from, meta, parent_id, chunk_index, last_accessed_at, access_count, decayed_at, blind_index`
+
+// removeWhere deletes the memories matching q, archiving them first according
+// to the archive mode. Chunks go with their document. Reason is recorded with
+// archived memories and in the audit log.
+func (s *Sweeper) removeWhere(ctx context.Context, q *query, reason string) (int64, error) {
+	switch s.config.Archive {
+	case ArchiveTable:
+		return s.archiveToTable(ctx, q, reason)
+	case ArchiveFile:
+		return s.archiveToFile(ctx, q, reason)
+	default:
+		return s.deleteWhere(ctx, q, reason)
+	}
+}
+
+// archiveToTable moves the memories matching q, with their chunks and
+// embeddings, to the archive tables in one transaction.
+func (s *Sweeper) archiveToTable(ctx context.Context, q *query, reason string) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids, err := selectIDs(ctx, tx, q)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO memories_archive (`+archiveColumns+`, archive_reason)
+		SELECT `+archiveColumns+`, $2
+		FROM memories
+		WHERE id = ANY($1) OR parent_id = ANY($1)
+		ON CONFLICT (id) DO NOTHING
+	`, ids, reason); err != nil {
+		return 0, fmt.Errorf("archive memories: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO memory_embeddings_archive (memory_id, model, dims, embedding)
+		SELECT e.memory_id, e.model, e.dims, e.embedding
+		FROM memory_embeddings e
+		JOIN memories m ON m.id = e.memory_id
+		WHERE m.id = ANY($1) OR m.parent_id = ANY($1)
+		ON CONFLICT (memory_id, model) DO NOTHING
+	`, ids); err != nil {
+		return 0, fmt.Errorf("archive embeddings: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM memories WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.recordAudit(ctx, tx, ActionArchive, reason, ids); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// archiveToFile exports the memories matching q, with their chunks and
+// embeddings, to a new gzip-compressed JSONL file and then deletes them.
+// Nothing is deleted unless every memory was written.
+func (s *Sweeper) archiveToFile(ctx context.Context, q *query, reason string) (int64, error) {
+	ids, err := selectIDs(ctx, s.pool, q)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT id FROM memories WHERE id = ANY($1) OR parent_id = ANY($1)`, ids)
+	if err != nil {
+		return 0, fmt.Errorf("select chunks: %w", err)
+	}
+	all, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("select chunks: %w", err)
+	}
+
+	path, err := s.writeArchiveFile(ctx, all, reason)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `DELETE FROM memories WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.recordAudit(ctx, tx, ActionArchive, reason, ids); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	log.Printf("[sweeper] archived %d memories (%s) to %s", len(all), reason, path)
+	return result.RowsAffected(), nil
+}
+
+// writeArchiveFile exports the given memories to a new file in ArchiveDir and
+// returns its path.
+func (s *Sweeper) writeArchiveFile(ctx context.Context, ids []int64, reason string) (string, error) {
+	if err := os.MkdirAll(s.config.ArchiveDir, 0o755); err != nil {
+		return "", fmt.Errorf("create archive directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s-%s-%s.jsonl.gz",
+		s.tenantID, s.workspaceID, time.Now().UTC().Format("20060102T150405.000"), reason)
+	path := filepath.Join(s.config.ArchiveDir, archiveFileName(name))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("create archive file: %w", err)
+	}
+
+	zw := gzip.NewWriter(f)
+	result, err := transfer.NewExporter(s.pool).Export(ctx, zw, transfer.ExportOptions{
+		IncludeEmbeddings: true,
+		IDs:               ids,
+	})
+	if err == nil && result.Errors > 0 {
+		err = fmt.Errorf("%d memories could not be exported", result.Errors)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("write archive file: %w", err)
+	}
+	return path, nil
+}
+
+// querier is implemented by both pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// selectIDs returns the IDs of the memories matching q.
+func selectIDs(ctx context.Context, conn querier, q *query) ([]int64, error) {
+	rows, err := conn.Query(ctx, `SELECT m.id FROM memories m WHERE `+q.String(), q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("select memories: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("select memories: %w", err)
+	}
+	return ids, nil
+}
+
+// archiveFileName replaces characters that are not safe in file names.
+func archiveFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,34 @@
+package sweeper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// auditActor is the actor and tool of the sweeper's audit log entries.
+const auditActor = "sweeper"
+
+// recordAudit appends a change to ids to the audit log of the sweeper's
+// workspace, in the transaction that makes the change. Action and reason are
+// those of Change.
+func (s *Sweeper) recordAudit(ctx context.Context, tx pgx.Tx, action, reason string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	detailJSON, err := json.Marshal(map[string]any{"action": action, "reason": reason})
+	if err != nil {
+		return fmt.Errorf("marshal detail: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO audit_log (tenant_id, workspace_id, actor, tool, memory_ids, detail)
+		VALUES ($1, $2, $3, $3, $4, $5)
+	`, s.tenantID, s.workspaceID, auditActor, ids, detailJSON)
+	if err != nil {
+		return fmt.Errorf("record audit entry: %w", err)
+	}
+	return nil
+}
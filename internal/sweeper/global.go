@@ -0,0 +1,164 @@
+package sweeper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lockKey is the advisory lock held while sweeping. In global mode all
+// instances share one lock; otherwise each workspace has its own. Global
+// sweeps also take the lock of each workspace while sweeping it.
+const lockKey = "cortex-sweeper"
+
+// WorkspaceStats counts what one sweep changed in a workspace.
+type WorkspaceStats struct {
+	TenantID      string   `json:"tenant_id"`
+	WorkspaceID   string   `json:"workspace_id"`
+	Expired       int64    `json:"expired"`        // removed by ttl_days
+	Evicted       int64    `json:"evicted"`        // removed as unaccessed
+	PolicyExpired int64    `json:"policy_expired"` // removed by retention policies
+	Decayed       int64    `json:"decayed"`
+	TodosArchived int64    `json:"todos_archived"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// Removed returns the number of memories deleted or moved to the archive tier.
+func (w WorkspaceStats) Removed() int64 {
+	return w.Expired + w.Evicted + w.PolicyExpired
+}
+
+func (w WorkspaceStats) changed() bool {
+	return w.Removed() > 0 || w.Decayed > 0 || w.TodosArchived > 0 || len(w.Errors) > 0
+}
+
+// RunStats describes one sweep. Only workspaces with changes or errors are listed.
+type RunStats struct {
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	Skipped    bool             `json:"skipped,omitempty"` // another instance held the sweep lock
+	Error      string           `json:"error,omitempty"`   // the sweep could not start
+	Workspaces []WorkspaceStats `json:"workspaces,omitempty"`
+
+	tenants []string // tenants whose workspaces a global sweep covered
+}
+
+// Stats accumulates sweeper activity since the process started.
+type Stats struct {
+	Runs    int64            `json:"runs"`
+	Skipped int64            `json:"skipped"`
+	Removed map[string]int64 `json:"removed"` // memories removed per "tenant/workspace"
+	LastRun *RunStats        `json:"last_run,omitempty"`
+}
+
+// Stats returns a snapshot of the sweeper's activity.
+func (s *Sweeper) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Removed = make(map[string]int64, len(s.stats.Removed))
+	for k, v := range s.stats.Removed {
+		stats.Removed[k] = v
+	}
+	return stats
+}
+
+// record adds a finished run to the sweeper's stats.
+func (s *Sweeper) record(run *RunStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Runs++
+	if run.Skipped {
+		s.stats.Skipped++
+	}
+	if s.stats.Removed == nil {
+		s.stats.Removed = make(map[string]int64)
+	}
+	for _, ws := range run.Workspaces {
+		if removed := ws.Removed(); removed > 0 {
+			s.stats.Removed[ws.TenantID+"/"+ws.WorkspaceID] += removed
+		}
+	}
+	s.stats.LastRun = run
+}
+
+// workspace identifies a tenant's workspace.
+type workspace struct {
+	tenantID    string
+	workspaceID string
+}
+
+// listWorkspaces returns every workspace that has memories.
+func (s *Sweeper) listWorkspaces(ctx context.Context) ([]workspace, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT tenant_id, workspace_id
+		FROM memories
+		ORDER BY tenant_id, workspace_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (workspace, error) {
+		var ws workspace
+		err := row.Scan(&ws.tenantID, &ws.workspaceID)
+		return ws, err
+	})
+}
+
+// forWorkspace returns a sweeper with the same configuration for another workspace.
+func (s *Sweeper) forWorkspace(ws workspace) *Sweeper {
+	return &Sweeper{
+		pool:        s.pool,
+		tenantID:    ws.tenantID,
+		workspaceID: ws.workspaceID,
+		config:      s.config,
+	}
+}
+
+// tryLock takes the sweep lock without waiting. It holds a pooled connection
+// until release is called, since advisory locks belong to a session; if the
+// process dies, Postgres releases the lock with the connection.
+func (s *Sweeper) tryLock(ctx context.Context) (release func(), locked bool, err error) {
+	if s.config.Global {
+		return s.tryLockKey(ctx, lockKey)
+	}
+	return s.tryLockWorkspace(ctx)
+}
+
+// tryLockWorkspace takes the sweep lock of the sweeper's workspace without waiting.
+func (s *Sweeper) tryLockWorkspace(ctx context.Context) (release func(), locked bool, err error) {
+	return s.tryLockKey(ctx, fmt.Sprintf("%s:%s/%s", lockKey, s.tenantID, s.workspaceID))
+}
+
+// tryLockKey takes the advisory lock named key without waiting.
+func (s *Sweeper) tryLockKey(ctx context.Context, key string) (release func(), locked bool, err error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, key).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, false, err
+	}
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	return func() {
+		// Unlock even if the sweep's context was cancelled
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock(hashtext($1))`, key); err != nil {
+			// Closing the session is the only other way to release the lock
+			log.Printf("[sweeper] error releasing sweep lock, closing connection: %v", err)
+			conn.Conn().Close(unlockCtx)
+		}
+		conn.Release()
+	}, true, nil
+}
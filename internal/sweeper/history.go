@@ -0,0 +1,88 @@
+package sweeper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// runHistory is how long finished runs are kept in sweeper_runs.
+const runHistory = 30 * 24 * time.Hour
+
+// saveRun records a finished run in sweeper_runs, with a row per listed
+// workspace, and prunes runs older than runHistory. Failures are only logged;
+// the run itself has already happened.
+func (s *Sweeper) saveRun(run *RunStats) {
+	// Save even if the sweep's context was cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.insertRun(ctx, run); err != nil {
+		log.Printf("[sweeper] error recording run: %v", err)
+	}
+}
+
+// insertRun records a run. Row-level security shows each tenant only its own
+// runs, so a global sweep is recorded once for every tenant it covered, with
+// the workspaces of that tenant.
+func (s *Sweeper) insertRun(ctx context.Context, run *RunStats) error {
+	var runErr *string
+	if run.Error != "" {
+		runErr = &run.Error
+	}
+	tenants := run.tenants
+	if !slices.Contains(tenants, s.tenantID) {
+		tenants = append([]string{s.tenantID}, tenants...)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, tenantID := range tenants {
+		var workspaces []WorkspaceStats
+		var removed int64
+		for _, ws := range run.Workspaces {
+			if ws.TenantID == tenantID {
+				workspaces = append(workspaces, ws)
+				removed += ws.Removed()
+			}
+		}
+
+		var id int64
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO sweeper_runs (tenant_id, workspace_id, global, started_at, finished_at, skipped, removed, error)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, tenantID, s.workspaceID, s.config.Global, run.StartedAt, run.FinishedAt, run.Skipped, removed, runErr).Scan(&id); err != nil {
+			return fmt.Errorf("insert run: %w", err)
+		}
+
+		for _, ws := range workspaces {
+			stepErrors := ws.Errors
+			if stepErrors == nil {
+				stepErrors = []string{}
+			}
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO sweeper_run_workspaces (run_id, tenant_id, workspace_id, expired, evicted, policy_expired,
+					decayed, todos_archived, errors)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			`, id, ws.TenantID, ws.WorkspaceID, ws.Expired, ws.Evicted, ws.PolicyExpired,
+				ws.Decayed, ws.TodosArchived, stepErrors); err != nil {
+				return fmt.Errorf("insert workspace stats: %w", err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM sweeper_runs WHERE started_at < NOW() - $1::float8 * INTERVAL '1 second'
+	`, runHistory.Seconds()); err != nil {
+		return fmt.Errorf("prune runs: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
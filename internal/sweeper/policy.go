@@ -0,0 +1,173 @@
+package sweeper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Times from which a policy's expiry is measured.
+const (
+	ExpireFromCreated   = "created"   // when the memory was created
+	ExpireFromUpdated   = "updated"   // when the memory was last updated
+	ExpireFromAccessed  = "accessed"  // when the memory was last read, or created if never read
+	ExpireFromCompleted = "completed" // when a todo was completed; unfinished todos never expire
+)
+
+// Policy is a retention rule for memories matching a set of kinds and tags.
+// A memory is governed by the first policy it matches; memories matching no
+// policy are only subject to their own ttl_days and unaccessed eviction.
+type Policy struct {
+	Name  string   `json:"name"`
+	Kinds []string `json:"kinds,omitempty"` // match any of these kinds; empty matches every kind
+	Tags  []string `json:"tags,omitempty"`  // match memories with any of these tags; empty matches every memory
+
+	// NeverExpire protects matching memories from every deletion the sweeper
+	// makes, including ttl_days and unaccessed eviction.
+	NeverExpire bool `json:"never_expire,omitempty"`
+
+	// ExpireAfterDays deletes matching memories this many days after ExpireFrom.
+	ExpireAfterDays int    `json:"expire_after_days,omitempty"`
+	ExpireFrom      string `json:"expire_from,omitempty"` // created (default), updated, accessed or completed
+
+	// DecayRate is the fraction of importance lost every DecayEveryDays, e.g.
+	// 0.1 every 30 days. Importance never decays below MinImportance.
+	DecayRate      float64 `json:"decay_rate,omitempty"`
+	DecayEveryDays int     `json:"decay_every_days,omitempty"`
+	MinImportance  float64 `json:"min_importance,omitempty"`
+}
+
+// Validate checks that the policy is consistent.
+func (p Policy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("policy name is required")
+	}
+	if p.NeverExpire && p.ExpireAfterDays > 0 {
+		return fmt.Errorf("policy %q: never_expire and expire_after_days are mutually exclusive", p.Name)
+	}
+	if p.ExpireAfterDays < 0 {
+		return fmt.Errorf("policy %q: expire_after_days must be non-negative", p.Name)
+	}
+	switch p.ExpireFrom {
+	case "", ExpireFromCreated, ExpireFromUpdated, ExpireFromAccessed, ExpireFromCompleted:
+	default:
+		return fmt.Errorf("policy %q: unknown expire_from %q (expected created, updated, accessed or completed)", p.Name, p.ExpireFrom)
+	}
+	if p.DecayRate < 0 || p.DecayRate >= 1 {
+		return fmt.Errorf("policy %q: decay_rate must be between 0 and 1", p.Name)
+	}
+	if p.DecayRate > 0 && p.DecayEveryDays <= 0 {
+		return fmt.Errorf("policy %q: decay_every_days must be positive when decay_rate is set", p.Name)
+	}
+	if p.MinImportance < 0 || p.MinImportance > 1 {
+		return fmt.Errorf("policy %q: min_importance must be between 0 and 1", p.Name)
+	}
+	return nil
+}
+
+// LoadPolicies reads a JSON array of policies from a file and validates them.
+func LoadPolicies(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read retention policies: %w", err)
+	}
+
+	var policies []Policy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("parse retention policies %s: %w", path, err)
+	}
+
+	names := make(map[string]bool, len(policies))
+	for _, p := range policies {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("duplicate retention policy %q", p.Name)
+		}
+		names[p.Name] = true
+	}
+	return policies, nil
+}
+
+// query accumulates the conditions and arguments of a statement over memories
+// aliased as m, scoped to the sweeper's tenant and workspace.
+type query struct {
+	conds []string
+	args  []any
+}
+
+func (s *Sweeper) newQuery() *query {
+	return &query{
+		conds: []string{"m.tenant_id = $1", "m.workspace_id = $2"},
+		args:  []any{s.tenantID, s.workspaceID},
+	}
+}
+
+// arg adds an argument and returns its placeholder.
+func (q *query) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *query) where(cond string) {
+	q.conds = append(q.conds, cond)
+}
+
+func (q *query) String() string {
+	return strings.Join(q.conds, "\n\t\t  AND ")
+}
+
+// matches returns a condition for memories matching p, ignoring precedence.
+func (q *query) matches(p Policy) string {
+	var conds []string
+	if len(p.Kinds) > 0 {
+		conds = append(conds, "m.kind = ANY("+q.arg(p.Kinds)+"::text[])")
+	}
+	if len(p.Tags) > 0 {
+		conds = append(conds, "COALESCE(m.tags, '{}') && "+q.arg(p.Tags)+"::text[]")
+	}
+	if len(conds) == 0 {
+		return "TRUE"
+	}
+	return "(" + strings.Join(conds, " AND ") + ")"
+}
+
+// governedBy returns a condition for memories whose first matching policy is policies[i].
+func (q *query) governedBy(policies []Policy, i int) string {
+	conds := []string{q.matches(policies[i])}
+	for _, earlier := range policies[:i] {
+		conds = append(conds, "NOT "+q.matches(earlier))
+	}
+	return "(" + strings.Join(conds, " AND ") + ")"
+}
+
+// unprotected restricts the query to memories not governed by a never_expire policy.
+func (q *query) unprotected(policies []Policy) {
+	var protected []string
+	for i, p := range policies {
+		if p.NeverExpire {
+			protected = append(protected, q.governedBy(policies, i))
+		}
+	}
+	if len(protected) > 0 {
+		q.where("NOT (" + strings.Join(protected, " OR ") + ")")
+	}
+}
+
+// expiredBy restricts the query to memories that policy p has expired.
+func (q *query) expiredBy(p Policy) {
+	cutoff := "NOW() - " + q.arg(p.ExpireAfterDays) + "::int * INTERVAL '1 day'"
+	switch p.ExpireFrom {
+	case ExpireFromUpdated:
+		q.where("m.updated_at < " + cutoff)
+	case ExpireFromAccessed:
+		q.where("COALESCE(m.last_accessed_at, m.created_at) < " + cutoff)
+	case ExpireFromCompleted:
+		q.where(`EXISTS (SELECT 1 FROM todos t WHERE t.memory_id = m.id
+		        AND t.status IN ('done', 'archived') AND t.completed_at < ` + cutoff + ")")
+	default:
+		q.where("m.created_at < " + cutoff)
+	}
+}
@@ -0,0 +1,170 @@
+package sweeper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnswift/cortex/internal/encryption"
+)
+
+// Actions the sweeper takes on a memory.
+const (
+	ActionDelete  = "delete"
+	ActionDecay   = "decay"
+	ActionArchive = "archive" // moved to the archive tier, or for todos, set to status archived
+)
+
+// Change is a memory the sweeper would change.
+type Change struct {
+	TenantID    string  `json:"tenant_id"`
+	WorkspaceID string  `json:"workspace_id"`
+	MemoryID    int64   `json:"memory_id"`
+	Kind        string  `json:"kind"`
+	Text        string  `json:"text"`
+	Action      string  `json:"action"`     // delete, decay or archive
+	Reason      string  `json:"reason"`     // ttl, unaccessed, todo_archive or policy:<name>
+	Importance  float32 `json:"importance"` // current importance, or the importance after decay
+}
+
+// Report lists what a sweep would change, in the order the sweep applies it.
+// A memory removed by an earlier step is not listed again by later ones.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Count returns the number of changes with the given action.
+func (r *Report) Count(action string) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Preview reports what a sweep with the current configuration would change,
+// without changing anything. In global mode it covers every workspace.
+func (s *Sweeper) Preview(ctx context.Context) (*Report, error) {
+	if !s.config.Global {
+		return s.previewWorkspace(ctx)
+	}
+
+	workspaces, err := s.listWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	report := &Report{}
+	for _, ws := range workspaces {
+		r, err := s.forWorkspace(ws).previewWorkspace(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", ws.tenantID, ws.workspaceID, err)
+		}
+		report.Changes = append(report.Changes, r.Changes...)
+	}
+	return report, nil
+}
+
+// previewWorkspace reports what a sweep would change in the sweeper's workspace.
+func (s *Sweeper) previewWorkspace(ctx context.Context) (*Report, error) {
+	p := &preview{report: &Report{}, deleted: make(map[int64]bool)}
+
+	remove := ActionDelete
+	if s.config.Archive == ArchiveTable || s.config.Archive == ArchiveFile {
+		remove = ActionArchive
+	}
+
+	p.removing = true
+	if err := s.previewSelect(ctx, p, s.expiredQuery(), remove, "ttl"); err != nil {
+		return nil, fmt.Errorf("preview expired memories: %w", err)
+	}
+
+	if s.config.EvictUnaccessedAfter > 0 {
+		q := s.unaccessedQuery(s.config.EvictUnaccessedAfter)
+		if err := s.previewSelect(ctx, p, q, remove, "unaccessed"); err != nil {
+			return nil, fmt.Errorf("preview unaccessed memories: %w", err)
+		}
+	}
+
+	for i, policy := range s.config.Policies {
+		if policy.ExpireAfterDays <= 0 {
+			continue
+		}
+		if err := s.previewSelect(ctx, p, s.policyExpiryQuery(i), remove, "policy:"+policy.Name); err != nil {
+			return nil, fmt.Errorf("preview policy %q: %w", policy.Name, err)
+		}
+	}
+
+	p.removing = false
+
+	for i, policy := range s.config.Policies {
+		if policy.DecayRate <= 0 {
+			continue
+		}
+		q, due, importance, _ := s.decayQuery(i)
+		rows := due + `
+		SELECT m.id, m.kind, m.text, ` + importance + `
+		FROM memories m
+		JOIN due ON due.id = m.id
+		WHERE due.steps >= 1
+		ORDER BY m.id`
+		if err := s.previewRows(ctx, p, rows, q.args, ActionDecay, "policy:"+policy.Name); err != nil {
+			return nil, fmt.Errorf("preview decay for policy %q: %w", policy.Name, err)
+		}
+	}
+
+	if s.config.ArchiveDoneTodosAfter > 0 {
+		q := s.doneTodosQuery(s.config.ArchiveDoneTodosAfter)
+		if err := s.previewSelect(ctx, p, q, ActionArchive, "todo_archive"); err != nil {
+			return nil, fmt.Errorf("preview todo archiving: %w", err)
+		}
+	}
+
+	return p.report, nil
+}
+
+// preview accumulates a report, tracking memories already reported as removed.
+type preview struct {
+	report   *Report
+	deleted  map[int64]bool
+	removing bool // the current step deletes or archives memories
+}
+
+// previewSelect reports the memories matching q.
+func (s *Sweeper) previewSelect(ctx context.Context, p *preview, q *query, action, reason string) error {
+	sql := `
+		SELECT m.id, m.kind, m.text, m.importance
+		FROM memories m
+		WHERE ` + q.String() + `
+		ORDER BY m.id`
+	return s.previewRows(ctx, p, sql, q.args, action, reason)
+}
+
+// previewRows reports the memories returned by sql, which selects id, kind,
+// text and importance.
+func (s *Sweeper) previewRows(ctx context.Context, p *preview, sql string, args []any, action, reason string) error {
+	rows, err := s.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := Change{TenantID: s.tenantID, WorkspaceID: s.workspaceID, Action: action, Reason: reason}
+		if err := rows.Scan(&c.MemoryID, &c.Kind, &c.Text, &c.Importance); err != nil {
+			return err
+		}
+		if encryption.IsSealed(c.Text) {
+			// The sweeper has no keys; the ID identifies the memory
+			c.Text = "[encrypted]"
+		}
+		if p.deleted[c.MemoryID] {
+			continue
+		}
+		if p.removing {
+			p.deleted[c.MemoryID] = true
+		}
+		p.report.Changes = append(p.report.Changes, c)
+	}
+	return rows.Err()
+}
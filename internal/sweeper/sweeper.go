@@ -1,24 +1,59 @@
-// Package sweeper provides automatic cleanup of expired memories based on TTL.
+// Package sweeper provides automatic cleanup of expired memories based on TTL
+// and retention policies.
 package sweeper
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Config controls what the sweeper cleans up besides expired memories.
+type Config struct {
+	// ArchiveDoneTodosAfter archives todos this long after they were completed.
+	// Archived todos are hidden from todo lists and search. Zero disables archiving.
+	ArchiveDoneTodosAfter time.Duration
+
+	// EvictUnaccessedAfter deletes memories older than this that have not been
+	// returned by a search or get within the same window. Chunks are kept with
+	// their document and unfinished todos are never evicted. Zero disables eviction.
+	EvictUnaccessedAfter time.Duration
+
+	// Policies are retention rules by kind and tag, evaluated in order. See Policy.
+	Policies []Policy
+
+	// Archive selects what happens to memories removed by ttl_days, eviction
+	// or a policy. ArchiveFile writes files to ArchiveDir.
+	Archive    ArchiveMode
+	ArchiveDir string
+
+	// Global sweeps every workspace of every tenant instead of only the
+	// sweeper's own, so workspaces no running process has open are swept too.
+	Global bool
+}
+
+// DefaultConfig returns the default sweeper configuration.
+func DefaultConfig() Config {
+	return Config{Archive: ArchiveNone}
+}
+
 // Sweeper manages automatic deletion of expired memories.
 type Sweeper struct {
 	pool        *pgxpool.Pool
 	tenantID    string
 	workspaceID string
+	config      Config
 
 	mu      sync.Mutex
 	running bool
 	done    chan struct{}
+	stats   Stats
 }
 
 // NewSweeper creates a new Sweeper instance.
@@ -27,6 +62,17 @@ func NewSweeper(pool *pgxpool.Pool, tenantID, workspaceID string) *Sweeper {
 		pool:        pool,
 		tenantID:    tenantID,
 		workspaceID: workspaceID,
+		config:      DefaultConfig(),
+	}
+}
+
+// WithConfig returns a new Sweeper with the given configuration.
+func (s *Sweeper) WithConfig(cfg Config) *Sweeper {
+	return &Sweeper{
+		pool:        s.pool,
+		tenantID:    s.tenantID,
+		workspaceID: s.workspaceID,
+		config:      cfg,
 	}
 }
 
@@ -83,29 +129,287 @@ func (s *Sweeper) Stop() {
 	log.Printf("[sweeper] stopped")
 }
 
-func (s *Sweeper) runSweep(ctx context.Context) {
-	deleted, err := s.DeleteExpired(ctx)
+// RunOnce runs a single sweep immediately and returns what it changed.
+func (s *Sweeper) RunOnce(ctx context.Context) *RunStats {
+	return s.runSweep(ctx)
+}
+
+// runSweep sweeps the sweeper's workspace, or every workspace in global mode,
+// unless another instance holds the sweep lock.
+func (s *Sweeper) runSweep(ctx context.Context) *RunStats {
+	run := &RunStats{StartedAt: time.Now()}
+	defer func() {
+		run.FinishedAt = time.Now()
+		s.record(run)
+		s.saveRun(run)
+	}()
+
+	release, locked, err := s.tryLock(ctx)
 	if err != nil {
-		log.Printf("[sweeper] error deleting expired memories: %v", err)
-		return
+		log.Printf("[sweeper] error acquiring sweep lock: %v", err)
+		run.Error = err.Error()
+		return run
+	}
+	if !locked {
+		log.Printf("[sweeper] another instance is sweeping, skipping this run")
+		run.Skipped = true
+		return run
+	}
+	defer release()
+
+	workspaces := []workspace{{s.tenantID, s.workspaceID}}
+	if s.config.Global {
+		workspaces, err = s.listWorkspaces(ctx)
+		if err != nil {
+			log.Printf("[sweeper] error listing workspaces: %v", err)
+			run.Error = err.Error()
+			return run
+		}
+	}
+
+	for _, ws := range workspaces {
+		if ctx.Err() != nil {
+			break
+		}
+		if !slices.Contains(run.tenants, ws.tenantID) {
+			run.tenants = append(run.tenants, ws.tenantID)
+		}
+		stats, swept := s.forWorkspace(ws).sweepLocked(ctx)
+		if swept && stats.changed() {
+			run.Workspaces = append(run.Workspaces, stats)
+		}
+	}
+	return run
+}
+
+// sweepLocked sweeps the workspace of a global sweep while holding its own
+// sweep lock, so it is never swept by a workspace sweeper at the same time.
+// It reports false if the workspace was not swept.
+func (s *Sweeper) sweepLocked(ctx context.Context) (WorkspaceStats, bool) {
+	if !s.config.Global {
+		return s.sweepWorkspace(ctx), true
+	}
+
+	release, locked, err := s.tryLockWorkspace(ctx)
+	if err != nil {
+		log.Printf("[sweeper] %s/%s: error acquiring sweep lock: %v", s.tenantID, s.workspaceID, err)
+		return WorkspaceStats{}, false
+	}
+	if !locked {
+		log.Printf("[sweeper] %s/%s: another instance is sweeping it, skipping", s.tenantID, s.workspaceID)
+		return WorkspaceStats{}, false
+	}
+	defer release()
+	return s.sweepWorkspace(ctx), true
+}
+
+// sweepWorkspace applies every cleanup step to the sweeper's workspace. A
+// failing step is logged and recorded, and the remaining steps still run.
+func (s *Sweeper) sweepWorkspace(ctx context.Context) WorkspaceStats {
+	stats := WorkspaceStats{TenantID: s.tenantID, WorkspaceID: s.workspaceID}
+	fail := func(step string, err error) {
+		log.Printf("[sweeper] %s/%s: error %s: %v", s.tenantID, s.workspaceID, step, err)
+		stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %v", step, err))
+	}
+
+	var err error
+	if stats.Expired, err = s.DeleteExpired(ctx); err != nil {
+		fail("deleting expired memories", err)
+	}
+
+	if s.config.EvictUnaccessedAfter > 0 {
+		if stats.Evicted, err = s.EvictUnaccessed(ctx, s.config.EvictUnaccessedAfter); err != nil {
+			fail("evicting unaccessed memories", err)
+		}
+	}
+
+	if len(s.config.Policies) > 0 {
+		if stats.PolicyExpired, err = s.ExpirePolicies(ctx); err != nil {
+			fail("applying retention policies", err)
+		}
+		if stats.Decayed, err = s.DecayImportance(ctx); err != nil {
+			fail("decaying importance", err)
+		}
+	}
+
+	if s.config.ArchiveDoneTodosAfter > 0 {
+		if stats.TodosArchived, err = s.ArchiveDoneTodos(ctx, s.config.ArchiveDoneTodosAfter); err != nil {
+			fail("archiving completed todos", err)
+		}
 	}
-	if deleted > 0 {
-		log.Printf("[sweeper] deleted %d expired memories", deleted)
+
+	if stats.Removed() > 0 || stats.Decayed > 0 || stats.TodosArchived > 0 {
+		log.Printf("[sweeper] %s/%s: removed %d memories (%d expired, %d unaccessed, %d by policy), decayed %d, archived %d completed todos",
+			s.tenantID, s.workspaceID, stats.Removed(), stats.Expired, stats.Evicted, stats.PolicyExpired,
+			stats.Decayed, stats.TodosArchived)
 	}
+	return stats
 }
 
-// DeleteExpired removes all memories that have exceeded their TTL.
-// A memory expires when: created_at + (ttl_days * 1 day) < NOW()
+// DeleteExpired removes all memories that have exceeded their TTL, archiving
+// them if an archive mode is configured.
+// A memory expires when: basis + (ttl_days * 1 day) < NOW(), where the basis
+// is its creation, last update, or last update or read, according to ttl_from.
+// Memories governed by a never_expire policy are kept.
 func (s *Sweeper) DeleteExpired(ctx context.Context) (int64, error) {
-	result, err := s.pool.Exec(ctx, `
-		DELETE FROM memories
-		WHERE tenant_id = $1
-		  AND workspace_id = $2
-		  AND ttl_days IS NOT NULL
-		  AND created_at + ttl_days * INTERVAL '1 day' < NOW()
-	`, s.tenantID, s.workspaceID)
+	return s.removeWhere(ctx, s.expiredQuery(), "ttl")
+}
+
+// ArchiveDoneTodos archives todos that were completed more than age ago.
+func (s *Sweeper) ArchiveDoneTodos(ctx context.Context, age time.Duration) (int64, error) {
+	q := s.doneTodosQuery(age)
+	ids, err := s.changeAudited(ctx, ActionArchive, "todo_archive", `
+		UPDATE todos
+		SET status = 'archived', updated_at = now()
+		WHERE memory_id IN (SELECT m.id FROM memories m WHERE `+q.String()+`)
+		RETURNING memory_id
+	`, q.args...)
+	return int64(len(ids)), err
+}
+
+// EvictUnaccessed deletes memories created more than window ago that have not
+// been accessed within window. Chunks are only deleted with their document,
+// open or in-progress todos are kept, and so are memories governed by a
+// never_expire policy.
+func (s *Sweeper) EvictUnaccessed(ctx context.Context, window time.Duration) (int64, error) {
+	return s.removeWhere(ctx, s.unaccessedQuery(window), "unaccessed")
+}
+
+// ExpirePolicies deletes memories that their retention policy has expired.
+func (s *Sweeper) ExpirePolicies(ctx context.Context) (int64, error) {
+	var total int64
+	for i, p := range s.config.Policies {
+		if p.ExpireAfterDays <= 0 {
+			continue
+		}
+		deleted, err := s.removeWhere(ctx, s.policyExpiryQuery(i), "policy:"+p.Name)
+		if err != nil {
+			return total, fmt.Errorf("policy %q: %w", p.Name, err)
+		}
+		total += deleted
+	}
+	return total, nil
+}
+
+// DecayImportance lowers the importance of memories governed by a decaying
+// policy by decay_rate for every full decay_every_days period since they were
+// created or last decayed.
+func (s *Sweeper) DecayImportance(ctx context.Context) (int64, error) {
+	var total int64
+	for i, p := range s.config.Policies {
+		if p.DecayRate <= 0 {
+			continue
+		}
+		q, due, importance, every := s.decayQuery(i)
+		ids, err := s.changeAudited(ctx, ActionDecay, "policy:"+p.Name, due+`
+		UPDATE memories m
+		SET importance = `+importance+`,
+		    decayed_at = due.base + due.steps * `+every+` * INTERVAL '1 day'
+		FROM due
+		WHERE m.id = due.id AND due.steps >= 1
+		RETURNING m.id
+		`, q.args...)
+		if err != nil {
+			return total, fmt.Errorf("policy %q: %w", p.Name, err)
+		}
+		total += int64(len(ids))
+	}
+	return total, nil
+}
+
+// deleteWhere deletes the memories matching q.
+func (s *Sweeper) deleteWhere(ctx context.Context, q *query, reason string) (int64, error) {
+	ids, err := s.changeAudited(ctx, ActionDelete, reason, `
+		DELETE FROM memories m
+		WHERE `+q.String()+`
+		RETURNING m.id`, q.args...)
+	return int64(len(ids)), err
+}
+
+// changeAudited runs a statement returning the IDs of the memories it
+// changed, and records them in the audit log in the same transaction.
+func (s *Sweeper) changeAudited(ctx context.Context, action, reason, sql string, args ...any) ([]int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, sql, args...)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return result.RowsAffected(), nil
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordAudit(ctx, tx, action, reason, ids); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return ids, nil
+}
+
+func (s *Sweeper) expiredQuery() *query {
+	q := s.newQuery()
+	q.where("m.ttl_days IS NOT NULL")
+	q.where(`CASE m.ttl_from
+		        WHEN 'updated' THEN m.updated_at
+		        WHEN 'accessed' THEN GREATEST(m.updated_at, m.last_accessed_at)
+		        ELSE m.created_at
+		    END + m.ttl_days * INTERVAL '1 day' < NOW()`)
+	q.unprotected(s.config.Policies)
+	return q
+}
+
+func (s *Sweeper) doneTodosQuery(age time.Duration) *query {
+	q := s.newQuery()
+	q.where(`EXISTS (SELECT 1 FROM todos t WHERE t.memory_id = m.id
+		        AND t.status = 'done' AND t.completed_at < NOW() - ` + q.arg(age.Seconds()) + ` * INTERVAL '1 second')`)
+	return q
+}
+
+func (s *Sweeper) unaccessedQuery(window time.Duration) *query {
+	q := s.newQuery()
+	cutoff := "NOW() - " + q.arg(window.Seconds()) + " * INTERVAL '1 second'"
+	q.where("m.parent_id IS NULL")
+	q.where("m.created_at < " + cutoff)
+	q.where("(m.last_accessed_at IS NULL OR m.last_accessed_at < " + cutoff + ")")
+	q.where(`NOT (m.kind = 'todo' AND COALESCE(
+		        (SELECT t.status FROM todos t WHERE t.memory_id = m.id), 'open') IN ('open', 'in_progress'))`)
+	q.unprotected(s.config.Policies)
+	return q
+}
+
+func (s *Sweeper) policyExpiryQuery(i int) *query {
+	q := s.newQuery()
+	q.where("m.parent_id IS NULL")
+	q.where(q.governedBy(s.config.Policies, i))
+	q.expiredBy(s.config.Policies[i])
+	return q
+}
+
+// decayQuery builds the decay of policies[i]: a "due" CTE listing each governed
+// memory with the start of its current decay period (base) and the number of
+// full periods since (steps), the decayed importance expression, and the
+// placeholder of the period length in days.
+func (s *Sweeper) decayQuery(i int) (q *query, due, importance, every string) {
+	p := s.config.Policies[i]
+	q = s.newQuery()
+	q.where(q.governedBy(s.config.Policies, i))
+	every = q.arg(p.DecayEveryDays) + "::float8"
+
+	due = `
+		WITH due AS (
+			SELECT m.id, COALESCE(m.decayed_at, m.created_at) AS base,
+			       floor(extract(epoch FROM NOW() - COALESCE(m.decayed_at, m.created_at))::float8 / (` + every + ` * 86400)) AS steps
+			FROM memories m
+			WHERE ` + q.String() + `
+		)`
+	importance = "LEAST(m.importance, GREATEST(" + q.arg(p.MinImportance) + "::float8, m.importance * power(1 - " +
+		q.arg(p.DecayRate) + "::float8, due.steps)))::real"
+	return q, due, importance, every
 }
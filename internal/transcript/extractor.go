@@ -0,0 +1,178 @@
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultWindowChars is the default amount of transcript text sent to the LLM per prompt.
+const DefaultWindowChars = 12000
+
+// DefaultDuplicateSimilarity is the minimum vector similarity at which a
+// candidate is considered a duplicate of an existing memory.
+const DefaultDuplicateSimilarity = 0.9
+
+// Memory kinds produced by extraction.
+const (
+	KindFact       = "fact"
+	KindDecision   = "decision"
+	KindPreference = "preference"
+	KindTodo       = "todo"
+	KindNote       = "note"
+)
+
+// ChatProvider is the interface for LLM text completion.
+type ChatProvider interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// Candidate is a memory proposed by the LLM from a conversation.
+type Candidate struct {
+	Text       string   `json:"text"`
+	Kind       string   `json:"kind"`
+	Importance float32  `json:"importance"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// Extractor uses an LLM to turn a conversation into candidate memories.
+type Extractor struct {
+	llm         ChatProvider
+	windowChars int
+}
+
+// NewExtractor creates a new conversation extractor.
+func NewExtractor(llm ChatProvider) *Extractor {
+	return &Extractor{llm: llm, windowChars: DefaultWindowChars}
+}
+
+// WithWindowChars returns a new Extractor that sends at most n characters of
+// transcript per prompt.
+func (e *Extractor) WithWindowChars(n int) *Extractor {
+	if n <= 0 {
+		n = DefaultWindowChars
+	}
+	return &Extractor{llm: e.llm, windowChars: n}
+}
+
+// Extract asks the LLM for durable memories in the transcript. Long transcripts
+// are processed in windows; candidates repeated across windows are returned once.
+func (e *Extractor) Extract(ctx context.Context, t *Transcript) ([]Candidate, error) {
+	var result []Candidate
+	seen := make(map[string]bool)
+
+	windows := t.Windows(e.windowChars)
+	for i, window := range windows {
+		prompt := buildExtractionPrompt(render(window))
+
+		response, err := e.llm.Complete(ctx, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("llm complete (window %d/%d): %w", i+1, len(windows), err)
+		}
+
+		candidates, err := parseExtractionResponse(response)
+		if err != nil {
+			return nil, fmt.Errorf("parse response (window %d/%d): %w", i+1, len(windows), err)
+		}
+
+		for _, c := range candidates {
+			key := dedupeKey(c.Text)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, c)
+		}
+	}
+
+	return result, nil
+}
+
+// buildExtractionPrompt creates the prompt for memory extraction.
+func buildExtractionPrompt(dialogue string) string {
+	return fmt.Sprintf(`Extract durable memories from the following conversation between a user and a coding assistant. Return a JSON object with:
+- "memories": array of objects with "text", "kind", "importance" (0-1), "tags" (optional array of short lowercase strings)
+
+Kinds:
+- fact: a stable fact about the project, codebase, environment or people
+- decision: a choice that was made, with its reason when given
+- preference: how the user likes things done
+- todo: follow-up work that was agreed on but not done in the conversation
+- note: anything else worth remembering
+
+Guidelines:
+- Only extract information that will still be useful in a future conversation
+- Skip transient details: individual commands run, errors that were fixed, intermediate steps
+- Write each memory as one self-contained statement that makes sense without the conversation
+- Do not repeat the same information in several memories
+- Use higher importance for decisions and preferences that affect future work
+- Return an empty array if there is nothing worth remembering
+
+Conversation:
+"""
+%s
+"""
+
+Respond with ONLY valid JSON, no markdown or explanation:`, dialogue)
+}
+
+// parseExtractionResponse parses the LLM response into candidates.
+func parseExtractionResponse(response string) ([]Candidate, error) {
+	// Clean up response - remove markdown code blocks if present
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+	response = strings.TrimSpace(response)
+
+	// Handle empty or null responses
+	if response == "" || response == "null" || response == "{}" {
+		return nil, nil
+	}
+
+	var result struct {
+		Memories []Candidate `json:"memories"`
+	}
+	if err := json.Unmarshal([]byte(response), &result); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w (response: %s)", err, truncate(response, 200))
+	}
+
+	candidates := make([]Candidate, 0, len(result.Memories))
+	for _, c := range result.Memories {
+		c.Text = strings.TrimSpace(c.Text)
+		if c.Text == "" {
+			continue
+		}
+		c.Kind = normalizeKind(c.Kind)
+		if c.Importance <= 0 || c.Importance > 1 {
+			c.Importance = 0.5 // Default importance
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, nil
+}
+
+// normalizeKind maps the LLM's kind onto the extracted kinds.
+func normalizeKind(kind string) string {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case KindFact, "facts", "knowledge":
+		return KindFact
+	case KindDecision, "decisions", "choice":
+		return KindDecision
+	case KindPreference, "preferences", "convention":
+		return KindPreference
+	case KindTodo, "task", "action", "action_item", "follow_up", "followup":
+		return KindTodo
+	default:
+		return KindNote
+	}
+}
+
+// dedupeKey normalizes text for exact duplicate detection: lowercase with
+// whitespace collapsed and trailing punctuation removed.
+func dedupeKey(text string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	return strings.TrimRight(key, ".!")
+}
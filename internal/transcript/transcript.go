@@ -0,0 +1,174 @@
+// Package transcript parses conversation transcripts and extracts durable
+// memories from them with an LLM.
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxLineSize bounds a single JSONL line; tool results can be large.
+const maxLineSize = 16 << 20
+
+// Turn is one user or assistant message of a conversation.
+type Turn struct {
+	Role      string // "user" or "assistant"
+	Text      string
+	Timestamp time.Time // zero if not recorded
+}
+
+// Transcript is a parsed conversation.
+type Transcript struct {
+	SessionID string // empty if not recorded
+	Turns     []Turn
+}
+
+// entry is one line of a Claude Code session transcript.
+// Lines of other types (summaries, snapshots) are ignored.
+type entry struct {
+	Type        string    `json:"type"`
+	SessionID   string    `json:"sessionId"`
+	Timestamp   time.Time `json:"timestamp"`
+	IsMeta      bool      `json:"isMeta"`
+	IsSidechain bool      `json:"isSidechain"`
+	Message     *struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// contentBlock is one block of a message whose content is an array.
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Parse reads a Claude Code session transcript (one JSON object per line).
+// Only the text of user and assistant messages is kept: tool calls, tool
+// results, thinking blocks, meta messages and sidechain (subagent) messages
+// are skipped. Malformed lines are skipped; an error is returned only if the
+// input cannot be read or contains no messages at all.
+func Parse(r io.Reader) (*Transcript, error) {
+	t := &Transcript{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var lines, skipped int
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines++
+
+		var e entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			skipped++
+			continue
+		}
+		if t.SessionID == "" {
+			t.SessionID = e.SessionID
+		}
+		if (e.Type != "user" && e.Type != "assistant") || e.Message == nil || e.IsMeta || e.IsSidechain {
+			continue
+		}
+
+		text := messageText(e.Message.Content)
+		if text == "" {
+			continue
+		}
+
+		role := e.Message.Role
+		if role == "" {
+			role = e.Type
+		}
+		t.Turns = append(t.Turns, Turn{Role: role, Text: text, Timestamp: e.Timestamp})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+
+	if len(t.Turns) == 0 {
+		if lines > 0 && skipped == lines {
+			return nil, fmt.Errorf("no valid transcript lines (%d malformed)", skipped)
+		}
+		return nil, fmt.Errorf("transcript contains no messages")
+	}
+
+	return t, nil
+}
+
+// messageText returns the text of a message's content, which is either a
+// string or an array of blocks of which only "text" blocks are kept.
+func messageText(content json.RawMessage) string {
+	if len(content) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return ""
+	}
+
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" && strings.TrimSpace(b.Text) != "" {
+			parts = append(parts, strings.TrimSpace(b.Text))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Windows splits the transcript into consecutive groups of turns whose
+// rendered text is at most maxChars, so each can be sent to the LLM in one
+// prompt. A single turn longer than maxChars is truncated.
+func (t *Transcript) Windows(maxChars int) [][]Turn {
+	var windows [][]Turn
+	var current []Turn
+	size := 0
+
+	for _, turn := range t.Turns {
+		if len(turn.Text) > maxChars {
+			turn.Text = truncate(turn.Text, maxChars)
+		}
+		n := len(turn.Role) + len(turn.Text) + 4
+		if len(current) > 0 && size+n > maxChars {
+			windows = append(windows, current)
+			current, size = nil, 0
+		}
+		current = append(current, turn)
+		size += n
+	}
+	if len(current) > 0 {
+		windows = append(windows, current)
+	}
+
+	return windows
+}
+
+// render formats turns as a plain-text dialogue for a prompt.
+func render(turns []Turn) string {
+	var b strings.Builder
+	for _, t := range turns {
+		fmt.Fprintf(&b, "[%s]: %s\n\n", t.Role, t.Text)
+	}
+	return b.String()
+}
+
+// truncate shortens a string to the given length.
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
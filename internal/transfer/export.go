@@ -11,7 +11,8 @@ import (
 
 // Exporter handles memory export operations.
 type Exporter struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	sealer Sealer
 }
 
 // NewExporter creates a new exporter with the given database pool.
@@ -19,6 +20,14 @@ func NewExporter(pool *pgxpool.Pool) *Exporter {
 	return &Exporter{pool: pool}
 }
 
+// WithSealer opens sealed memories before they are written. Without one,
+// sealed memories are exported as stored and only a DB with the keyfile can
+// read them once imported.
+func (e *Exporter) WithSealer(s Sealer) *Exporter {
+	e.sealer = s
+	return e
+}
+
 // Export writes memories to the given writer in JSONL format.
 // Each line is a complete JSON object representing one memory.
 func (e *Exporter) Export(ctx context.Context, w io.Writer, opts ExportOptions) (*ExportResult, error) {
@@ -70,7 +79,7 @@ func (e *Exporter) Export(ctx context.Context, w io.Writer, opts ExportOptions)
 func (e *Exporter) buildExportQuery(opts ExportOptions) (string, []any) {
 	query := `
 		SELECT id, tenant_id, workspace_id, kind, text, source, created_at, updated_at,
-		       tags, importance, ttl_days, meta
+		       tags, importance, ttl_days, ttl_from, meta, parent_id, chunk_index
 		FROM memories
 		WHERE 1=1
 	`
@@ -95,6 +104,12 @@ func (e *Exporter) buildExportQuery(opts ExportOptions) (string, []any) {
 		argIdx++
 	}
 
+	if len(opts.IDs) > 0 {
+		query += fmt.Sprintf(" AND id = ANY($%d)", argIdx)
+		args = append(args, opts.IDs)
+		argIdx++
+	}
+
 	if opts.Since != nil {
 		query += fmt.Sprintf(" AND updated_at >= $%d", argIdx)
 		args = append(args, *opts.Since)
@@ -127,7 +142,10 @@ func (e *Exporter) scanMemoryRecord(rows interface{ Scan(dest ...any) error }, _
 		&record.Tags,
 		&record.Importance,
 		&record.TTLDays,
+		&record.TTLFrom,
 		&metaJSON,
+		&record.ParentID,
+		&record.ChunkIndex,
 	)
 	if err != nil {
 		return nil, err
@@ -139,6 +157,13 @@ func (e *Exporter) scanMemoryRecord(rows interface{ Scan(dest ...any) error }, _
 		}
 	}
 
+	if e.sealer != nil {
+		record.Text, record.Meta, err = e.sealer.OpenMemory(record.Text, record.Meta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &record, nil
 }
 
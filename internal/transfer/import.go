@@ -9,6 +9,8 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/johnswift/cortex/internal/encryption"
+	"github.com/johnswift/cortex/internal/redact"
 	"github.com/pgvector/pgvector-go"
 )
 
@@ -23,6 +25,7 @@ type EmbeddingProvider interface {
 type Importer struct {
 	pool     *pgxpool.Pool
 	embedder EmbeddingProvider
+	sealer   Sealer
 }
 
 // NewImporter creates a new importer with the given database pool.
@@ -33,6 +36,14 @@ func NewImporter(pool *pgxpool.Pool, embedder EmbeddingProvider) *Importer {
 	}
 }
 
+// WithSealer opens sealed records, such as those of archive files, and seals
+// records imported into encrypted workspaces. Without one, records are
+// written as they are.
+func (i *Importer) WithSealer(s Sealer) *Importer {
+	i.sealer = s
+	return i
+}
+
 // Import reads memories from JSONL format and inserts them into the database.
 func (i *Importer) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportResult, error) {
 	result := &ImportResult{}
@@ -66,6 +77,22 @@ func (i *Importer) Import(ctx context.Context, r io.Reader, opts ImportOptions)
 			record.WorkspaceID = "default"
 		}
 
+		if i.sealer != nil {
+			var err error
+			record.Text, record.Meta, err = i.sealer.OpenMemory(record.Text, record.Meta)
+			if err != nil {
+				result.Errors++
+				continue
+			}
+		}
+
+		if opts.Prepare != nil {
+			if err := opts.Prepare(&record); err != nil {
+				result.Errors++
+				continue
+			}
+		}
+
 		if opts.DryRun {
 			result.Imported++
 			continue
@@ -85,12 +112,14 @@ func (i *Importer) Import(ctx context.Context, r io.Reader, opts ImportOptions)
 		}
 
 		// Import the record
-		if err := i.importRecord(ctx, &record, opts); err != nil {
+		id, err := i.importRecord(ctx, &record, opts)
+		if err != nil {
 			result.Errors++
 			continue
 		}
 
 		result.Imported++
+		result.IDs = append(result.IDs, id)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -108,28 +137,45 @@ func (i *Importer) memoryExists(ctx context.Context, id int64, tenantID, workspa
 	return exists, err
 }
 
-func (i *Importer) importRecord(ctx context.Context, record *MemoryRecord, opts ImportOptions) error {
+func (i *Importer) importRecord(ctx context.Context, record *MemoryRecord, opts ImportOptions) (int64, error) {
 	tx, err := i.pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
+		return 0, fmt.Errorf("begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	// Seal text and meta for an encrypted workspace. A sealed record written
+	// as is gets an empty blind index, so it is not matched by trigram search.
+	text, meta := record.Text, record.Meta
+	var index []string
+	if i.sealer != nil {
+		text, meta, index, err = i.sealer.SealMemory(record.WorkspaceID, record.Text, record.Meta)
+		if err != nil {
+			return 0, err
+		}
+	} else if encryption.IsSealed(text) {
+		index = []string{}
+	}
+
 	// Prepare meta JSON
-	metaJSON, err := json.Marshal(record.Meta)
+	metaJSON, err := json.Marshal(meta)
 	if err != nil {
-		return fmt.Errorf("marshal meta: %w", err)
+		return 0, fmt.Errorf("marshal meta: %w", err)
 	}
 
 	if record.Tags == nil {
 		record.Tags = []string{}
 	}
+	if record.TTLFrom == "" {
+		record.TTLFrom = "created"
+	}
 
 	// Upsert memory
 	var memoryID int64
 	err = tx.QueryRow(ctx, `
-		INSERT INTO memories (id, tenant_id, workspace_id, kind, text, source, created_at, updated_at, tags, importance, ttl_days, meta)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO memories (id, tenant_id, workspace_id, kind, text, source, created_at, updated_at, tags, importance, ttl_days, ttl_from,
+			meta, parent_id, chunk_index, blind_index)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		ON CONFLICT (id) DO UPDATE SET
 			workspace_id = EXCLUDED.workspace_id,
 			kind = EXCLUDED.kind,
@@ -139,38 +185,44 @@ func (i *Importer) importRecord(ctx context.Context, record *MemoryRecord, opts
 			tags = EXCLUDED.tags,
 			importance = EXCLUDED.importance,
 			ttl_days = EXCLUDED.ttl_days,
-			meta = EXCLUDED.meta
+			ttl_from = EXCLUDED.ttl_from,
+			meta = EXCLUDED.meta,
+			parent_id = EXCLUDED.parent_id,
+			chunk_index = EXCLUDED.chunk_index,
+			blind_index = EXCLUDED.blind_index
 		RETURNING id
-	`, record.ID, record.TenantID, record.WorkspaceID, record.Kind, record.Text, record.Source,
+	`, record.ID, record.TenantID, record.WorkspaceID, record.Kind, text, record.Source,
 		record.CreatedAt, record.UpdatedAt, record.Tags, record.Importance,
-		record.TTLDays, metaJSON).Scan(&memoryID)
+		record.TTLDays, record.TTLFrom, metaJSON, record.ParentID, record.ChunkIndex, index).Scan(&memoryID)
 
 	if err != nil {
-		return fmt.Errorf("upsert memory: %w", err)
+		return 0, fmt.Errorf("upsert memory: %w", err)
 	}
 
-	// Handle embedding
-	if opts.RegenerateEmbeddings && i.embedder != nil {
+	// Handle embedding. Ingested documents are searched through their chunks
+	// and are not embedded themselves, and memories marked no_llm are never sent
+	// to the embedding model.
+	if opts.RegenerateEmbeddings && i.embedder != nil && record.Kind != "document" && !redact.SkipsLLM(record.Meta) {
 		// Generate new embedding
 		vector, err := i.embedder.Embed(ctx, record.Text)
 		if err != nil {
-			return fmt.Errorf("generate embedding: %w", err)
+			return 0, fmt.Errorf("generate embedding: %w", err)
 		}
 		if err := i.upsertEmbedding(ctx, tx, memoryID, i.embedder.EmbedModel(), vector); err != nil {
-			return fmt.Errorf("upsert embedding: %w", err)
+			return 0, fmt.Errorf("upsert embedding: %w", err)
 		}
 	} else if record.Embedding != nil && !opts.RegenerateEmbeddings {
 		// Use existing embedding from export
 		if err := i.upsertEmbedding(ctx, tx, memoryID, record.Embedding.Model, record.Embedding.Vector); err != nil {
-			return fmt.Errorf("upsert embedding: %w", err)
+			return 0, fmt.Errorf("upsert embedding: %w", err)
 		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
+		return 0, fmt.Errorf("commit transaction: %w", err)
 	}
 
-	return nil
+	return memoryID, nil
 }
 
 func (i *Importer) upsertEmbedding(ctx context.Context, tx pgx.Tx, memoryID int64, model string, vector []float32) error {
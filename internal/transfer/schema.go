@@ -12,27 +12,32 @@ type MemoryRecord struct {
 	ID          int64     `json:"id"`
 	TenantID    string    `json:"tenant_id"`
 	WorkspaceID string    `json:"workspace_id"`
-	Kind      string    `json:"kind"`
-	Text      string    `json:"text"`
-	Source    *string   `json:"source,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Kind        string    `json:"kind"`
+	Text        string    `json:"text"`
+	Source      *string   `json:"source,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 
 	// Metadata
 	Tags       []string       `json:"tags,omitempty"`
 	Importance float32        `json:"importance"`
 	TTLDays    *int           `json:"ttl_days,omitempty"`
+	TTLFrom    string         `json:"ttl_from,omitempty"` // created (default), updated or accessed
 	Meta       map[string]any `json:"meta,omitempty"`
 
+	// Document chunking (set for chunks of an ingested document)
+	ParentID   *int64 `json:"parent_id,omitempty"`
+	ChunkIndex *int   `json:"chunk_index,omitempty"`
+
 	// Embedding (optional, for full export)
 	Embedding *EmbeddingRecord `json:"embedding,omitempty"`
 }
 
 // EmbeddingRecord represents an embedding in export format.
 type EmbeddingRecord struct {
-	Model     string    `json:"model"`
-	Dims      int       `json:"dims"`
-	Vector    []float32 `json:"vector"`
+	Model  string    `json:"model"`
+	Dims   int       `json:"dims"`
+	Vector []float32 `json:"vector"`
 }
 
 // ExportOptions configures export behavior.
@@ -54,6 +59,17 @@ type ExportOptions struct {
 
 	// Limit maximum number of records to export (0 = unlimited)
 	Limit int
+
+	// IDs restricts export to these memory IDs (empty = all)
+	IDs []int64
+}
+
+// Sealer encrypts memory text and meta at rest, as db.DB does for encrypted
+// workspaces. Exports open sealed memories, so files are portable; imports
+// seal them for the workspace they are written to.
+type Sealer interface {
+	OpenMemory(text string, meta map[string]any) (string, map[string]any, error)
+	SealMemory(workspaceID, text string, meta map[string]any) (string, map[string]any, []string, error)
 }
 
 // ImportOptions configures import behavior.
@@ -72,6 +88,10 @@ type ImportOptions struct {
 
 	// DryRun validates import without writing to database
 	DryRun bool
+
+	// Prepare, if set, is called with each record before it is written, such
+	// as to redact its text. A record it returns an error for is not imported.
+	Prepare func(record *MemoryRecord) error
 }
 
 // ImportResult contains statistics from an import operation.
@@ -80,6 +100,8 @@ type ImportResult struct {
 	Imported int64 `json:"imported"`
 	Skipped  int64 `json:"skipped"`
 	Errors   int64 `json:"errors"`
+
+	IDs []int64 `json:"-"` // IDs of the imported memories
 }
 
 // ExportResult contains statistics from an export operation.
@@ -0,0 +1,118 @@
+package watch
+
+import (
+	"path"
+	"strings"
+)
+
+// Patterns is a list of gitignore-style patterns:
+//
+//   - blank lines and lines starting with # are ignored
+//   - a pattern without a slash matches a name at any depth ("*.md", "vendor")
+//   - a pattern with a slash is relative to the root ("docs/*.md"); a leading
+//     slash only anchors it ("/README.md")
+//   - "**" matches any number of directories ("docs/**/*.md")
+//   - a trailing slash matches directories only ("build/")
+//   - a leading ! re-includes paths excluded by an earlier pattern
+//
+// A pattern that matches a directory also matches everything below it.
+type Patterns struct {
+	rules []rule
+}
+
+type rule struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// ParsePatterns parses gitignore-style pattern lines.
+func ParsePatterns(lines []string) *Patterns {
+	p := &Patterns{}
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var r rule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:] // escaped leading # or !
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		r.segments = strings.Split(line, "/")
+		if !anchored {
+			r.segments = append([]string{"**"}, r.segments...)
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p
+}
+
+// Empty reports whether there are no patterns.
+func (p *Patterns) Empty() bool {
+	return p == nil || len(p.rules) == 0
+}
+
+// Match reports whether a slash-separated path relative to the root matches.
+// The last matching pattern wins, so negated patterns can re-include paths.
+func (p *Patterns) Match(rel string, isDir bool) bool {
+	if p == nil {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	matched := false
+	for _, r := range p.rules {
+		if r.matches(parts, isDir) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+// matches checks the path itself and each of its parent directories.
+func (r rule) matches(parts []string, isDir bool) bool {
+	for n := len(parts); n > 0; n-- {
+		dir := isDir || n < len(parts)
+		if r.dirOnly && !dir {
+			continue
+		}
+		if matchSegments(r.segments, parts[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], parts[0])
+	return err == nil && ok && matchSegments(pattern[1:], parts[1:])
+}
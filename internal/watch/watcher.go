@@ -0,0 +1,195 @@
+// Package watch detects added, changed and removed files in a directory tree
+// by polling and comparing content hashes.
+package watch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MaxFileSize is the largest file the watcher reports; larger files are ignored.
+const MaxFileSize = 1 << 20
+
+// Op describes a change to a file.
+type Op string
+
+const (
+	OpAdd    Op = "added"
+	OpChange Op = "changed"
+	OpRemove Op = "removed"
+)
+
+// Event reports a change to a file.
+type Event struct {
+	Op   Op
+	Path string // absolute path
+	Hash string // SHA-256 of the content, empty for OpRemove
+}
+
+// Watcher polls a directory tree for changes to the files selected by its patterns.
+type Watcher struct {
+	root    string
+	include *Patterns // empty = all files
+	exclude *Patterns
+	files   map[string]fileState // by absolute path
+}
+
+type fileState struct {
+	hash    string
+	size    int64
+	modTime time.Time
+}
+
+// New creates a watcher for root. Files are reported if they match include
+// (or include is empty) and do not match exclude. Hidden directories such as
+// .git are always skipped.
+func New(root string, include, exclude *Patterns) (*Watcher, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve root: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	return &Watcher{
+		root:    abs,
+		include: include,
+		exclude: exclude,
+		files:   make(map[string]fileState),
+	}, nil
+}
+
+// Root returns the absolute path of the watched directory.
+func (w *Watcher) Root() string {
+	return w.root
+}
+
+// Seed records a file as already known with the given content hash, e.g. from
+// a previous run. The next Poll reports it only if its content differs, or as
+// removed if it no longer exists.
+func (w *Watcher) Seed(path, hash string) {
+	w.files[path] = fileState{hash: hash}
+}
+
+// Retry undoes what Poll recorded for an event whose handling failed, so the
+// next Poll reports it again: a removed file is known again and reported as
+// removed if it is still missing, and any other file is reported as added.
+func (w *Watcher) Retry(ev Event) {
+	if ev.Op == OpRemove {
+		// An empty hash never matches, so a file back in place is reported as changed
+		w.files[ev.Path] = fileState{}
+		return
+	}
+	delete(w.files, ev.Path)
+}
+
+// Poll scans the tree and returns the changes since the previous poll, sorted by path.
+// Files whose size and modification time are unchanged are not re-read.
+func (w *Watcher) Poll() ([]Event, error) {
+	var events []Event
+	seen := make(map[string]bool, len(w.files))
+
+	err := filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == w.root {
+				return err
+			}
+			return nil // skip unreadable entries
+		}
+
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if path == w.root {
+				return nil
+			}
+			if d.Name()[0] == '.' || w.exclude.Match(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if !w.include.Empty() && !w.include.Match(rel, false) {
+			return nil
+		}
+		if w.exclude.Match(rel, false) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed while walking
+		}
+		if info.Size() > MaxFileSize {
+			return nil
+		}
+
+		seen[path] = true
+		prev, known := w.files[path]
+		if known && prev.size == info.Size() && prev.modTime.Equal(info.ModTime()) {
+			return nil
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			return nil // unreadable or removed while walking; retried next poll
+		}
+		w.files[path] = fileState{hash: hash, size: info.Size(), modTime: info.ModTime()}
+
+		switch {
+		case !known:
+			events = append(events, Event{Op: OpAdd, Path: path, Hash: hash})
+		case prev.hash != hash:
+			events = append(events, Event{Op: OpChange, Path: path, Hash: hash})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", w.root, err)
+	}
+
+	for path := range w.files {
+		if !seen[path] {
+			delete(w.files, path)
+			events = append(events, Event{Op: OpRemove, Path: path})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events, nil
+}
+
+// hashFile returns the hex SHA-256 of a file's content.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,89 @@
+// Package workspace derives workspace IDs from the project a client works in.
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitTimeout bounds each git command run during detection.
+const gitTimeout = 5 * time.Second
+
+// Detect returns the workspace ID for the project at dir: the repository
+// name of its git "origin" remote, or the name of the repository's top-level
+// directory if it has no such remote. It fails if dir is not in a git
+// repository or git is not installed.
+func Detect(ctx context.Context, dir string) (string, error) {
+	if remote, err := git(ctx, dir, "remote", "get-url", "origin"); err == nil {
+		if id := Sanitize(repoName(remote)); id != "" {
+			return id, nil
+		}
+	}
+
+	toplevel, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("%s is not in a git repository: %w", dir, err)
+	}
+	if id := Sanitize(filepath.Base(toplevel)); id != "" {
+		return id, nil
+	}
+	return "", fmt.Errorf("no workspace name for repository %s", toplevel)
+}
+
+// DetectRoot returns the workspace ID for an MCP root, which must be a
+// file:// URI of a directory in a git repository.
+func DetectRoot(ctx context.Context, uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("parse root %q: %w", uri, err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("root %q is not a file URI", uri)
+	}
+	return Detect(ctx, filepath.FromSlash(u.Path))
+}
+
+// Sanitize turns name into a workspace ID: lowercase letters, digits, '.',
+// '_' and '-', with any other run of characters replaced by a single '-'.
+func Sanitize(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			b.WriteRune(r)
+			dash = false
+		case !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.Trim(b.String(), "-.")
+}
+
+// repoName returns the last path element of a git remote URL without its
+// .git suffix, e.g. "cortex" for git@github.com:swiftj/cortex.git.
+func repoName(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimRight(remote, "/"), ".git")
+	if i := strings.LastIndexAny(remote, "/:"); i >= 0 {
+		remote = remote[i+1:]
+	}
+	return remote
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
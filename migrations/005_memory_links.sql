@@ -0,0 +1,21 @@
+-- Migration 005: Memory-to-memory links
+-- Directed, typed edges between memories (e.g., 'contradicts' from conflict detection)
+
+CREATE TABLE IF NOT EXISTS memory_links (
+    id           BIGSERIAL PRIMARY KEY,
+    tenant_id    TEXT NOT NULL DEFAULT 'local',
+    workspace_id TEXT NOT NULL DEFAULT 'default',
+    source_id    BIGINT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+    target_id    BIGINT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+    link_type    TEXT NOT NULL,              -- e.g., 'contradicts'
+    meta         JSONB DEFAULT '{}'::jsonb,  -- e.g., {"reason": "...", "confidence": 0.9}
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    -- Prevent duplicate links
+    CONSTRAINT memory_links_unique UNIQUE (source_id, target_id, link_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_links_tenant_workspace ON memory_links (tenant_id, workspace_id);
+CREATE INDEX IF NOT EXISTS idx_memory_links_source ON memory_links (source_id);
+CREATE INDEX IF NOT EXISTS idx_memory_links_target ON memory_links (target_id);
+CREATE INDEX IF NOT EXISTS idx_memory_links_type ON memory_links (link_type);
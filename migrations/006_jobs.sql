@@ -0,0 +1,31 @@
+-- Migration 006: Background job queue
+-- Post-write processing (embeddings, entity extraction) runs from this queue so that
+-- failures are retried with backoff instead of being lost. Workers claim jobs with
+-- SELECT ... FOR UPDATE SKIP LOCKED; jobs that exhaust their attempts are marked 'dead'.
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    tenant_id    TEXT NOT NULL DEFAULT 'local',
+    workspace_id TEXT NOT NULL DEFAULT 'default',
+    kind         TEXT NOT NULL,                     -- e.g., 'embed', 'extract_entities'
+    memory_id    BIGINT REFERENCES memories(id) ON DELETE CASCADE,
+    payload      JSONB DEFAULT '{}'::jsonb,
+    status       TEXT NOT NULL DEFAULT 'pending',   -- pending|running|dead
+    attempts     INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(), -- earliest time the job may run
+    locked_at    TIMESTAMPTZ,                       -- when a worker claimed the job
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Claim query: pending jobs in run_at order, per tenant/workspace
+CREATE INDEX IF NOT EXISTS idx_jobs_pending
+    ON jobs (tenant_id, workspace_id, run_at) WHERE status = 'pending';
+
+-- At most one pending job of each kind per memory
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_pending_unique
+    ON jobs (kind, memory_id) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs (status);
@@ -0,0 +1,10 @@
+-- Migration 007: Documents split into chunks
+-- An ingested document is stored as a parent memory holding the full text;
+-- each chunk is a child memory that is embedded and searched on its own.
+
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES memories(id) ON DELETE CASCADE;
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS chunk_index INT;
+
+-- Index for listing a document's chunks
+CREATE INDEX IF NOT EXISTS idx_memories_parent
+  ON memories (parent_id, chunk_index) WHERE parent_id IS NOT NULL;
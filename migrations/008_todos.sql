@@ -0,0 +1,27 @@
+-- Migration 008: Todo lifecycle
+-- Structured fields for memories of kind 'todo'. A todo memory without a row
+-- here is treated as open with normal priority.
+
+CREATE TABLE IF NOT EXISTS todos (
+    memory_id    BIGINT PRIMARY KEY REFERENCES memories(id) ON DELETE CASCADE,
+    tenant_id    TEXT NOT NULL DEFAULT 'local',
+    workspace_id TEXT NOT NULL DEFAULT 'default',
+    status       TEXT NOT NULL DEFAULT 'open',   -- open|in_progress|done|archived
+    due_at       TIMESTAMPTZ,
+    assignee_id  BIGINT REFERENCES entities(id) ON DELETE SET NULL,
+    priority     SMALLINT NOT NULL DEFAULT 1,    -- 0 low, 1 normal, 2 high, 3 urgent
+    completed_at TIMESTAMPTZ,                    -- set when status becomes 'done'
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT todos_status_check CHECK (status IN ('open', 'in_progress', 'done', 'archived')),
+    CONSTRAINT todos_priority_check CHECK (priority BETWEEN 0 AND 3)
+);
+
+-- Listing todos by status and due date
+CREATE INDEX IF NOT EXISTS idx_todos_status_due
+    ON todos (tenant_id, workspace_id, status, due_at);
+
+-- Sweeper: completed todos by completion time
+CREATE INDEX IF NOT EXISTS idx_todos_completed
+    ON todos (completed_at) WHERE status = 'done';
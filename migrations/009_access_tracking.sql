@@ -0,0 +1,10 @@
+-- Migration 009: Access tracking
+-- Counts how often each memory is returned by memory.search and memory.get.
+-- Updates are batched in the server, so these columns lag reads by up to one flush interval.
+
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS last_accessed_at TIMESTAMPTZ;
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS access_count BIGINT NOT NULL DEFAULT 0;
+
+-- Sweeper: memories not accessed within the eviction window
+CREATE INDEX IF NOT EXISTS idx_memories_last_accessed
+  ON memories (tenant_id, workspace_id, last_accessed_at);
@@ -0,0 +1,5 @@
+-- Migration 010: Retention policies
+-- Start of the current importance decay period. NULL means the memory has
+-- never decayed and its period started at created_at.
+
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS decayed_at TIMESTAMPTZ;
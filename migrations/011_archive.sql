@@ -0,0 +1,43 @@
+-- Migration 011: Archive tier
+-- With ARCHIVE_MODE=table the sweeper moves expired memories here, with their
+-- chunks and embeddings, instead of deleting them. Archived memories are only
+-- returned by memory.search with include_archived. Columns mirror memories.
+
+CREATE TABLE IF NOT EXISTS memories_archive (
+  id               BIGINT PRIMARY KEY,         -- ID the memory had in memories
+  tenant_id        TEXT NOT NULL,
+  workspace_id     TEXT NOT NULL,
+  kind             TEXT NOT NULL,
+  text             TEXT NOT NULL,
+  source           TEXT,
+  created_at       TIMESTAMPTZ NOT NULL,
+  updated_at       TIMESTAMPTZ NOT NULL,
+  tags             TEXT[] DEFAULT '{}',
+  importance       REAL DEFAULT 0.5,
+  ttl_days         INT,
+  meta             JSONB DEFAULT '{}'::jsonb,
+  parent_id        BIGINT,                     -- archived with its document
+  chunk_index      INT,
+  last_accessed_at TIMESTAMPTZ,
+  access_count     BIGINT NOT NULL DEFAULT 0,
+  decayed_at       TIMESTAMPTZ,
+  archived_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  archive_reason   TEXT NOT NULL              -- ttl, unaccessed or policy:<name>
+);
+
+CREATE TABLE IF NOT EXISTS memory_embeddings_archive (
+  memory_id  BIGINT NOT NULL REFERENCES memories_archive(id) ON DELETE CASCADE,
+  model      TEXT NOT NULL,
+  dims       INT  NOT NULL,
+  embedding  VECTOR NOT NULL,
+  PRIMARY KEY (memory_id, model)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memories_archive_workspace
+  ON memories_archive (tenant_id, workspace_id);
+
+CREATE INDEX IF NOT EXISTS idx_memories_archive_text_trgm
+  ON memories_archive USING gin (text gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_memories_archive_parent
+  ON memories_archive (parent_id) WHERE parent_id IS NOT NULL;
@@ -0,0 +1,48 @@
+-- Migration 012: Sweeper run history and TTL basis
+-- Each sweep is recorded in sweeper_runs, with one sweeper_run_workspaces row
+-- per workspace it changed or failed in. The sweeper prunes runs older than
+-- 30 days.
+
+CREATE TABLE IF NOT EXISTS sweeper_runs (
+  id             BIGSERIAL PRIMARY KEY,
+  tenant_id      TEXT NOT NULL,              -- workspace of the sweeping process
+  workspace_id   TEXT NOT NULL,
+  global         BOOLEAN NOT NULL DEFAULT false,
+  started_at     TIMESTAMPTZ NOT NULL,
+  finished_at    TIMESTAMPTZ NOT NULL,
+  skipped        BOOLEAN NOT NULL DEFAULT false, -- another instance held the sweep lock
+  removed        BIGINT NOT NULL DEFAULT 0,      -- deleted or archived, across all workspaces
+  error          TEXT                            -- the sweep could not start
+);
+
+CREATE TABLE IF NOT EXISTS sweeper_run_workspaces (
+  run_id          BIGINT NOT NULL REFERENCES sweeper_runs(id) ON DELETE CASCADE,
+  tenant_id       TEXT NOT NULL,
+  workspace_id    TEXT NOT NULL,
+  expired         BIGINT NOT NULL DEFAULT 0,
+  evicted         BIGINT NOT NULL DEFAULT 0,
+  policy_expired  BIGINT NOT NULL DEFAULT 0,
+  decayed         BIGINT NOT NULL DEFAULT 0,
+  todos_archived  BIGINT NOT NULL DEFAULT 0,
+  errors          TEXT[] NOT NULL DEFAULT '{}',
+  PRIMARY KEY (run_id, tenant_id, workspace_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sweeper_runs_started
+  ON sweeper_runs (started_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_sweeper_run_workspaces_workspace
+  ON sweeper_run_workspaces (tenant_id, workspace_id, run_id DESC);
+
+-- What ttl_days counts from: created (the default), updated (the last write)
+-- or accessed (the last write or read, whichever is later).
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS ttl_from TEXT NOT NULL DEFAULT 'created';
+ALTER TABLE memories_archive ADD COLUMN IF NOT EXISTS ttl_from TEXT NOT NULL DEFAULT 'created';
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'memories_ttl_from_check') THEN
+    ALTER TABLE memories ADD CONSTRAINT memories_ttl_from_check
+      CHECK (ttl_from IN ('created', 'updated', 'accessed'));
+  END IF;
+END $$;
@@ -0,0 +1,51 @@
+-- Migration 013: Row-level security for tenant isolation
+-- Every tenant-scoped table only shows and accepts rows of the tenant in the
+-- cortex.tenant_id setting, which Cortex sets on each pooled connection. Rows
+-- without a tenant_id column follow the memory they belong to. Sessions with
+-- cortex.all_tenants = 'on' (migrations, the global sweep) see every tenant.
+-- A session that sets neither sees nothing. Superusers and roles with
+-- BYPASSRLS are not subject to these policies.
+
+DO $$
+DECLARE
+  t TEXT;
+BEGIN
+  FOREACH t IN ARRAY ARRAY[
+    'memories', 'memories_archive', 'entities', 'entity_relations', 'memory_links',
+    'jobs', 'todos', 'sweeper_runs', 'sweeper_run_workspaces'
+  ] LOOP
+    EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+    EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+    IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = t AND policyname = 'tenant_isolation') THEN
+      EXECUTE format($p$
+        CREATE POLICY tenant_isolation ON %I
+          USING (tenant_id = current_setting('cortex.tenant_id', true)
+                 OR current_setting('cortex.all_tenants', true) = 'on')
+      $p$, t);
+    END IF;
+  END LOOP;
+END $$;
+
+-- Embeddings and entity mentions are visible when their memory is
+DO $$
+DECLARE
+  t TEXT;
+  parent TEXT;
+BEGIN
+  FOR t, parent IN
+    SELECT * FROM (VALUES
+      ('memory_embeddings', 'memories'),
+      ('memory_entities', 'memories'),
+      ('memory_embeddings_archive', 'memories_archive')
+    ) AS v (child, parent)
+  LOOP
+    EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+    EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+    IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = t AND policyname = 'tenant_isolation') THEN
+      EXECUTE format($p$
+        CREATE POLICY tenant_isolation ON %I
+          USING (EXISTS (SELECT 1 FROM %I p WHERE p.id = memory_id))
+      $p$, t, parent);
+    END IF;
+  END LOOP;
+END $$;
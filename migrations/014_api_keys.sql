@@ -0,0 +1,34 @@
+-- Migration 014: API keys
+-- Keys authenticate MCP clients on network transports. Only a SHA-256 hash of
+-- each key is stored; the key itself is shown once when it is created. A key
+-- belongs to a tenant and grants one role (read, write or admin) on a set of
+-- workspaces, where '*' means every workspace of the tenant.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id            BIGSERIAL PRIMARY KEY,
+  tenant_id     TEXT NOT NULL,
+  name          TEXT NOT NULL,
+  key_prefix    TEXT NOT NULL,              -- start of the key, to recognize it in listings
+  key_hash      TEXT NOT NULL UNIQUE,       -- hex SHA-256 of the key
+  role          TEXT NOT NULL,
+  workspaces    TEXT[] NOT NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at  TIMESTAMPTZ,
+  revoked_at    TIMESTAMPTZ,
+
+  CONSTRAINT api_keys_role_check CHECK (role IN ('read', 'write', 'admin'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys (tenant_id, created_at DESC);
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'api_keys' AND policyname = 'tenant_isolation') THEN
+    CREATE POLICY tenant_isolation ON api_keys
+      USING (tenant_id = current_setting('cortex.tenant_id', true)
+             OR current_setting('cortex.all_tenants', true) = 'on');
+  END IF;
+END $$;
@@ -0,0 +1,48 @@
+-- Migration 015: Audit log
+-- One row per mutating tool call, CLI import and sweeper change: who made
+-- it (API key, or the sweeper), from which MCP client, with a SHA-256 digest
+-- of the tool arguments and the IDs of the memories it affected. Rows cannot
+-- be updated or deleted.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+  id              BIGSERIAL PRIMARY KEY,
+  tenant_id       TEXT NOT NULL,
+  workspace_id    TEXT NOT NULL,
+  at              TIMESTAMPTZ NOT NULL DEFAULT now(),
+  actor           TEXT NOT NULL,              -- key:<prefix> (<name>), local, cli or sweeper
+  tool            TEXT NOT NULL,              -- tool name, cli:<command> or sweeper
+  args_digest     TEXT,                       -- hex SHA-256 of the tool arguments
+  client_name     TEXT,                       -- clientInfo from the MCP initialize request
+  client_version  TEXT,
+  memory_ids      BIGINT[] NOT NULL DEFAULT '{}',
+  detail          JSONB NOT NULL DEFAULT '{}'::jsonb
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_workspace
+  ON audit_log (tenant_id, workspace_id, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_memory_ids
+  ON audit_log USING gin (memory_ids);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+  BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+ALTER TABLE audit_log ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_log FORCE ROW LEVEL SECURITY;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'audit_log' AND policyname = 'tenant_isolation') THEN
+    CREATE POLICY tenant_isolation ON audit_log
+      USING (tenant_id = current_setting('cortex.tenant_id', true)
+             OR current_setting('cortex.all_tenants', true) = 'on');
+  END IF;
+END $$;
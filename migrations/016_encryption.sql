@@ -0,0 +1,13 @@
+-- Migration 016: Encryption at rest
+-- Memories of encrypted workspaces store sealed text and meta. Their blind
+-- index holds keyed hashes of the words of the text, so lexical search can
+-- match exact words without reading it. NULL means the text is not sealed.
+
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS blind_index TEXT[];
+ALTER TABLE memories_archive ADD COLUMN IF NOT EXISTS blind_index TEXT[];
+
+CREATE INDEX IF NOT EXISTS idx_memories_blind_index
+  ON memories USING gin (blind_index) WHERE blind_index IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_memories_archive_blind_index
+  ON memories_archive USING gin (blind_index) WHERE blind_index IS NOT NULL;